@@ -0,0 +1,288 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"goverter/pkg/converter"
+	"goverter/pkg/formats"
+	"goverter/pkg/jobstore"
+	"goverter/pkg/scheduler"
+	"goverter/pkg/utils"
+)
+
+var (
+	bulkOutDir    string
+	bulkRecursive bool
+	bulkOverwrite bool
+	bulkInclude   []string
+	bulkExclude   []string
+	bulkJobs      int
+	bulkFailFast  bool
+)
+
+type bulkStatus string
+
+const (
+	bulkConverted bulkStatus = "converted"
+	bulkSkipped   bulkStatus = "skipped"
+	bulkFailed    bulkStatus = "failed"
+)
+
+type bulkResult struct {
+	Input    string
+	Output   string
+	Status   bulkStatus
+	Detail   string
+	Err      error
+	Duration time.Duration
+}
+
+func runBulkConvert(ctx context.Context, targetFormat string) error {
+	if outputFormat != "" {
+		targetFormat = outputFormat
+	}
+	if bulkDir == "" || targetFormat == "" {
+		return fmt.Errorf("both --bulk and --format flags are required for bulk conversion")
+	}
+
+	targetExt := "." + strings.TrimPrefix(strings.ToLower(targetFormat), ".")
+	c := converter.NewConverter()
+
+	inputExts := c.GetInputFormats(targetExt)
+	if len(inputExts) == 0 {
+		return fmt.Errorf("no supported input formats convert to %s", targetExt)
+	}
+
+	files, err := utils.ListFilesByExtension(bulkDir, inputExts, bulkRecursive)
+	if err != nil {
+		return fmt.Errorf("scanning %s: %w", bulkDir, err)
+	}
+
+	outRoot := bulkOutDir
+	if outRoot == "" {
+		outRoot = bulkDir
+	}
+
+	options, err := conversionOptions()
+	if err != nil {
+		return err
+	}
+	if err := converter.ValidateOptions(targetExt, options); err != nil {
+		return err
+	}
+
+	var (
+		results  []bulkResult
+		requests []converter.ConversionRequest
+		pending  []int // index in results of each request
+	)
+	for _, file := range files {
+		rel, err := filepath.Rel(bulkDir, file)
+		if err != nil {
+			rel = filepath.Base(file)
+		}
+
+		if len(bulkInclude) > 0 && !utils.MatchesAnyGlob(rel, bulkInclude) {
+			continue
+		}
+		if utils.MatchesAnyGlob(rel, bulkExclude) {
+			continue
+		}
+
+		outputPath := filepath.Join(outRoot, strings.TrimSuffix(rel, filepath.Ext(rel))+targetExt)
+		result := bulkResult{Input: file, Output: outputPath}
+
+		switch {
+		case formats.Default().Same(filepath.Ext(file), targetExt):
+			result.Status = bulkSkipped
+			result.Detail = "already " + targetExt
+		case !bulkOverwrite && fileExists(outputPath):
+			result.Status = bulkSkipped
+			result.Detail = "output exists"
+		default:
+			if err := utils.EnsureDir(filepath.Dir(outputPath)); err != nil {
+				result.Status = bulkFailed
+				result.Detail = err.Error()
+				result.Err = err
+				break
+			}
+			pending = append(pending, len(results))
+			requests = append(requests, converter.ConversionRequest{
+				InputPath:  file,
+				OutputPath: outputPath,
+				Options:    options,
+			})
+		}
+
+		results = append(results, result)
+	}
+
+	// Journal the batch so that 'resume' can finish it if we die midway.
+	store := openJobStoreOrWarn()
+	batch := jobstore.NewID()
+	jobIDs := make([]string, len(requests))
+	for i, req := range requests {
+		if store == nil {
+			break
+		}
+		job, err := store.Add(jobstore.Job{Batch: batch, Input: req.InputPath, Output: req.OutputPath, Options: req.Options})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: job store unavailable, progress will not be resumable: %v\n", err)
+			store = nil
+			break
+		}
+		jobIDs[i] = job.ID
+	}
+
+	runBulkRequests(ctx, c, requests, func(i int, err error, elapsed time.Duration) {
+		result := &results[pending[i]]
+		result.Duration = elapsed
+		if store != nil && !errors.Is(err, scheduler.ErrSkipped) {
+			if storeErr := store.Finish(jobIDs[i], err); storeErr != nil {
+				fmt.Fprintf(os.Stderr, "Warning: failed to record job %s: %v\n", jobIDs[i], storeErr)
+			}
+		}
+		switch {
+		case err == nil:
+			result.Status = bulkConverted
+		case errors.Is(err, scheduler.ErrSkipped):
+			result.Status = bulkSkipped
+			result.Detail = "not started"
+		default:
+			result.Status = bulkFailed
+			result.Err = err
+			result.Detail = err.Error()
+			if hint := converter.ErrorHint(err); hint != "" {
+				result.Detail += " (" + hint + ")"
+			}
+		}
+	})
+
+	emitBulkResults(results)
+	failed := printBulkSummary(results)
+	if ctx.Err() != nil {
+		logf("Bulk conversion cancelled; remaining files were not converted\n")
+	}
+	if (ctx.Err() != nil || failed > 0) && store != nil {
+		logf("Resume with: goverter resume %s\n", batch)
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if failed > 0 {
+		return errReported
+	}
+	return nil
+}
+
+// runBulkRequests converts requests on the worker pool, calling report with
+// each request's index and error as it finishes. A single progress bar
+// tracks the combined progress of all files.
+func runBulkRequests(ctx context.Context, c *converter.Converter, requests []converter.ConversionRequest, report func(int, error, time.Duration)) {
+	if len(requests) == 0 {
+		return
+	}
+
+	label := fmt.Sprintf("Converting %d files", len(requests))
+	runWithProgress(label, func(progress chan float64) error {
+		var (
+			mu        sync.Mutex
+			fractions = make([]float64, len(requests))
+			wg        sync.WaitGroup
+		)
+		update := func(i int, value float64) {
+			mu.Lock()
+			defer mu.Unlock()
+			fractions[i] = value
+			total := 0.0
+			for _, f := range fractions {
+				total += f
+			}
+			select {
+			case progress <- total / float64(len(fractions)):
+			default:
+			}
+		}
+
+		for i := range requests {
+			i, fileProgress := i, make(chan float64, 16)
+			requests[i].Progress = fileProgress
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for value := range fileProgress {
+					update(i, value)
+				}
+				// Finished, failed or skipped: the file no longer counts
+				// as outstanding work.
+				update(i, 1)
+			}()
+		}
+
+		stream := c.BatchConvertStream(ctx, requests, converter.BatchOptions{
+			Jobs:     bulkJobs,
+			FailFast: bulkFailFast,
+		})
+		for result := range stream {
+			close(requests[result.Index].Progress)
+			err := result.Err
+			if err != nil && !result.Skipped() {
+				// Callers report the input themselves, so drop the
+				// batch's "failed to convert <file>" prefix.
+				if inner := errors.Unwrap(err); inner != nil {
+					err = inner
+				}
+			}
+			report(result.Index, err, result.Duration)
+		}
+		wg.Wait()
+		return nil
+	})
+}
+
+// emitBulkResults writes one JSON result per file in JSON mode.
+func emitBulkResults(results []bulkResult) {
+	for _, r := range results {
+		out := result{Command: "convert", Input: r.Input, Output: r.Output, Detail: r.Detail}
+		switch r.Status {
+		case bulkSkipped:
+			out.Status = statusSkipped
+		case bulkFailed:
+			out.Detail = ""
+		}
+		fillResult(&out, r.Duration, r.Err)
+		emit(out)
+	}
+}
+
+// printBulkSummary writes the per-file table and totals, returning the
+// number of failed conversions.
+func printBulkSummary(results []bulkResult) int {
+	counts := make(map[bulkStatus]int)
+
+	w := tabwriter.NewWriter(logOut(), 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "STATUS\tINPUT\tOUTPUT\tDETAIL")
+	for _, r := range results {
+		counts[r.Status]++
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", r.Status, r.Input, r.Output, r.Detail)
+	}
+	w.Flush()
+
+	logf("\n%d converted, %d skipped, %d failed (%d files)\n",
+		counts[bulkConverted], counts[bulkSkipped], counts[bulkFailed], len(results))
+
+	return counts[bulkFailed]
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
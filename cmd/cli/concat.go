@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"goverter/pkg/converter"
+)
+
+var (
+	concatCrossfade  float64
+	concatTransition string
+)
+
+func runConcat(cmd *cobra.Command, args []string) error {
+	if outputFile == "" {
+		return fmt.Errorf("the --output flag is required")
+	}
+
+	options := make(map[string]string)
+	if quality != "" {
+		options["quality"] = quality
+	}
+
+	c := converter.NewConverter()
+	op := startOperation("concat", "", outputFile)
+	op.Data = map[string][]string{"inputs": args}
+	err := runWithProgress("Joining", func(progress chan float64) error {
+		return c.Concat(cmd.Context(), converter.ConcatRequest{
+			InputPaths: args,
+			OutputPath: outputFile,
+			Crossfade:  concatCrossfade,
+			Transition: concatTransition,
+			Options:    options,
+			Progress:   progress,
+		})
+	})
+	if err := op.finish(err, "Error joining files"); err != nil {
+		return err
+	}
+
+	logf("Successfully joined %d files into %s\n", len(args), outputFile)
+	return nil
+}
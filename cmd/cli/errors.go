@@ -0,0 +1,34 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"goverter/pkg/converter"
+)
+
+// printConversionError prints err after prefix to stderr. Tool failures
+// additionally get the command line, exit code, a hint when the failure is
+// recognised and the tail of the tool's stderr.
+func printConversionError(prefix string, err error) {
+	fmt.Fprintf(os.Stderr, "%s: %v\n", prefix, err)
+
+	var toolErr *converter.ToolError
+	if !errors.As(err, &toolErr) {
+		return
+	}
+
+	fmt.Fprintf(os.Stderr, "  Command:   %s\n", toolErr.CommandLine())
+	fmt.Fprintf(os.Stderr, "  Exit code: %d\n", toolErr.ExitCode)
+	if hint := converter.ErrorHint(err); hint != "" {
+		fmt.Fprintf(os.Stderr, "  Hint:      %s\n", hint)
+	}
+	if stderr := strings.TrimSpace(toolErr.Stderr); stderr != "" {
+		fmt.Fprintln(os.Stderr, "  Tool output:")
+		for _, line := range strings.Split(stderr, "\n") {
+			fmt.Fprintf(os.Stderr, "    %s\n", line)
+		}
+	}
+}
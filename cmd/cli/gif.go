@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"goverter/pkg/converter"
+)
+
+var (
+	gifFormat string
+	gifFPS    float64
+	gifDither string
+	gifColors int
+	gifStart  string
+	gifEnd    string
+	gifLoop   int
+)
+
+func runGif(cmd *cobra.Command, args []string) error {
+	if inputFile == "" {
+		return fmt.Errorf("the --input flag is required")
+	}
+	format := strings.TrimPrefix(strings.ToLower(gifFormat), ".")
+	switch format {
+	case "gif", "webp", "apng":
+	default:
+		return fmt.Errorf("--format must be gif, webp or apng, got %q", gifFormat)
+	}
+	output := outputFile
+	if output == "" {
+		output = strings.TrimSuffix(inputFile, filepath.Ext(inputFile)) + "." + format
+	}
+
+	options := make(map[string]string)
+	set := func(key, value string, ok bool) {
+		if ok {
+			options[key] = value
+		}
+	}
+	set("fps", strconv.FormatFloat(gifFPS, 'f', -1, 64), gifFPS > 0)
+	set("width", strconv.Itoa(width), width > 0)
+	set("height", strconv.Itoa(height), height > 0)
+	set("dither", gifDither, gifDither != "")
+	set("max_colors", strconv.Itoa(gifColors), gifColors > 0)
+	set("start", gifStart, gifStart != "")
+	set("end", gifEnd, gifEnd != "")
+	set("loop", strconv.Itoa(gifLoop), gifLoop > 0)
+	set("quality", quality, quality != "")
+	if err := converter.ValidateOptions(filepath.Ext(output), options); err != nil {
+		return err
+	}
+
+	c := converter.NewConverter()
+	op := startOperation("gif", inputFile, output)
+	err := runWithProgress("Rendering", func(progress chan float64) error {
+		return c.ConvertContext(cmd.Context(), converter.ConversionRequest{
+			InputPath:  inputFile,
+			OutputPath: output,
+			Options:    options,
+			Progress:   progress,
+		})
+	})
+	if err := op.finish(err, "Error creating animation"); err != nil {
+		return err
+	}
+
+	logf("Successfully created %s\n", output)
+	return nil
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"goverter/pkg/converter"
+)
+
+var (
+	loudnessTarget string
+	truePeak       string
+	loudnessRange  string
+)
+
+func runLoudness(cmd *cobra.Command, args []string) error {
+	if inputFile == "" {
+		return fmt.Errorf("the --input flag is required")
+	}
+
+	c := converter.NewConverter()
+	op := startOperation("loudness", inputFile, "")
+	var loudness *converter.Loudness
+	err := runWithProgress("Measuring", func(progress chan float64) error {
+		var err error
+		loudness, err = c.MeasureLoudness(cmd.Context(), inputFile, progress)
+		return err
+	})
+	if err == nil {
+		op.Data = loudness
+	}
+	if err := op.finish(err, "Error measuring loudness"); err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(logOut(), 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "Integrated loudness:\t%.1f LUFS\n", loudness.Integrated)
+	fmt.Fprintf(w, "True peak:\t%.1f dBTP\n", loudness.TruePeak)
+	fmt.Fprintf(w, "Loudness range:\t%.1f LU\n", loudness.Range)
+	fmt.Fprintf(w, "Threshold:\t%.1f LUFS\n", loudness.Threshold)
+	return w.Flush()
+}
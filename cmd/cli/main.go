@@ -1,12 +1,23 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
 
 	"github.com/spf13/cobra"
 	"goverter/pkg/converter"
+	"goverter/pkg/formats"
 	"goverter/pkg/image"
+	"goverter/pkg/presets"
+	"goverter/pkg/probe"
 	"goverter/pkg/video"
 )
 
@@ -23,6 +34,7 @@ var (
 	cropHeight   int
 	bulkDir      string
 	outputFormat string
+	explainPlan  bool
 )
 
 func main() {
@@ -31,39 +43,177 @@ func main() {
 		Short: "A versatile file conversion and media processing tool",
 		Long: `Goverter is a CLI tool for converting files between formats,
 processing images and videos, and handling bulk operations.`,
+		SilenceErrors: true,
+		SilenceUsage:  true,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return validateOutputMode()
+		},
 	}
+	rootCmd.PersistentFlags().StringVar(&jobStorePath, "job-store", "", "Job journal used by bulk, watch and resume (default: jobs.jsonl in the config directory)")
+	rootCmd.PersistentFlags().StringVar(&outputMode, "output-format", outputText, "Result format: text, or json for one JSON object per operation on stdout")
 
 	// Convert command
 	var convertCmd = &cobra.Command{
 		Use:   "convert [format]",
 		Short: "Convert files between formats",
-		Args:  cobra.ExactArgs(1),
-		Run:   runConvert,
+		Args:  cobra.MaximumNArgs(1),
+		RunE:  runConvert,
 	}
 	convertCmd.Flags().StringVarP(&inputFile, "input", "i", "", "Input file path")
 	convertCmd.Flags().StringVarP(&outputFile, "output", "o", "", "Output file path")
-	convertCmd.Flags().StringVarP(&quality, "quality", "q", "", "Quality setting (CRF for video, 1-100 for images)")
+	convertCmd.Flags().StringVarP(&quality, "quality", "q", "", "Quality from 1 to 100, mapped to each encoder's own scale")
+	convertCmd.Flags().StringVarP(&presetName, "preset", "p", "", "Named preset supplying the output format and options (see 'presets')")
+	convertCmd.Flags().StringVar(&videoCodec, "video-codec", "", "Video codec: h264, hevc, vp9, av1, mpeg4, flv1 or wmv2")
+	convertCmd.Flags().StringVar(&audioCodec, "audio-codec", "", "Audio codec: aac, mp3, opus, vorbis, flac, pcm, ac3 or wmav2")
+	convertCmd.Flags().StringVar(&pixelFormat, "pix-fmt", "", "Pixel format, e.g. yuv420p or yuv420p10le")
+	convertCmd.Flags().StringVar(&codecProfile, "profile", "", "Codec profile, e.g. high for h264 or main10 for hevc")
+	convertCmd.Flags().StringVar(&codecLevel, "level", "", "Codec level for h264 and hevc, e.g. 4.1")
+	convertCmd.Flags().StringVar(&targetSize, "target-size", "", "Encode video in two passes to fit this size, e.g. 25MB or 8MiB")
+	convertCmd.Flags().StringVar(&encoderPreset, "encoder-preset", "", "Speed/size trade-off: ultrafast..veryslow for h264/hevc, realtime/good/best for vp9, 0-13 for av1")
+	convertCmd.Flags().StringVar(&loudnessTarget, "loudness", "", "Normalise audio to this integrated loudness in LUFS, e.g. -16 (two passes)")
+	convertCmd.Flags().StringVar(&truePeak, "true-peak", "", "True peak ceiling in dBTP for --loudness (default -1.5)")
+	convertCmd.Flags().StringVar(&loudnessRange, "lra", "", "Loudness range target in LU for --loudness (default 11)")
 	convertCmd.Flags().StringVarP(&bulkDir, "bulk", "b", "", "Bulk convert all files in directory")
 	convertCmd.Flags().StringVarP(&outputFormat, "format", "f", "", "Output format for bulk conversion")
+	convertCmd.Flags().StringVar(&bulkOutDir, "out-dir", "", "Output root for bulk conversion (mirrors the input tree)")
+	convertCmd.Flags().BoolVarP(&bulkRecursive, "recursive", "r", false, "Descend into subdirectories during bulk conversion")
+	convertCmd.Flags().BoolVar(&bulkOverwrite, "overwrite", false, "Overwrite existing outputs during bulk conversion")
+	convertCmd.Flags().StringSliceVar(&bulkInclude, "include", nil, "Only bulk convert files matching these globs")
+	convertCmd.Flags().StringSliceVar(&bulkExclude, "exclude", nil, "Skip files matching these globs during bulk conversion")
+	convertCmd.Flags().IntVarP(&bulkJobs, "jobs", "j", 0, "Number of bulk conversions to run at once (default: one per CPU)")
+	convertCmd.Flags().BoolVar(&bulkFailFast, "fail-fast", false, "Stop bulk conversion at the first failure")
+	convertCmd.Flags().BoolVar(&explainPlan, "explain", false, "Print the conversion plan without converting")
 
 	// Frame command
 	var frameCmd = &cobra.Command{
 		Use:   "frame [timestamp]",
 		Short: "Extract a frame from video at specific timestamp",
 		Args:  cobra.ExactArgs(1),
-		Run:   runFrame,
+		RunE:  runFrame,
 	}
 	frameCmd.Flags().StringVarP(&inputFile, "input", "i", "", "Input video file path")
 	frameCmd.Flags().StringVarP(&outputFile, "output", "o", "", "Output image file path")
 	frameCmd.Flags().IntVar(&width, "width", 0, "Output width (optional)")
 	frameCmd.Flags().IntVar(&height, "height", 0, "Output height (optional)")
 
+	// GIF command
+	var gifCmd = &cobra.Command{
+		Use:   "gif",
+		Short: "Turn a video clip into an animated GIF, WebP or APNG",
+		Args:  cobra.NoArgs,
+		RunE:  runGif,
+	}
+	gifCmd.Flags().StringVarP(&inputFile, "input", "i", "", "Input video file path")
+	gifCmd.Flags().StringVarP(&outputFile, "output", "o", "", "Output path (default: the input with the --format extension)")
+	gifCmd.Flags().StringVarP(&gifFormat, "format", "f", "gif", "Animation format: gif, webp or apng")
+	gifCmd.Flags().Float64Var(&gifFPS, "fps", 0, "Frame rate, capped at the source's (default 10)")
+	gifCmd.Flags().IntVar(&width, "width", 0, "Maximum width (default 480)")
+	gifCmd.Flags().IntVar(&height, "height", 0, "Maximum height")
+	gifCmd.Flags().StringVar(&gifDither, "dither", "", "GIF dithering: none, bayer, floyd_steinberg, sierra2 or sierra2_4a (default)")
+	gifCmd.Flags().IntVar(&gifColors, "colors", 0, "GIF palette size, 2-256 (default 256)")
+	gifCmd.Flags().StringVar(&gifStart, "start", "", "Start position, seconds or HH:MM:SS")
+	gifCmd.Flags().StringVar(&gifEnd, "end", "", "End position, seconds or HH:MM:SS")
+	gifCmd.Flags().IntVar(&gifLoop, "loop", 0, "Number of times to play (default: forever)")
+	gifCmd.Flags().StringVarP(&quality, "quality", "q", "", "WebP quality from 1 to 100")
+
+	// Trim command
+	var trimCmd = &cobra.Command{
+		Use:   "trim",
+		Short: "Cut segments out of a video or audio file",
+		Long: `Trim keeps part of a video or audio file: one range given with --start
+and --end or --duration, or several with --keep. --remove cuts the listed
+segments out instead. Segments are START-END, START+DURATION or START-
+(to the end), separated by commas.
+
+By default the streams are copied, which is fast and lossless but starts
+each cut at the keyframe before it. --accurate re-encodes for frame-exact
+cuts.`,
+		Args: cobra.NoArgs,
+		RunE: runTrim,
+	}
+	trimCmd.Flags().StringVarP(&inputFile, "input", "i", "", "Input video or audio file path")
+	trimCmd.Flags().StringVarP(&outputFile, "output", "o", "", "Output path (default: the input name with _trimmed)")
+	trimCmd.Flags().StringVar(&trimStart, "start", "", "Start position, seconds or HH:MM:SS")
+	trimCmd.Flags().StringVar(&trimEnd, "end", "", "End position, seconds or HH:MM:SS")
+	trimCmd.Flags().StringVar(&trimDuration, "duration", "", "Length to keep from --start, seconds or HH:MM:SS")
+	trimCmd.Flags().StringVar(&trimKeep, "keep", "", "Segments to keep, e.g. 0:10-0:20,1:00+30")
+	trimCmd.Flags().StringVar(&trimRemove, "remove", "", "Segments to cut out, e.g. 0-5,2:00-")
+	trimCmd.Flags().BoolVar(&trimAccurate, "accurate", false, "Re-encode for frame-exact cuts instead of copying streams")
+	trimCmd.Flags().StringVarP(&quality, "quality", "q", "", "Quality from 1 to 100 for --accurate")
+
+	// Concat command
+	var concatCmd = &cobra.Command{
+		Use:   "concat [input]...",
+		Short: "Join video or audio files end to end",
+		Long: `Concat joins its inputs in the order given. Inputs in the output's
+container with matching streams are joined without re-encoding. Otherwise,
+or with --crossfade or --quality, every clip is scaled and padded to the
+first one's resolution, converted to its frame rate and sample rate, and
+re-encoded.`,
+		Args: cobra.MinimumNArgs(2),
+		RunE: runConcat,
+	}
+	concatCmd.Flags().StringVarP(&outputFile, "output", "o", "", "Output file path")
+	concatCmd.Flags().Float64Var(&concatCrossfade, "crossfade", 0, "Overlap each clip with the next for this many seconds")
+	concatCmd.Flags().StringVar(&concatTransition, "transition", "fade", "Crossfade transition: "+strings.Join(converter.Transitions, ", "))
+	concatCmd.Flags().StringVarP(&quality, "quality", "q", "", "Quality from 1 to 100; forces a re-encode")
+
+	// Split command
+	var splitCmd = &cobra.Command{
+		Use:   "split",
+		Short: "Split a recording into chunks at its silences",
+		Long: `Split runs silence detection over a recording and writes a chunk for each
+stretch between silences, cutting in the middle of each silence. Chunks are
+named after the input with a sequence number, and a JSON manifest lists
+their start and end times.
+
+With --by-chapters, the input is split at its chapter markers instead and
+each chunk is named from its chapter title with the --name template, which
+can use {{.Name}}, {{.Title}}, {{.Number}} and {{.Ext}}. Chunks in the
+input's format are stream copied unless --quality is given.
+
+With --strip, the input is instead converted to a single output with its
+leading and trailing silence removed.`,
+		Args: cobra.NoArgs,
+		RunE: runSplit,
+	}
+	splitCmd.Flags().StringVarP(&inputFile, "input", "i", "", "Input audio or video file path")
+	splitCmd.Flags().StringVarP(&outputFile, "output", "o", "", "Output path for --strip (default: the input name with _stripped)")
+	splitCmd.Flags().StringVar(&splitOutDir, "out-dir", "", "Directory for the chunks (default: the input's directory)")
+	splitCmd.Flags().StringVarP(&splitFormat, "format", "f", "", "Format of the chunks (default: the input's); audio only when splitting at silences")
+	splitCmd.Flags().Float64Var(&splitNoise, "noise", 0, "Silence threshold in dB (default -35)")
+	splitCmd.Flags().Float64Var(&splitMinSilence, "min-silence", 0, "Shortest pause in seconds that counts as silence (default 0.5)")
+	splitCmd.Flags().StringVar(&splitMinLength, "min-length", "", "Shortest chunk, seconds or HH:MM:SS")
+	splitCmd.Flags().StringVar(&splitMaxLength, "max-length", "", "Longest chunk, seconds or HH:MM:SS; longer stretches are cut without a silence")
+	splitCmd.Flags().StringVar(&splitManifest, "manifest", "", "Manifest path (default: <input name>_chunks.json in the output directory)")
+	splitCmd.Flags().BoolVar(&splitChapters, "by-chapters", false, "Split at the input's chapter markers instead of at silences")
+	splitCmd.Flags().StringVar(&splitName, "name", converter.DefaultChapterTemplate, "File name template for --by-chapters")
+	splitCmd.Flags().BoolVar(&splitStrip, "strip", false, "Strip leading and trailing silence into one output instead of splitting")
+	splitCmd.Flags().StringVarP(&quality, "quality", "q", "", "Quality from 1 to 100 for the output")
+
+	// Waveform command
+	var waveformCmd = &cobra.Command{
+		Use:   "waveform",
+		Short: "Draw the waveform or spectrogram of an audio or video file",
+		Args:  cobra.NoArgs,
+		RunE:  runWaveform,
+	}
+	waveformCmd.Flags().StringVarP(&inputFile, "input", "i", "", "Input audio or video file path")
+	waveformCmd.Flags().StringVarP(&outputFile, "output", "o", "", "Output .png or .jpg (default: the input name with _waveform or _spectrogram)")
+	waveformCmd.Flags().StringVar(&waveformStyle, "style", converter.StyleWaveform, "Image style: waveform or spectrogram")
+	waveformCmd.Flags().IntVar(&width, "width", 0, "Image width (default 1200)")
+	waveformCmd.Flags().IntVar(&height, "height", 0, "Image height (default 240 for a waveform, 480 for a spectrogram)")
+	waveformCmd.Flags().StringVar(&waveformColor, "color", "", "Waveform colour as a name or #RRGGBB, or a spectrogram colour scheme such as magma or viridis")
+	waveformCmd.Flags().StringVar(&waveformBackground, "background", "", "Waveform background colour (default: transparent)")
+	waveformCmd.Flags().BoolVar(&waveformSplit, "split-channels", false, "Draw each channel of a waveform in its own row")
+	waveformCmd.Flags().BoolVar(&waveformLegend, "legend", false, "Add frequency and time axes to a spectrogram")
+
 	// Crop command
 	var cropCmd = &cobra.Command{
 		Use:   "crop [x] [y] [width] [height]",
 		Short: "Crop an image",
 		Args:  cobra.ExactArgs(4),
-		Run:   runCrop,
+		RunE:  runCrop,
 	}
 	cropCmd.Flags().StringVarP(&inputFile, "input", "i", "", "Input image file path")
 	cropCmd.Flags().StringVarP(&outputFile, "output", "o", "", "Output image file path")
@@ -74,7 +224,7 @@ processing images and videos, and handling bulk operations.`,
 		Use:   "resize [width] [height]",
 		Short: "Resize an image",
 		Args:  cobra.ExactArgs(2),
-		Run:   runResize,
+		RunE:  runResize,
 	}
 	resizeCmd.Flags().StringVarP(&inputFile, "input", "i", "", "Input image file path")
 	resizeCmd.Flags().StringVarP(&outputFile, "output", "o", "", "Output image file path")
@@ -84,67 +234,217 @@ processing images and videos, and handling bulk operations.`,
 	var infoCmd = &cobra.Command{
 		Use:   "info",
 		Short: "Get information about a media file",
-		Run:   runInfo,
+		RunE:  runInfo,
 	}
 	infoCmd.Flags().StringVarP(&inputFile, "input", "i", "", "Input file path")
 
+	// Loudness command
+	var loudnessCmd = &cobra.Command{
+		Use:   "loudness",
+		Short: "Measure the EBU R128 loudness of a video or audio file",
+		Args:  cobra.NoArgs,
+		RunE:  runLoudness,
+	}
+	loudnessCmd.Flags().StringVarP(&inputFile, "input", "i", "", "Input video or audio file path")
+
+	// Formats command
+	var formatsCmd = &cobra.Command{
+		Use:   "formats",
+		Short: "List known file formats",
+		RunE:  runFormats,
+	}
+
+	// Presets command
+	var presetsCmd = &cobra.Command{
+		Use:   "presets",
+		Short: "List conversion presets",
+		RunE:  runPresets,
+	}
+
+	// Run command
+	var runCmd = &cobra.Command{
+		Use:   "run [job file]",
+		Short: "Run the steps of a YAML or TOML job file",
+		Args:  cobra.ExactArgs(1),
+		RunE:  runJob,
+	}
+	runCmd.Flags().BoolVar(&runDryRun, "dry-run", false, "Print the commands each step would run without running them")
+
+	// Watch command
+	var watchCmd = &cobra.Command{
+		Use:   "watch [dir]",
+		Short: "Convert files as they appear in a folder",
+		Args:  cobra.ExactArgs(1),
+		RunE:  runWatch,
+	}
+	watchCmd.Flags().StringVar(&watchTo, "to", "", "Target format")
+	watchCmd.Flags().StringVar(&watchOut, "out", "", "Output directory")
+	watchCmd.Flags().StringVar(&watchDoneDir, "done", "", "Move originals here after a successful conversion")
+	watchCmd.Flags().StringVar(&watchFailedDir, "failed", "", "Move originals here after a failed conversion")
+	watchCmd.Flags().DurationVar(&watchSettle, "settle", 2*time.Second, "How long a file must stop changing before it is converted")
+	watchCmd.Flags().StringVarP(&quality, "quality", "q", "", "Quality from 1 to 100, mapped to each encoder's own scale")
+	watchCmd.MarkFlagRequired("to")
+	watchCmd.MarkFlagRequired("out")
+
+	// Serve command
+	var serveCmd = &cobra.Command{
+		Use:   "serve",
+		Short: "Serve an HTTP API for conversion jobs",
+		Args:  cobra.NoArgs,
+		RunE:  runServe,
+	}
+	serveCmd.Flags().StringVar(&serveAddr, "addr", "127.0.0.1:8080", "Address to listen on")
+	serveCmd.Flags().StringVar(&serveRoot, "root", ".", "Directory clients may read and write by path")
+	serveCmd.Flags().StringVar(&serveDataDir, "data-dir", "", "Directory for uploads and results (default: a temporary directory)")
+	serveCmd.Flags().Int64Var(&serveMaxUploadMB, "max-upload-mb", 1024, "Largest accepted upload in MiB")
+	serveCmd.Flags().IntVarP(&serveJobs, "jobs", "j", 2, "Number of conversions to run at once")
+	serveCmd.Flags().DurationVar(&serveJobTTL, "job-ttl", time.Hour, "How long finished jobs, their results and unused uploads are kept")
+
+	// Resume command
+	var resumeCmd = &cobra.Command{
+		Use:   "resume [batch]",
+		Short: "Finish an interrupted bulk, watch or GUI batch",
+		Long: `Resume runs the unfinished jobs of a batch recorded in the job store.
+Jobs whose output still matches its recorded checksum are skipped. Without
+a batch it lists the batches that have unfinished or failed jobs.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: runResume,
+	}
+	resumeCmd.Flags().BoolVar(&resumeRetryFailed, "retry-failed", false, "Also rerun jobs that failed")
+	resumeCmd.Flags().BoolVar(&resumeAll, "all", false, "Resume every batch")
+	resumeCmd.Flags().IntVarP(&bulkJobs, "jobs", "j", 0, "Number of conversions to run at once (default: one per CPU)")
+	resumeCmd.Flags().BoolVar(&bulkFailFast, "fail-fast", false, "Stop at the first failure")
+
 	// Add subcommands
-	rootCmd.AddCommand(convertCmd, frameCmd, cropCmd, resizeCmd, infoCmd)
+	rootCmd.AddCommand(convertCmd, frameCmd, gifCmd, trimCmd, concatCmd, splitCmd, waveformCmd, cropCmd, resizeCmd, infoCmd, loudnessCmd, formatsCmd, presetsCmd, runCmd, watchCmd, serveCmd, resumeCmd)
 
-	if err := rootCmd.Execute(); err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	if err := formats.DefaultLoadError(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: ignoring formats file: %v\n", err)
+	}
+	if err := presets.DefaultLoadError(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: ignoring presets file: %v\n", err)
+	}
+
+	// Ctrl-C cancels the command context, which stops the running tool and
+	// removes its partial output.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	cmd, err := rootCmd.ExecuteContextC(ctx)
+	if err != nil && ctx.Err() != nil {
+		stop()
+		fmt.Fprintln(os.Stderr, "Cancelled")
+		os.Exit(130)
+	}
+	if err != nil {
+		if !errors.Is(err, errReported) {
+			emit(result{Command: cmd.Name(), Status: statusFailed, Error: err.Error()})
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		}
 		os.Exit(1)
 	}
 }
 
-func runConvert(cmd *cobra.Command, args []string) {
+func runConvert(cmd *cobra.Command, args []string) error {
+	preset, err := selectedPreset()
+	if err != nil {
+		return err
+	}
+
 	if bulkDir != "" {
-		runBulkConvert(args[0])
-		return
+		targetFormat := ""
+		if len(args) > 0 {
+			targetFormat = args[0]
+		} else if preset != nil {
+			targetFormat = preset.Format
+		}
+		return runBulkConvert(cmd.Context(), targetFormat)
 	}
 
+	// A preset names the format, so the output can default to the input
+	// with the preset's extension.
+	if outputFile == "" && preset != nil && inputFile != "" {
+		outputFile = strings.TrimSuffix(inputFile, filepath.Ext(inputFile)) + preset.Ext()
+		if outputFile == inputFile {
+			return fmt.Errorf("%s is already %s; pass --output", inputFile, preset.Format)
+		}
+	}
 	if inputFile == "" || outputFile == "" {
-		fmt.Println("Error: Both --input and --output flags are required")
-		return
+		return fmt.Errorf("both --input and --output flags are required")
 	}
 
 	c := converter.NewConverter()
 
-	options := make(map[string]string)
-	if quality != "" {
-		options["quality"] = quality
+	if explainPlan {
+		return printPlan(c, inputFile, outputFile)
 	}
 
-	req := converter.ConversionRequest{
-		InputPath:  inputFile,
-		OutputPath: outputFile,
-		Options:    options,
+	options, err := conversionOptions()
+	if err != nil {
+		return err
 	}
 
-	if err := c.Convert(req); err != nil {
-		fmt.Printf("Error converting file: %v\n", err)
-		return
+	op := startOperation("convert", inputFile, outputFile)
+	err = runWithProgress("Converting", func(progress chan float64) error {
+		return c.ConvertContext(cmd.Context(), converter.ConversionRequest{
+			InputPath:  inputFile,
+			OutputPath: outputFile,
+			Options:    options,
+			Progress:   progress,
+		})
+	})
+	if err := op.finish(err, "Error converting file"); err != nil {
+		return err
 	}
 
-	fmt.Printf("Successfully converted %s to %s\n", inputFile, outputFile)
+	logf("Successfully converted %s to %s\n", inputFile, outputFile)
+	return nil
 }
 
-func runBulkConvert(targetFormat string) {
-	if bulkDir == "" || outputFormat == "" {
-		fmt.Println("Error: Both --bulk and --format flags are required for bulk conversion")
-		return
+type planStepInfo struct {
+	Backend   string `json:"backend"`
+	From      string `json:"from"`
+	To        string `json:"to"`
+	Available bool   `json:"available"`
+}
+
+func printPlan(c *converter.Converter, input, output string) error {
+	op := startOperation("convert", input, output)
+	plan, err := c.Plan(filepath.Ext(input), filepath.Ext(output))
+	if err != nil {
+		return op.finish(err, "Error")
 	}
 
-	// Implementation for bulk conversion
-	fmt.Printf("Bulk conversion not yet implemented for directory: %s\n", bulkDir)
+	steps := make([]planStepInfo, len(plan.Steps))
+	for i, step := range plan.Steps {
+		steps[i] = planStepInfo{
+			Backend:   step.Backend.Name(),
+			From:      step.From,
+			To:        step.To,
+			Available: step.Backend.Available(),
+		}
+	}
+	op.Status = statusSkipped
+	op.Detail = "plan only"
+	op.Data = map[string]any{"cost": plan.Cost, "steps": steps}
+	op.finish(nil, "")
+
+	logf("Plan for %s -> %s (cost %d):\n", input, output, plan.Cost)
+	for i, step := range steps {
+		status := ""
+		if !step.Available {
+			status = " (not installed)"
+		}
+		logf("  %d. %s -> %s via %s%s\n", i+1, step.From, step.To, step.Backend, status)
+	}
+	return nil
 }
 
-func runFrame(cmd *cobra.Command, args []string) {
+func runFrame(cmd *cobra.Command, args []string) error {
 	timestamp := args[0]
 
 	if inputFile == "" || outputFile == "" {
-		fmt.Println("Error: Both --input and --output flags are required")
-		return
+		return fmt.Errorf("both --input and --output flags are required")
 	}
 
 	fe := video.NewFrameExtractor()
@@ -156,18 +456,18 @@ func runFrame(cmd *cobra.Command, args []string) {
 		Height:     height,
 	}
 
-	if err := fe.ExtractFrame(req); err != nil {
-		fmt.Printf("Error extracting frame: %v\n", err)
-		return
+	op := startOperation("frame", inputFile, outputFile)
+	if err := op.finish(fe.ExtractFrameContext(cmd.Context(), req), "Error extracting frame"); err != nil {
+		return err
 	}
 
-	fmt.Printf("Successfully extracted frame at %s to %s\n", timestamp, outputFile)
+	logf("Successfully extracted frame at %s to %s\n", timestamp, outputFile)
+	return nil
 }
 
-func runCrop(cmd *cobra.Command, args []string) {
+func runCrop(cmd *cobra.Command, args []string) error {
 	if inputFile == "" || outputFile == "" {
-		fmt.Println("Error: Both --input and --output flags are required")
-		return
+		return fmt.Errorf("both --input and --output flags are required")
 	}
 
 	processor := image.NewProcessor()
@@ -181,18 +481,18 @@ func runCrop(cmd *cobra.Command, args []string) {
 		Quality:    parseInt(quality),
 	}
 
-	if err := processor.Crop(req); err != nil {
-		fmt.Printf("Error cropping image: %v\n", err)
-		return
+	op := startOperation("crop", inputFile, outputFile)
+	if err := op.finish(processor.CropContext(cmd.Context(), req), "Error cropping image"); err != nil {
+		return err
 	}
 
-	fmt.Printf("Successfully cropped %s to %s\n", inputFile, outputFile)
+	logf("Successfully cropped %s to %s\n", inputFile, outputFile)
+	return nil
 }
 
-func runResize(cmd *cobra.Command, args []string) {
+func runResize(cmd *cobra.Command, args []string) error {
 	if inputFile == "" || outputFile == "" {
-		fmt.Println("Error: Both --input and --output flags are required")
-		return
+		return fmt.Errorf("both --input and --output flags are required")
 	}
 
 	processor := image.NewProcessor()
@@ -204,51 +504,110 @@ func runResize(cmd *cobra.Command, args []string) {
 		Quality:    parseInt(quality),
 	}
 
-	if err := processor.Resize(req); err != nil {
-		fmt.Printf("Error resizing image: %v\n", err)
-		return
+	op := startOperation("resize", inputFile, outputFile)
+	if err := op.finish(processor.ResizeContext(cmd.Context(), req), "Error resizing image"); err != nil {
+		return err
 	}
 
-	fmt.Printf("Successfully resized %s to %s\n", inputFile, outputFile)
+	logf("Successfully resized %s to %s\n", inputFile, outputFile)
+	return nil
 }
 
-func runInfo(cmd *cobra.Command, args []string) {
+func runInfo(cmd *cobra.Command, args []string) error {
 	if inputFile == "" {
-		fmt.Println("Error: --input flag is required")
-		return
+		return fmt.Errorf("--input flag is required")
 	}
 
 	ext := getExt(inputFile)
+	op := startOperation("info", inputFile, "")
 
-	switch ext {
-	case ".mp4", ".avi", ".mkv", ".mov", ".wmv":
+	switch formats.Default().Category(ext) {
+	case formats.CategoryVideo:
 		fe := video.NewFrameExtractor()
-		info, err := fe.GetVideoInfo(inputFile)
+		info, err := fe.GetVideoInfoContext(cmd.Context(), inputFile)
 		if err != nil {
-			fmt.Printf("Error getting video info: %v\n", err)
-			return
+			return op.finish(err, "Error getting video info")
 		}
-		fmt.Printf("Video Information:\n")
-		fmt.Printf("  Duration: %s\n", info.Duration)
-		fmt.Printf("  Dimensions: %dx%d\n", info.Width, info.Height)
-		fmt.Printf("  Codec: %s\n", info.Codec)
-		fmt.Printf("  Frame Rate: %s\n", info.FrameRate)
+		op.Data = info
+		op.finish(nil, "")
+
+		logf("Video Information:\n")
+		logf("  Duration: %s\n", info.Duration)
+		logf("  Dimensions: %dx%d\n", info.Width, info.Height)
+		logf("  Codec: %s\n", info.Codec)
+		logf("  Frame Rate: %s\n", info.FrameRate)
+		logf("  Bitrate: %s\n", info.Bitrate)
+		logChapters(info.Probe.Chapters)
+
+	case formats.CategoryAudio:
+		info, err := probe.Probe(cmd.Context(), inputFile)
+		if err != nil {
+			return op.finish(err, "Error getting audio info")
+		}
+		stream := info.AudioStream()
+		if stream == nil {
+			return op.finish(fmt.Errorf("no audio stream in %s", inputFile), "Error getting audio info")
+		}
+		op.Data = info
+		op.finish(nil, "")
+
+		logf("Audio Information:\n")
+		logf("  Duration: %s\n", formatClock(info.Format.Duration))
+		logf("  Codec: %s\n", stream.CodecName)
+		logf("  Sample Rate: %d Hz\n", stream.SampleRate)
+		logf("  Channels: %d\n", stream.Channels)
+		if bitrate := info.Format.BitRate; bitrate > 0 {
+			logf("  Bitrate: %d kb/s\n", bitrate/1000)
+		}
+		logChapters(info.Chapters)
 
-	case ".jpg", ".jpeg", ".png", ".gif", ".bmp", ".webp":
+	case formats.CategoryImage:
 		processor := image.NewProcessor()
 		info, err := processor.GetImageInfo(inputFile)
 		if err != nil {
-			fmt.Printf("Error getting image info: %v\n", err)
-			return
+			return op.finish(err, "Error getting image info")
 		}
-		fmt.Printf("Image Information:\n")
-		fmt.Printf("  Dimensions: %dx%d\n", info.Width, info.Height)
-		fmt.Printf("  Format: %s\n", info.Format)
-		fmt.Printf("  File Size: %d bytes\n", info.FileSize)
+		op.Data = info
+		op.finish(nil, "")
+
+		logf("Image Information:\n")
+		logf("  Dimensions: %dx%d\n", info.Width, info.Height)
+		logf("  Format: %s\n", info.Format)
+		logf("  File Size: %d bytes\n", info.FileSize)
 
 	default:
-		fmt.Printf("Unsupported file type for info: %s\n", ext)
+		return op.finish(fmt.Errorf("unsupported file type for info: %s", ext), "Error")
+	}
+	return nil
+}
+
+func logChapters(chapters []probe.Chapter) {
+	if len(chapters) == 0 {
+		return
+	}
+	logf("  Chapters:\n")
+	for i, chapter := range chapters {
+		logf("    %2d  %s - %s  %s\n", i+1, formatClock(chapter.Start), formatClock(chapter.End), chapter.Title)
+	}
+}
+
+func runFormats(cmd *cobra.Command, args []string) error {
+	op := startOperation("formats", "", "")
+	op.Data = formats.Default().Formats()
+	op.finish(nil, "")
+
+	w := tabwriter.NewWriter(logOut(), 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "EXT\tNAME\tCATEGORY\tMIME\tREAD\tWRITE\tALIASES")
+	for _, f := range formats.Default().Formats() {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%v\t%v\t%s\n",
+			f.Ext, f.Name, f.Category, f.MIME, f.Read, f.Write, strings.Join(f.Aliases, ","))
+	}
+	w.Flush()
+
+	if path := formats.ConfigPath(); path != "" && formats.DefaultLoadError() == nil {
+		logf("\nIncludes formats from %s\n", path)
 	}
+	return nil
 }
 
 func getExt(filename string) string {
@@ -0,0 +1,44 @@
+package main
+
+var (
+	videoCodec    string
+	audioCodec    string
+	pixelFormat   string
+	codecProfile  string
+	codecLevel    string
+	encoderPreset string
+	targetSize    string
+)
+
+// conversionOptions builds the options for a conversion: the preset's
+// options, if any, with --quality, the codec flags and the loudness flags
+// on top.
+func conversionOptions() (map[string]string, error) {
+	preset, err := selectedPreset()
+	if err != nil {
+		return nil, err
+	}
+
+	options := make(map[string]string)
+	if preset != nil {
+		options = preset.Merge(nil)
+	}
+	for key, value := range map[string]string{
+		"quality":        quality,
+		"video_codec":    videoCodec,
+		"audio_codec":    audioCodec,
+		"pix_fmt":        pixelFormat,
+		"profile":        codecProfile,
+		"level":          codecLevel,
+		"encoder_preset": encoderPreset,
+		"target_size":    targetSize,
+		"loudness":       loudnessTarget,
+		"true_peak":      truePeak,
+		"lra":            loudnessRange,
+	} {
+		if value != "" {
+			options[key] = value
+		}
+	}
+	return options, nil
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"goverter/pkg/converter"
+)
+
+const (
+	outputText = "text"
+	outputJSON = "json"
+)
+
+// outputMode is the global --output-format flag. The subcommands already
+// use -o/--output for the output file, which would shadow a global
+// --output, hence the longer name.
+var outputMode string
+
+// errReported is returned by commands that have already reported their
+// failure, so main only has to set the exit code.
+var errReported = errors.New("failure already reported")
+
+// result is the JSON object written to stdout for every operation in JSON
+// mode, one per line.
+type result struct {
+	Command    string `json:"command"`
+	Status     string `json:"status"` // "ok", "failed" or "skipped"
+	Input      string `json:"input,omitempty"`
+	Output     string `json:"output,omitempty"`
+	DurationMS int64  `json:"duration_ms"`
+	InputSize  int64  `json:"input_size,omitempty"`
+	OutputSize int64  `json:"output_size,omitempty"`
+	Error      string `json:"error,omitempty"`
+	Hint       string `json:"hint,omitempty"`
+	Detail     string `json:"detail,omitempty"`
+	Data       any    `json:"data,omitempty"`
+}
+
+const (
+	statusOK      = "ok"
+	statusFailed  = "failed"
+	statusSkipped = "skipped"
+)
+
+func validateOutputMode() error {
+	switch outputMode {
+	case outputText, outputJSON:
+		return nil
+	default:
+		return fmt.Errorf("invalid --output-format %q (want %q or %q)", outputMode, outputText, outputJSON)
+	}
+}
+
+func jsonOutput() bool {
+	return outputMode == outputJSON
+}
+
+// logOut receives human-readable messages: stdout normally, stderr in JSON
+// mode so that stdout only carries results.
+func logOut() io.Writer {
+	if jsonOutput() {
+		return os.Stderr
+	}
+	return os.Stdout
+}
+
+func logf(format string, args ...any) {
+	fmt.Fprintf(logOut(), format, args...)
+}
+
+// emit writes r to stdout in JSON mode and does nothing otherwise.
+func emit(r result) {
+	if !jsonOutput() {
+		return
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetEscapeHTML(false)
+	encoder.Encode(r)
+}
+
+// operation times one command run and builds its result.
+type operation struct {
+	result
+	start time.Time
+}
+
+func startOperation(command, input, output string) *operation {
+	return &operation{
+		result: result{Command: command, Input: input, Output: output},
+		start:  time.Now(),
+	}
+}
+
+// finish records err, emits the result and returns errReported on failure.
+// In text mode a failure is printed to stderr after failMsg.
+func (op *operation) finish(err error, failMsg string) error {
+	fillResult(&op.result, time.Since(op.start), err)
+	emit(op.result)
+
+	if err != nil {
+		if !jsonOutput() {
+			printConversionError(failMsg, err)
+		}
+		return errReported
+	}
+	return nil
+}
+
+// fillResult sets the status, timing, file sizes and error of r.
+func fillResult(r *result, elapsed time.Duration, err error) {
+	r.DurationMS = elapsed.Milliseconds()
+	r.InputSize = fileSize(r.Input)
+	if err != nil {
+		r.Status = statusFailed
+		r.Error = err.Error()
+		r.Hint = converter.ErrorHint(err)
+		return
+	}
+	if r.Status == "" {
+		r.Status = statusOK
+	}
+	r.OutputSize = fileSize(r.Output)
+}
+
+func fileSize(path string) int64 {
+	if path == "" {
+		return 0
+	}
+	stat, err := os.Stat(path)
+	if err != nil || stat.IsDir() {
+		return 0
+	}
+	return stat.Size()
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"goverter/pkg/presets"
+)
+
+var presetName string
+
+// selectedPreset returns the preset named by --preset, or nil without one.
+func selectedPreset() (*presets.Preset, error) {
+	if presetName == "" {
+		return nil, nil
+	}
+	preset, err := presets.Default().Get(presetName)
+	if err != nil {
+		return nil, err
+	}
+	return &preset, nil
+}
+
+func runPresets(cmd *cobra.Command, args []string) error {
+	op := startOperation("presets", "", "")
+	op.Data = presets.Default().Presets()
+	op.finish(nil, "")
+
+	w := tabwriter.NewWriter(logOut(), 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tFORMAT\tOPTIONS\tDESCRIPTION")
+	for _, p := range presets.Default().Presets() {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", p.Name, p.Format, formatOptions(p.Options), p.Description)
+	}
+	w.Flush()
+
+	if path := presets.ConfigPath(); path != "" && presets.DefaultLoadError() == nil {
+		logf("\nIncludes presets from %s\n", path)
+	}
+	return nil
+}
+
+func formatOptions(options map[string]string) string {
+	pairs := make([]string, 0, len(options))
+	for key, value := range options {
+		pairs = append(pairs, key+"="+value)
+	}
+	sort.Strings(pairs)
+	if len(pairs) == 0 {
+		return "-"
+	}
+	return strings.Join(pairs, ",")
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+const progressBarWidth = 30
+
+// runWithProgress hands run a progress channel and draws a bar on stderr
+// while values arrive. The bar is only drawn once the first value shows up,
+// so conversions that never report progress stay quiet.
+func runWithProgress(label string, run func(progress chan float64) error) error {
+	progress := make(chan float64, 16)
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		drawn := false
+		for value := range progress {
+			drawProgress(os.Stderr, label, value)
+			drawn = true
+		}
+		if drawn {
+			fmt.Fprintln(os.Stderr)
+		}
+	}()
+
+	err := run(progress)
+	close(progress)
+	<-done
+	return err
+}
+
+func drawProgress(w io.Writer, label string, value float64) {
+	if value < 0 {
+		value = 0
+	}
+	if value > 1 {
+		value = 1
+	}
+
+	filled := int(value * progressBarWidth)
+	bar := strings.Repeat("#", filled) + strings.Repeat(".", progressBarWidth-filled)
+	fmt.Fprintf(w, "\r%s [%s] %3.0f%%", label, bar, value*100)
+}
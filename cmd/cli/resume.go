@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"goverter/pkg/converter"
+	"goverter/pkg/jobstore"
+	"goverter/pkg/scheduler"
+)
+
+var (
+	jobStorePath      string
+	resumeRetryFailed bool
+	resumeAll         bool
+)
+
+// openJobStore opens the journal from --job-store or the default location.
+func openJobStore() (*jobstore.Store, error) {
+	if jobStorePath != "" {
+		return jobstore.Open(jobStorePath)
+	}
+	return jobstore.OpenDefault()
+}
+
+// openJobStoreOrWarn is for commands that work without a journal: they
+// warn and carry on rather than refuse to convert.
+func openJobStoreOrWarn() *jobstore.Store {
+	store, err := openJobStore()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: job store unavailable, progress will not be resumable: %v\n", err)
+		return nil
+	}
+	return store
+}
+
+func runResume(cmd *cobra.Command, args []string) error {
+	store, err := openJobStore()
+	if err != nil {
+		return err
+	}
+
+	if len(args) == 0 && !resumeAll {
+		listResumable(store)
+		return nil
+	}
+
+	batch := ""
+	if len(args) > 0 {
+		batch = args[0]
+	}
+	jobs := store.Jobs(batch)
+	if len(jobs) == 0 {
+		return fmt.Errorf("no jobs in batch %q", batch)
+	}
+
+	resumable := store.Resumable(batch, resumeRetryFailed)
+	skipped := len(jobs) - len(resumable)
+	var (
+		requests []converter.ConversionRequest
+		ids      []string
+	)
+	for _, job := range resumable {
+		requests = append(requests, converter.ConversionRequest{
+			InputPath:  job.Input,
+			OutputPath: job.Output,
+			Options:    job.Options,
+		})
+		ids = append(ids, job.ID)
+	}
+
+	logf("Resuming %d job(s); skipping %d completed or failed\n", len(requests), skipped)
+	failed := resumeJobs(cmd.Context(), store, ids, requests)
+
+	if cmd.Context().Err() != nil {
+		return cmd.Context().Err()
+	}
+	if failed > 0 {
+		logf("%d job(s) failed; rerun with --retry-failed to try them again\n", failed)
+		return errReported
+	}
+	return nil
+}
+
+func resumeJobs(ctx context.Context, store *jobstore.Store, ids []string, requests []converter.ConversionRequest) int {
+	failed := 0
+	c := converter.NewConverter()
+
+	runBulkRequests(ctx, c, requests, func(i int, err error, elapsed time.Duration) {
+		job, _ := store.Get(ids[i])
+		out := result{Command: "resume", Input: job.Input, Output: job.Output}
+
+		if errors.Is(err, scheduler.ErrSkipped) {
+			out.Status = statusSkipped
+			out.Detail = "not started"
+			fillResult(&out, elapsed, nil)
+			emit(out)
+			return
+		}
+
+		if storeErr := store.Finish(ids[i], err); storeErr != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to record job %s: %v\n", ids[i], storeErr)
+		}
+		fillResult(&out, elapsed, err)
+		emit(out)
+
+		if err != nil {
+			failed++
+			if !jsonOutput() {
+				printConversionError("Failed "+job.Input, err)
+			}
+			return
+		}
+		logf("Converted %s -> %s\n", job.Input, job.Output)
+	})
+	return failed
+}
+
+// listResumable prints the batches that still have unfinished jobs.
+func listResumable(store *jobstore.Store) {
+	type summary struct {
+		batch                        string
+		total, done, failed, pending int
+		updated                      time.Time
+	}
+	byBatch := make(map[string]*summary)
+	for _, job := range store.Jobs("") {
+		s, ok := byBatch[job.Batch]
+		if !ok {
+			s = &summary{batch: job.Batch}
+			byBatch[job.Batch] = s
+		}
+		s.total++
+		switch job.Status {
+		case jobstore.StatusDone:
+			s.done++
+		case jobstore.StatusFailed:
+			s.failed++
+		default:
+			s.pending++
+		}
+		if job.Updated.After(s.updated) {
+			s.updated = job.Updated
+		}
+	}
+
+	var summaries []*summary
+	for _, s := range byBatch {
+		if s.pending > 0 || s.failed > 0 {
+			summaries = append(summaries, s)
+		}
+	}
+	sort.Slice(summaries, func(a, b int) bool { return summaries[a].updated.After(summaries[b].updated) })
+
+	for _, s := range summaries {
+		emit(result{Command: "resume", Status: statusSkipped, Detail: "resumable batch", Data: map[string]any{
+			"batch": s.batch, "total": s.total, "done": s.done, "failed": s.failed, "pending": s.pending, "updated": s.updated,
+		}})
+	}
+
+	if len(summaries) == 0 {
+		logf("Nothing to resume in %s\n", store.Path())
+		return
+	}
+	w := tabwriter.NewWriter(logOut(), 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "BATCH\tPENDING\tFAILED\tDONE\tTOTAL\tUPDATED")
+	for _, s := range summaries {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\t%s\n", s.batch, s.pending, s.failed, s.done, s.total, s.updated.Format("2006-01-02 15:04"))
+	}
+	w.Flush()
+	logf("\nRun 'goverter resume <batch>' to continue one of them.\n")
+}
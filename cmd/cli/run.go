@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"goverter/pkg/converter"
+	"goverter/pkg/pipeline"
+)
+
+var runDryRun bool
+
+func runJob(cmd *cobra.Command, args []string) error {
+	job, err := pipeline.Load(args[0])
+	if err != nil {
+		return err
+	}
+
+	runner := pipeline.NewRunner()
+	plan, err := runner.Plan(job)
+	if err != nil {
+		return err
+	}
+
+	if runDryRun {
+		return printCommands(cmd, runner, plan)
+	}
+
+	err = runner.Run(cmd.Context(), job, plan, func(r pipeline.StepResult) {
+		out := result{Command: "run", Input: r.Step.Input, Output: r.Step.Output, Detail: r.Step.String()}
+		switch {
+		case r.Skipped:
+			out.Status = statusSkipped
+			logf("skipped %s\n", r.Step)
+		case r.Err != nil:
+			if !jsonOutput() {
+				printConversionError("failed "+r.Step.String(), r.Err)
+			}
+		default:
+			logf("done    %s\n", r.Step)
+		}
+		fillResult(&out, r.Duration, r.Err)
+		emit(out)
+	})
+	if err != nil {
+		if cmd.Context().Err() != nil {
+			return err
+		}
+		logf("%v\n", err)
+		return errReported
+	}
+	return nil
+}
+
+// printCommands prints the commands every planned step would run, under a
+// "# step" line describing it.
+func printCommands(cmd *cobra.Command, runner *pipeline.Runner, plan *pipeline.Plan) error {
+	for _, task := range plan.Tasks {
+		for _, step := range task.Steps {
+			commands, err := runner.Commands(cmd.Context(), step)
+			if err != nil {
+				return fmt.Errorf("planning %s: %w", step, err)
+			}
+			emit(result{
+				Command: "run",
+				Status:  statusSkipped,
+				Input:   step.Input,
+				Output:  step.Output,
+				Detail:  "dry run: " + step.String(),
+				Data:    commands,
+			})
+
+			logf("# %s\n", step)
+			if len(commands) == 0 {
+				logf("(done in-process)\n")
+			}
+			for _, argv := range commands {
+				logf("%s\n", converter.CommandLine(argv))
+			}
+		}
+	}
+	return nil
+}
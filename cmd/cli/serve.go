@@ -0,0 +1,35 @@
+package main
+
+import (
+	"time"
+
+	"github.com/spf13/cobra"
+	"goverter/pkg/converter"
+	"goverter/pkg/server"
+)
+
+var (
+	serveAddr        string
+	serveRoot        string
+	serveDataDir     string
+	serveMaxUploadMB int64
+	serveJobs        int
+	serveJobTTL      time.Duration
+)
+
+func runServe(cmd *cobra.Command, args []string) error {
+	srv, err := server.New(cmd.Context(), converter.NewConverter(), server.Config{
+		Addr:      serveAddr,
+		Root:      serveRoot,
+		DataDir:   serveDataDir,
+		MaxUpload: serveMaxUploadMB << 20,
+		Workers:   serveJobs,
+		JobTTL:    serveJobTTL,
+	})
+	if err != nil {
+		return err
+	}
+
+	logf("Serving on http://%s (root %s)\n", serveAddr, serveRoot)
+	return srv.ListenAndServe(cmd.Context())
+}
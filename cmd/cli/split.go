@@ -0,0 +1,160 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"goverter/pkg/converter"
+)
+
+var (
+	splitOutDir     string
+	splitFormat     string
+	splitNoise      float64
+	splitMinSilence float64
+	splitMinLength  string
+	splitMaxLength  string
+	splitManifest   string
+	splitStrip      bool
+	splitChapters   bool
+	splitName       string
+)
+
+func runSplit(cmd *cobra.Command, args []string) error {
+	if inputFile == "" {
+		return fmt.Errorf("the --input flag is required")
+	}
+	if splitStrip && splitChapters {
+		return fmt.Errorf("--strip and --by-chapters cannot be combined")
+	}
+	if splitStrip {
+		return runStripSilence(cmd)
+	}
+	if splitChapters {
+		for _, name := range []string{"noise", "min-silence", "min-length", "max-length"} {
+			if cmd.Flags().Changed(name) {
+				return fmt.Errorf("--%s applies to splitting at silences, not --by-chapters", name)
+			}
+		}
+	} else if cmd.Flags().Changed("name") {
+		return fmt.Errorf("--name needs --by-chapters")
+	}
+
+	minLength, err := splitLength("--min-length", splitMinLength)
+	if err != nil {
+		return err
+	}
+	maxLength, err := splitLength("--max-length", splitMaxLength)
+	if err != nil {
+		return err
+	}
+
+	outDir := splitOutDir
+	if outDir == "" {
+		outDir = filepath.Dir(inputFile)
+	}
+	manifest := splitManifest
+	if manifest == "" {
+		base := strings.TrimSuffix(filepath.Base(inputFile), filepath.Ext(inputFile))
+		manifest = filepath.Join(outDir, base+"_chunks.json")
+	}
+	options := make(map[string]string)
+	if quality != "" {
+		options["quality"] = quality
+	}
+
+	c := converter.NewConverter()
+	op := startOperation("split", inputFile, manifest)
+	var chunks []converter.Chunk
+	err = runWithProgress("Splitting", func(progress chan float64) error {
+		var err error
+		chunks, err = c.Split(cmd.Context(), converter.SplitRequest{
+			InputPath:    inputFile,
+			OutputDir:    outDir,
+			Format:       splitFormat,
+			Silence:      converter.SilenceOptions{Threshold: splitNoise, MinLength: splitMinSilence},
+			MinChunk:     minLength,
+			MaxChunk:     maxLength,
+			Options:      options,
+			ByChapters:   splitChapters,
+			NameTemplate: splitName,
+			ManifestPath: manifest,
+			Progress:     progress,
+		})
+		return err
+	})
+	op.Data = chunks
+	if err := op.finish(err, "Error splitting"); err != nil {
+		return err
+	}
+
+	for _, chunk := range chunks {
+		logf("%s  %s - %s\n", chunk.Path, formatClock(chunk.Start), formatClock(chunk.End))
+	}
+	logf("Split %s into %d chunks; manifest written to %s\n", inputFile, len(chunks), manifest)
+	return nil
+}
+
+// runStripSilence converts the input to the output with its leading and
+// trailing silence cut off.
+func runStripSilence(cmd *cobra.Command) error {
+	output := outputFile
+	if output == "" {
+		ext := filepath.Ext(inputFile)
+		if splitFormat != "" {
+			ext = "." + strings.TrimPrefix(splitFormat, ".")
+		}
+		output = strings.TrimSuffix(inputFile, filepath.Ext(inputFile)) + "_stripped" + ext
+	}
+
+	options := map[string]string{"strip_silence": "true"}
+	if splitNoise != 0 {
+		options["silence_threshold"] = strconv.FormatFloat(splitNoise, 'f', -1, 64)
+	}
+	if splitMinSilence != 0 {
+		options["min_silence"] = strconv.FormatFloat(splitMinSilence, 'f', -1, 64)
+	}
+	if quality != "" {
+		options["quality"] = quality
+	}
+	if err := converter.ValidateOptions(filepath.Ext(output), options); err != nil {
+		return err
+	}
+
+	c := converter.NewConverter()
+	op := startOperation("split", inputFile, output)
+	err := runWithProgress("Stripping silence", func(progress chan float64) error {
+		return c.ConvertContext(cmd.Context(), converter.ConversionRequest{
+			InputPath:  inputFile,
+			OutputPath: output,
+			Options:    options,
+			Progress:   progress,
+		})
+	})
+	if err := op.finish(err, "Error stripping silence"); err != nil {
+		return err
+	}
+
+	logf("Successfully stripped silence from %s into %s\n", inputFile, output)
+	return nil
+}
+
+func splitLength(flag, value string) (float64, error) {
+	if value == "" {
+		return 0, nil
+	}
+	seconds, err := converter.ParseTimestamp(value)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", flag, err)
+	}
+	return seconds, nil
+}
+
+// formatClock formats seconds as H:MM:SS.mmm.
+func formatClock(seconds float64) string {
+	ms := int64(seconds*1000 + 0.5)
+	return fmt.Sprintf("%d:%02d:%02d.%03d", ms/3600000, ms/60000%60, ms/1000%60, ms%1000)
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"goverter/pkg/converter"
+)
+
+var (
+	trimStart    string
+	trimEnd      string
+	trimDuration string
+	trimKeep     string
+	trimRemove   string
+	trimAccurate bool
+)
+
+func runTrim(cmd *cobra.Command, args []string) error {
+	if inputFile == "" {
+		return fmt.Errorf("the --input flag is required")
+	}
+	segments, remove, err := trimSegments()
+	if err != nil {
+		return err
+	}
+	output := outputFile
+	if output == "" {
+		ext := filepath.Ext(inputFile)
+		output = strings.TrimSuffix(inputFile, ext) + "_trimmed" + ext
+	}
+
+	options := make(map[string]string)
+	if quality != "" {
+		options["quality"] = quality
+	}
+
+	c := converter.NewConverter()
+	op := startOperation("trim", inputFile, output)
+	err = runWithProgress("Trimming", func(progress chan float64) error {
+		return c.Trim(cmd.Context(), converter.TrimRequest{
+			InputPath:  inputFile,
+			OutputPath: output,
+			Segments:   segments,
+			Remove:     remove,
+			Accurate:   trimAccurate,
+			Options:    options,
+			Progress:   progress,
+		})
+	})
+	if err := op.finish(err, "Error trimming"); err != nil {
+		return err
+	}
+
+	logf("Successfully trimmed %s to %s\n", inputFile, output)
+	return nil
+}
+
+// trimSegments builds the segments from either --keep, --remove or the
+// --start/--end/--duration flags, and reports whether they are to be cut
+// out.
+func trimSegments() ([]converter.Segment, bool, error) {
+	ranged := trimStart != "" || trimEnd != "" || trimDuration != ""
+	switch {
+	case trimKeep != "" && trimRemove != "":
+		return nil, false, fmt.Errorf("use either --keep or --remove, not both")
+	case (trimKeep != "" || trimRemove != "") && ranged:
+		return nil, false, fmt.Errorf("--start, --end and --duration cannot be combined with --keep or --remove")
+	case trimKeep != "":
+		segments, err := converter.ParseSegments(trimKeep)
+		return segments, false, err
+	case trimRemove != "":
+		segments, err := converter.ParseSegments(trimRemove)
+		return segments, true, err
+	case !ranged:
+		return nil, false, fmt.Errorf("give --start, --end or --duration, or a --keep or --remove list")
+	case trimEnd != "" && trimDuration != "":
+		return nil, false, fmt.Errorf("use either --end or --duration, not both")
+	}
+
+	var segment converter.Segment
+	var err error
+	if trimStart != "" {
+		if segment.Start, err = converter.ParseTimestamp(trimStart); err != nil {
+			return nil, false, fmt.Errorf("--start: %w", err)
+		}
+	}
+	switch {
+	case trimEnd != "":
+		if segment.End, err = converter.ParseTimestamp(trimEnd); err != nil {
+			return nil, false, fmt.Errorf("--end: %w", err)
+		}
+		if segment.End <= segment.Start {
+			return nil, false, fmt.Errorf("--end must be after --start")
+		}
+	case trimDuration != "":
+		duration, err := converter.ParseTimestamp(trimDuration)
+		if err != nil || duration == 0 {
+			return nil, false, fmt.Errorf("--duration must be a positive length, got %q", trimDuration)
+		}
+		segment.End = segment.Start + duration
+	}
+	return []converter.Segment{segment}, false, nil
+}
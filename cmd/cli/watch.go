@@ -0,0 +1,61 @@
+package main
+
+import (
+	"time"
+
+	"github.com/spf13/cobra"
+	"goverter/pkg/converter"
+	"goverter/pkg/watch"
+)
+
+var (
+	watchTo        string
+	watchOut       string
+	watchDoneDir   string
+	watchFailedDir string
+	watchSettle    time.Duration
+)
+
+func runWatch(cmd *cobra.Command, args []string) error {
+	options := make(map[string]string)
+	if quality != "" {
+		options["quality"] = quality
+	}
+
+	store, err := openJobStore()
+	if err != nil {
+		return err
+	}
+
+	w, err := watch.New(converter.NewConverter(), watch.Options{
+		Dir:       args[0],
+		OutDir:    watchOut,
+		Format:    watchTo,
+		Options:   options,
+		DoneDir:   watchDoneDir,
+		FailedDir: watchFailedDir,
+		Settle:    watchSettle,
+		Store:     store,
+	})
+	if err != nil {
+		return err
+	}
+
+	logf("Watching %s for files to convert to %s (Ctrl-C to stop)\n", args[0], watchTo)
+	return w.Run(cmd.Context(), func(e watch.Event) {
+		out := result{Command: "watch", Input: e.Input, Output: e.Output}
+		if e.MovedTo != "" {
+			out.Detail = "original moved to " + e.MovedTo
+		}
+		fillResult(&out, e.Duration, e.Err)
+		emit(out)
+
+		if e.Err != nil {
+			if !jsonOutput() {
+				printConversionError("Failed "+e.Input, e.Err)
+			}
+			return
+		}
+		logf("Converted %s -> %s\n", e.Input, e.Output)
+	})
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"goverter/pkg/converter"
+)
+
+var (
+	waveformStyle      string
+	waveformColor      string
+	waveformBackground string
+	waveformSplit      bool
+	waveformLegend     bool
+)
+
+func runWaveform(cmd *cobra.Command, args []string) error {
+	if inputFile == "" {
+		return fmt.Errorf("the --input flag is required")
+	}
+	output := outputFile
+	if output == "" {
+		style := waveformStyle
+		if style == "" {
+			style = converter.StyleWaveform
+		}
+		output = strings.TrimSuffix(inputFile, getExt(inputFile)) + "_" + style + ".png"
+	}
+
+	c := converter.NewConverter()
+	req := converter.WaveformRequest{
+		InputPath:     inputFile,
+		OutputPath:    output,
+		Style:         waveformStyle,
+		Width:         width,
+		Height:        height,
+		Color:         waveformColor,
+		Background:    waveformBackground,
+		SplitChannels: waveformSplit,
+		Legend:        waveformLegend,
+	}
+
+	op := startOperation("waveform", inputFile, output)
+	if err := op.finish(c.DrawWaveform(cmd.Context(), req), "Error drawing "+req.Style); err != nil {
+		return err
+	}
+
+	logf("Successfully drew %s to %s\n", inputFile, output)
+	return nil
+}
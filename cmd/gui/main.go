@@ -1,21 +1,28 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/app"
+	"fyne.io/fyne/v2/canvas"
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/dialog"
 	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
 	"goverter/pkg/converter"
+	"goverter/pkg/formats"
 	"goverter/pkg/image"
+	"goverter/pkg/jobstore"
 	"goverter/pkg/media"
+	"goverter/pkg/presets"
 	"goverter/pkg/video"
 )
 
@@ -26,6 +33,7 @@ type GUI struct {
 	imageProcessor *image.Processor
 	frameExtractor *video.FrameExtractor
 	mediaPlayer    *media.Player
+	jobStore       *jobstore.Store // nil if the journal cannot be opened
 
 	// UI Elements
 	fileList      *widget.List
@@ -33,19 +41,25 @@ type GUI struct {
 	statusLabel   *widget.Label
 	progressBar   *widget.ProgressBar
 	outputFormat  *widget.Select
+	presetSelect  *widget.Select
 	qualitySlider *widget.Slider
 	qualityLabel  *widget.Label
 	outputDir     *widget.Entry
 	convertBtn    *widget.Button
+	cancelBtn     *widget.Button
 	addFilesBtn   *widget.Button
 	clearBtn      *widget.Button
 	playBtn       *widget.Button
 
+	// Cancels the running conversion, nil when idle
+	cancelConvert context.CancelFunc
+
 	// Current tab
 	currentTab       string
 	contentContainer *fyne.Container
 	pages            map[string]fyne.CanvasObject
 	previewArea      *fyne.Container
+	previewFile      string // the file whose preview is shown or loading
 }
 
 func main() {
@@ -59,7 +73,16 @@ func main() {
 		currentTab:     "upload",
 	}
 
+	// The journal lets 'goverter resume' finish a queue the GUI did not
+	// get through; conversions work without it.
+	if store, err := jobstore.OpenDefault(); err == nil {
+		g.jobStore = store
+	}
+
 	g.createUI()
+	if err := presets.DefaultLoadError(); err != nil {
+		g.updateStatus(fmt.Sprintf("⚠️ Ignoring presets file: %v", err))
+	}
 	g.window.ShowAndRun()
 }
 
@@ -214,6 +237,7 @@ func (g *GUI) createUploadPage() fyne.CanvasObject {
 		),
 		),
 		widget.NewCard("🔧 Tool Status", "", g.createToolStatus()),
+		previewCard,
 	)
 
 	return container.NewHSplit(leftPanel, rightPanel)
@@ -278,6 +302,7 @@ func (g *GUI) createConvertPage() fyne.CanvasObject {
 		g.addFilesBtn,
 		g.clearBtn,
 		g.convertBtn,
+		g.cancelBtn,
 	)
 
 	// Main layout (centered)
@@ -399,6 +424,14 @@ func (g *GUI) createDropArea() *widget.Card {
 	g.convertBtn = widget.NewButton("🔄 Convert Files", g.convertFiles)
 	g.convertBtn.Disable()
 
+	g.cancelBtn = widget.NewButton("⏹️ Cancel", func() {
+		if g.cancelConvert != nil {
+			g.cancelConvert()
+			g.updateStatus("⏹️ Cancelling...")
+		}
+	})
+	g.cancelBtn.Disable()
+
 	return widget.NewCard("", "", container.NewVBox(dropLabel, g.addFilesBtn, g.clearBtn))
 }
 
@@ -424,6 +457,11 @@ func (g *GUI) createDynamicConversionOptions() *fyne.Container {
 		g.qualityLabel.SetText(fmt.Sprintf("🎨 Quality: %.0f", value))
 	}
 
+	// Preset selection; a preset picks the format and adds its options. The
+	// quality slider still applies unless the preset sets its own quality
+	g.presetSelect = widget.NewSelect(append([]string{noPreset}, presets.Default().Names()...), g.selectPreset)
+	g.presetSelect.SetSelected(noPreset)
+
 	// Output directory
 	g.outputDir = widget.NewEntry()
 	g.outputDir.SetPlaceHolder("📁 Same as input directory")
@@ -442,6 +480,9 @@ func (g *GUI) createDynamicConversionOptions() *fyne.Container {
 	formatInfo.Wrapping = fyne.TextWrapWord
 
 	return container.NewVBox(
+		widget.NewLabel("🧩 Preset:"),
+		g.presetSelect,
+		widget.NewSeparator(),
 		widget.NewLabel("📂 Output Format:"),
 		g.outputFormat,
 		widget.NewSeparator(),
@@ -460,30 +501,12 @@ func (g *GUI) getValidOutputFormats() []string {
 		return []string{"mp4", "jpg", "mp3", "pdf"} // Default formats
 	}
 
-	// Get supported formats from converter
-	supportedFormats := g.converter.GetSupportedFormats()
-
-	// Determine input file types
-	inputTypes := make(map[string]bool)
-	for _, file := range g.files {
-		ext := strings.ToLower(filepath.Ext(file))
-		if ext != "" {
-			inputTypes[ext] = true
-		}
-	}
-
 	// Collect all possible output formats
 	allFormats := make(map[string]bool)
 
-	for category, formats := range supportedFormats {
-		for _, inputFormat := range formats.InputFormats {
-			if inputTypes["."+inputFormat] {
-				// Add all output formats for this input format
-				for _, outputFormat := range formats.OutputFormats {
-					allFormats[outputFormat] = true
-				}
-				break
-			}
+	for _, file := range g.files {
+		for _, outputFormat := range g.converter.GetOutputFormats(filepath.Ext(file)) {
+			allFormats[strings.TrimPrefix(outputFormat, ".")] = true
 		}
 	}
 
@@ -509,47 +532,61 @@ func (g *GUI) getValidOutputFormats() []string {
 	return result
 }
 
+const noPreset = "None"
+
+func (g *GUI) selectPreset(name string) {
+	preset, ok := presets.Default().Lookup(name)
+	if !ok {
+		g.qualitySlider.Enable()
+		return
+	}
+
+	format := strings.TrimPrefix(preset.Ext(), ".")
+	found := false
+	for _, option := range g.outputFormat.Options {
+		found = found || option == format
+	}
+	if !found {
+		g.outputFormat.Options = append(g.outputFormat.Options, format)
+	}
+	g.outputFormat.SetSelected(format)
+	if quality, err := strconv.ParseFloat(preset.Options["quality"], 64); err == nil {
+		g.qualitySlider.SetValue(quality)
+		g.qualitySlider.Disable()
+	} else {
+		g.qualitySlider.Enable()
+	}
+	if preset.Description != "" {
+		g.updateStatus(fmt.Sprintf("🧩 %s: %s", preset.Name, preset.Description))
+	}
+}
+
 func (g *GUI) updateConversionOptions(selectedFormat string) {
 	// Update format information
 	formatInfo := g.getFormatInfo(selectedFormat)
 
 	// Find the format info label and update it
 	// This is a simplified approach - in a real app you'd store the reference
-	g.updateStatus(fmt.Sprintf("📂 Selected format: %s", selectedFormat))
+	g.updateStatus(fmt.Sprintf("📂 Selected format: %s", formatInfo))
 }
 
 func (g *GUI) getFormatInfo(format string) string {
 	// Provide information about the selected format
-	descriptions := map[string]string{
-		"mp4":  "MP4 - Modern video format with good compression",
-		"avi":  "AVI - Classic video format, large file sizes",
-		"mkv":  "MKV - Flexible container for multiple tracks",
-		"mov":  "MOV - Apple QuickTime video format",
-		"jpg":  "JPG - Compressed image format for photos",
-		"png":  "PNG - Lossless image format with transparency",
-		"gif":  "GIF - Animated image format",
-		"mp3":  "MP3 - Compressed audio format",
-		"wav":  "WAV - Uncompressed audio format",
-		"flac": "FLAC - Lossless audio compression",
-		"pdf":  "PDF - Document format for sharing",
-		"txt":  "TXT - Plain text document",
-	}
-
-	if desc, exists := descriptions[format]; exists {
-		return desc
+	if f, ok := formats.Default().Lookup(format); ok && f.Description != "" {
+		return fmt.Sprintf("%s - %s", f.Name, f.Description)
 	}
 	return fmt.Sprintf("%s - Media format", strings.ToUpper(format))
 }
 
 func (g *GUI) createConversionOptions() *fyne.Container {
 	// Output format selection
-	formats := []string{
-		"mp4", "avi", "mkv", "mov", "wmv", "flv", "webm", "gif",
-		"jpg", "jpeg", "png", "bmp", "webp", "tiff",
-		"mp3", "wav", "flac", "aac", "ogg", "m4a",
-		"pdf", "txt", "html", "docx",
+	var outputFormats []string
+	for _, f := range formats.Default().Formats() {
+		if f.Write {
+			outputFormats = append(outputFormats, strings.TrimPrefix(f.Ext, "."))
+		}
 	}
-	g.outputFormat = widget.NewSelect(formats, nil)
+	g.outputFormat = widget.NewSelect(outputFormats, nil)
 	g.outputFormat.SetSelected("mp4")
 
 	// Quality slider
@@ -635,6 +672,8 @@ func (g *GUI) createVideoToolsSection() fyne.CanvasObject {
 	gifContainer := g.createGifTool(videoEntry)
 	audioContainer := g.createAudioExtractionTool(videoEntry)
 	infoContainer := g.createVideoInfoTool(videoEntry)
+	trimContainer := g.createTrimTool(videoEntry)
+	concatContainer := g.createConcatTool()
 
 	return container.NewVBox(
 		widget.NewCard("🎬 Select Video", "", container.NewHBox(videoEntry, selectVideoBtn)),
@@ -648,6 +687,11 @@ func (g *GUI) createVideoToolsSection() fyne.CanvasObject {
 			widget.NewCard("🎵 Extract Audio", "", audioContainer),
 			widget.NewCard("ℹ️ Video Info", "", infoContainer),
 		),
+		widget.NewSeparator(),
+		container.NewGridWithColumns(2,
+			widget.NewCard("✂️ Trim", "", trimContainer),
+			widget.NewCard("🔗 Join Files", "", concatContainer),
+		),
 	)
 }
 
@@ -668,6 +712,7 @@ func (g *GUI) createAudioToolsSection() fyne.CanvasObject {
 	// Tool options
 	convertContainer := g.createAudioConversionTool(audioEntry)
 	infoContainer := g.createAudioInfoTool(audioEntry)
+	trimContainer := g.createTrimTool(audioEntry)
 
 	return container.NewVBox(
 		widget.NewCard("🎵 Select Audio", "", container.NewHBox(audioEntry, selectAudioBtn)),
@@ -676,6 +721,8 @@ func (g *GUI) createAudioToolsSection() fyne.CanvasObject {
 			widget.NewCard("🔄 Convert", "", convertContainer),
 			widget.NewCard("ℹ️ Audio Info", "", infoContainer),
 		),
+		widget.NewSeparator(),
+		widget.NewCard("✂️ Trim", "", trimContainer),
 	)
 }
 
@@ -771,24 +818,113 @@ func (g *GUI) createFrameTool(videoEntry *widget.Entry) fyne.CanvasObject {
 }
 
 func (g *GUI) createGifTool(videoEntry *widget.Entry) fyne.CanvasObject {
+	gifFormat := widget.NewSelect([]string{"gif", "webp", "apng"}, nil)
+	gifFormat.SetSelected("gif")
 	gifFps := widget.NewEntry()
 	gifFps.SetText("10")
 	gifWidth := widget.NewEntry()
-	gifHeight := widget.NewEntry()
-	gifHeight.SetText("-1")
+	gifWidth.SetText("480")
+	gifStart := widget.NewEntry()
+	gifStart.SetPlaceHolder("00:00:00")
+	gifEnd := widget.NewEntry()
+	gifEnd.SetPlaceHolder("end of video")
+	gifDither := widget.NewSelect([]string{"sierra2_4a", "floyd_steinberg", "sierra2", "bayer", "none"}, nil)
+	gifDither.SetSelected("sierra2_4a")
+	gifColors := widget.NewEntry()
+	gifColors.SetText("256")
+	gifLoop := widget.NewEntry()
+	gifLoop.SetText("0")
+
+	// Palette settings only apply to GIFs
+	gifFormat.OnChanged = func(format string) {
+		if format == "gif" {
+			gifDither.Enable()
+			gifColors.Enable()
+		} else {
+			gifDither.Disable()
+			gifColors.Disable()
+		}
+	}
 
 	return container.NewVBox(
-		container.NewGridWithColumns(3,
+		container.NewGridWithColumns(4,
+			widget.NewLabel("Format:"), gifFormat,
 			widget.NewLabel("FPS:"), gifFps,
-			widget.NewLabel("Width:"), gifWidth,
-			widget.NewLabel("Height:"), gifHeight,
+			widget.NewLabel("Max width:"), gifWidth,
+			widget.NewLabel("Plays (0 = loop):"), gifLoop,
+			widget.NewLabel("Start:"), gifStart,
+			widget.NewLabel("End:"), gifEnd,
+			widget.NewLabel("Dither:"), gifDither,
+			widget.NewLabel("Colors:"), gifColors,
 		),
-		widget.NewButton("🎨 Convert to GIF", func() {
-			g.convertToGif(videoEntry.Text, gifFps.Text, gifWidth.Text, gifHeight.Text)
+		widget.NewButton("🎨 Create Animation", func() {
+			options := map[string]string{
+				"fps":   gifFps.Text,
+				"width": gifWidth.Text,
+				"start": gifStart.Text,
+				"end":   gifEnd.Text,
+				"loop":  gifLoop.Text,
+			}
+			if gifFormat.Selected == "gif" {
+				options["dither"] = gifDither.Selected
+				options["max_colors"] = gifColors.Text
+			}
+			g.convertToGif(videoEntry.Text, gifFormat.Selected, options)
 		}),
 	)
 }
 
+func (g *GUI) createTrimTool(mediaEntry *widget.Entry) fyne.CanvasObject {
+	trimStart := widget.NewEntry()
+	trimStart.SetPlaceHolder("00:00:00")
+	trimEnd := widget.NewEntry()
+	trimEnd.SetPlaceHolder("end of file")
+	trimSegments := widget.NewEntry()
+	trimSegments.SetPlaceHolder("or a list: 0:10-0:20, 1:00+30, 2:00-")
+	trimMode := widget.NewRadioGroup([]string{"Keep", "Remove"}, nil)
+	trimMode.SetSelected("Keep")
+	trimMode.Horizontal = true
+	trimAccurate := widget.NewCheck("Frame-accurate (re-encode)", nil)
+
+	run := newBackgroundRun()
+	run.start.SetText("✂️ Trim")
+	run.start.OnTapped = func() {
+		g.trimMedia(mediaEntry.Text, trimStart.Text, trimEnd.Text, trimSegments.Text, trimMode.Selected == "Remove", trimAccurate.Checked, run)
+	}
+
+	return container.NewVBox(
+		container.NewGridWithColumns(4,
+			widget.NewLabel("Start:"), trimStart,
+			widget.NewLabel("End:"), trimEnd,
+		),
+		trimSegments,
+		container.NewHBox(trimMode, trimAccurate),
+		run.controls(),
+	)
+}
+
+func (g *GUI) createConcatTool() fyne.CanvasObject {
+	crossfadeEntry := widget.NewEntry()
+	crossfadeEntry.SetText("0")
+	transitionSelect := widget.NewSelect(converter.Transitions, nil)
+	transitionSelect.SetSelected("fade")
+
+	run := newBackgroundRun()
+	run.start.SetText("🔗 Join")
+	run.start.OnTapped = func() {
+		g.concatFiles(crossfadeEntry.Text, transitionSelect.Selected, run)
+	}
+
+	return container.NewVBox(
+		widget.NewLabel("Joins the selected files in list order"),
+		container.NewGridWithColumns(2,
+			widget.NewLabel("Crossfade (s):"), crossfadeEntry,
+			widget.NewLabel("Transition:"), transitionSelect,
+		),
+		run.controls(),
+	)
+}
+
 func (g *GUI) createAudioExtractionTool(videoEntry *widget.Entry) fyne.CanvasObject {
 	audioBitrate := widget.NewSelect([]string{"128k", "192k", "256k", "320k"}, nil)
 	audioBitrate.SetSelected("192k")
@@ -811,7 +947,11 @@ func (g *GUI) createVideoInfoTool(videoEntry *widget.Entry) fyne.CanvasObject {
 }
 
 func (g *GUI) createAudioConversionTool(audioEntry *widget.Entry) fyne.CanvasObject {
-	audioFormat := widget.NewSelect([]string{"mp3", "wav", "flac", "aac", "ogg"}, nil)
+	var audioFormats []string
+	for _, ext := range formats.Default().OutputExtensions(formats.CategoryAudio) {
+		audioFormats = append(audioFormats, strings.TrimPrefix(ext, "."))
+	}
+	audioFormat := widget.NewSelect(audioFormats, nil)
 	audioFormat.SetSelected("mp3")
 
 	return container.NewVBox(
@@ -845,36 +985,123 @@ func (g *GUI) convertFiles() {
 		return
 	}
 
+	if g.cancelConvert != nil {
+		dialog.ShowError(fmt.Errorf("a conversion is already running"), g.window)
+		return
+	}
+
 	g.updateStatus("🔄 Converting files...")
 	g.progressBar.SetValue(0)
+	g.convertBtn.Disable()
 
+	ctx, cancel := context.WithCancel(context.Background())
+	g.cancelConvert = cancel
+	g.cancelBtn.Enable()
+
+	files := append([]string(nil), g.files...)
 	outputFormat := g.outputFormat.Selected
 	quality := fmt.Sprintf("%.0f", g.qualitySlider.Value)
+	options := map[string]string{"quality": quality}
+	if preset, ok := presets.Default().Lookup(g.presetSelect.Selected); ok {
+		options = preset.Merge(nil)
+		if _, ok := options["quality"]; !ok {
+			options["quality"] = quality
+		}
+	}
 	outputDir := g.outputDir.Text
 
-	for i, file := range g.files {
-		outputPath := g.generateOutputPath(file, outputFormat, outputDir)
-
-		req := converter.ConversionRequest{
-			InputPath:  file,
-			OutputPath: outputPath,
-			Options:    map[string]string{"quality": quality},
+	// Run off the UI thread so the progress bar keeps moving during long
+	// transcodes; widget updates are marshalled back with fyne.Do.
+	go func() {
+		defer fyne.Do(func() {
+			cancel()
+			g.cancelConvert = nil
+			g.cancelBtn.Disable()
+			g.convertBtn.Enable()
+		})
+
+		outputs := make([]string, len(files))
+		for i, file := range files {
+			outputs[i] = g.generateOutputPath(file, outputFormat, outputDir)
+		}
+		batch, jobIDs := g.journalQueue(files, outputs, options)
+		resumeHint := ""
+		if batch != "" {
+			resumeHint = fmt.Sprintf(" (resume with: goverter resume %s)", batch)
 		}
 
-		err := g.converter.Convert(req)
-		if err != nil {
-			dialog.ShowError(fmt.Errorf("failed to convert %s: %w", file, err), g.window)
-			g.updateStatus("❌ Conversion failed")
-			return
+		for i, file := range files {
+			i, file := i, file
+			outputPath := outputs[i]
+			if jobIDs != nil {
+				g.jobStore.Start(jobIDs[i])
+			}
+			fyne.Do(func() {
+				g.updateStatus(fmt.Sprintf("🔄 Converting %s (%d/%d)...", filepath.Base(file), i+1, len(files)))
+			})
+
+			progress := make(chan float64, 16)
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				for value := range progress {
+					overall := (float64(i) + value) / float64(len(files))
+					fyne.Do(func() { g.progressBar.SetValue(overall) })
+				}
+			}()
+
+			err := g.converter.ConvertContext(ctx, converter.ConversionRequest{
+				InputPath:  file,
+				OutputPath: outputPath,
+				Options:    options,
+				Progress:   progress,
+			})
+			close(progress)
+			<-done
+
+			if ctx.Err() != nil {
+				fyne.Do(func() { g.updateStatus("⏹️ Conversion cancelled" + resumeHint) })
+				return
+			}
+			if jobIDs != nil {
+				g.jobStore.Finish(jobIDs[i], err)
+			}
+			if err != nil {
+				fyne.Do(func() {
+					g.showConversionError(fmt.Errorf("failed to convert %s: %w", file, err))
+					g.updateStatus("❌ Conversion failed" + resumeHint)
+				})
+				return
+			}
+
+			overall := float64(i+1) / float64(len(files))
+			fyne.Do(func() { g.progressBar.SetValue(overall) })
 		}
 
-		// Update progress
-		progress := float64(i+1) / float64(len(g.files))
-		g.progressBar.SetValue(progress)
+		fyne.Do(func() {
+			g.updateStatus("✅ Conversion completed!")
+			dialog.ShowInformation("Success", "All files converted successfully!", g.window)
+		})
+	}()
+}
+
+// journalQueue records the queue as a job store batch and returns the batch
+// and job IDs, or "" and nil when there is no store.
+func (g *GUI) journalQueue(files, outputs []string, options map[string]string) (string, []string) {
+	if g.jobStore == nil {
+		return "", nil
 	}
 
-	g.updateStatus("✅ Conversion completed!")
-	dialog.ShowInformation("Success", "All files converted successfully!", g.window)
+	batch := "gui-" + jobstore.NewID()
+	ids := make([]string, len(files))
+	for i, file := range files {
+		job, err := g.jobStore.Add(jobstore.Job{Batch: batch, Input: file, Output: outputs[i], Options: options})
+		if err != nil {
+			return "", nil
+		}
+		ids[i] = job.ID
+	}
+	return batch, ids
 }
 
 func (g *GUI) cropImage(imagePath, x, y, width, height string) {
@@ -986,31 +1213,182 @@ func (g *GUI) extractFrame(videoPath, timestamp, width, height string) {
 	dialog.ShowInformation("Success", fmt.Sprintf("📸 Frame extracted to: %s", outputPath), g.window)
 }
 
-func (g *GUI) convertToGif(videoPath, fps, width, height string) {
+func (g *GUI) convertToGif(videoPath, format string, options map[string]string) {
 	if videoPath == "" {
 		dialog.ShowError(fmt.Errorf("please select a video file"), g.window)
 		return
 	}
 
-	outputPath := strings.TrimSuffix(videoPath, filepath.Ext(videoPath)) + ".gif"
+	outputPath := strings.TrimSuffix(videoPath, filepath.Ext(videoPath)) + "." + format
 
 	req := converter.ConversionRequest{
 		InputPath:  videoPath,
 		OutputPath: outputPath,
-		Options: map[string]string{
-			"fps":    fps,
-			"width":  width,
-			"height": height,
-		},
+		Options:    options,
 	}
 
 	err := g.converter.Convert(req)
 	if err != nil {
-		dialog.ShowError(fmt.Errorf("failed to convert to GIF: %w", err), g.window)
+		g.showConversionError(fmt.Errorf("failed to create animation: %w", err))
 		return
 	}
 
-	dialog.ShowInformation("Success", fmt.Sprintf("🎨 Video converted to GIF: %s", outputPath), g.window)
+	dialog.ShowInformation("Success", fmt.Sprintf("🎨 Animation saved to: %s", outputPath), g.window)
+}
+
+func (g *GUI) trimMedia(mediaPath, start, end, list string, remove, accurate bool, run *backgroundRun) {
+	if mediaPath == "" {
+		dialog.ShowError(fmt.Errorf("please select a file"), g.window)
+		return
+	}
+
+	// The start and end fields are used when no list is given
+	spec := list
+	if strings.TrimSpace(spec) == "" {
+		spec = start + "-" + end
+	}
+	segments, err := converter.ParseSegments(spec)
+	if err != nil {
+		dialog.ShowError(err, g.window)
+		return
+	}
+
+	ext := filepath.Ext(mediaPath)
+	outputPath := strings.TrimSuffix(mediaPath, ext) + "_trimmed" + ext
+
+	g.updateStatus(fmt.Sprintf("✂️ Trimming %s...", filepath.Base(mediaPath)))
+	run.run(func(ctx context.Context, progress chan float64) error {
+		return g.converter.Trim(ctx, converter.TrimRequest{
+			InputPath:  mediaPath,
+			OutputPath: outputPath,
+			Segments:   segments,
+			Remove:     remove,
+			Accurate:   accurate,
+			Progress:   progress,
+		})
+	}, func(err error) {
+		switch {
+		case errors.Is(err, context.Canceled):
+			g.updateStatus("⏹️ Trim cancelled")
+		case err != nil:
+			g.updateStatus("❌ Trim failed")
+			g.showConversionError(fmt.Errorf("failed to trim: %w", err))
+		default:
+			g.updateStatus(fmt.Sprintf("✅ Trimmed %s", filepath.Base(mediaPath)))
+			dialog.ShowInformation("Success", fmt.Sprintf("✂️ Trimmed file saved to: %s", outputPath), g.window)
+		}
+	})
+}
+
+// concatFiles joins g.files in the order they appear in the file list.
+func (g *GUI) concatFiles(crossfade, transition string, run *backgroundRun) {
+	if len(g.files) < 2 {
+		dialog.ShowError(fmt.Errorf("please add at least two files to join"), g.window)
+		return
+	}
+
+	seconds, err := strconv.ParseFloat(strings.TrimSpace(crossfade), 64)
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("invalid crossfade %q", crossfade), g.window)
+		return
+	}
+
+	first := g.files[0]
+	ext := filepath.Ext(first)
+	outputPath := strings.TrimSuffix(first, ext) + "_joined" + ext
+
+	inputs := append([]string(nil), g.files...)
+	g.updateStatus(fmt.Sprintf("🔗 Joining %d files...", len(inputs)))
+	run.run(func(ctx context.Context, progress chan float64) error {
+		return g.converter.Concat(ctx, converter.ConcatRequest{
+			InputPaths: inputs,
+			OutputPath: outputPath,
+			Crossfade:  seconds,
+			Transition: transition,
+			Progress:   progress,
+		})
+	}, func(err error) {
+		switch {
+		case errors.Is(err, context.Canceled):
+			g.updateStatus("⏹️ Join cancelled")
+		case err != nil:
+			g.updateStatus("❌ Join failed")
+			g.showConversionError(fmt.Errorf("failed to join files: %w", err))
+		default:
+			g.updateStatus(fmt.Sprintf("✅ Joined %d files", len(inputs)))
+			dialog.ShowInformation("Success", fmt.Sprintf("🔗 %d files joined into: %s", len(inputs), outputPath), g.window)
+		}
+	})
+}
+
+// backgroundRun is the start button, cancel button and progress bar of a
+// tool whose work runs off the UI goroutine, as convertFiles does for a
+// batch.
+type backgroundRun struct {
+	start  *widget.Button
+	cancel *widget.Button
+	bar    *widget.ProgressBar
+
+	// Cancels the running work, nil when idle
+	stop context.CancelFunc
+}
+
+func newBackgroundRun() *backgroundRun {
+	r := &backgroundRun{
+		start: widget.NewButton("", nil),
+		bar:   widget.NewProgressBar(),
+	}
+	r.cancel = widget.NewButton("⏹️ Cancel", func() {
+		if r.stop != nil {
+			r.stop()
+		}
+	})
+	r.cancel.Disable()
+	return r
+}
+
+func (r *backgroundRun) controls() fyne.CanvasObject {
+	return container.NewVBox(r.bar, container.NewHBox(r.start, r.cancel))
+}
+
+// run starts work in a goroutine with a cancellable context and a progress
+// channel that drives the bar. done is called on the UI goroutine with
+// work's error once it returns.
+func (r *backgroundRun) run(work func(ctx context.Context, progress chan float64) error, done func(error)) {
+	if r.stop != nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	r.stop = cancel
+	r.start.Disable()
+	r.cancel.Enable()
+	r.bar.SetValue(0)
+
+	go func() {
+		progress := make(chan float64, 16)
+		forwarded := make(chan struct{})
+		go func() {
+			defer close(forwarded)
+			for value := range progress {
+				fyne.Do(func() { r.bar.SetValue(value) })
+			}
+		}()
+
+		err := work(ctx, progress)
+		close(progress)
+		<-forwarded
+
+		fyne.Do(func() {
+			cancel()
+			r.stop = nil
+			r.cancel.Disable()
+			r.start.Enable()
+			if err == nil {
+				r.bar.SetValue(1)
+			}
+			done(err)
+		})
+	}()
 }
 
 func (g *GUI) extractAudio(videoPath, bitrate string) {
@@ -1031,7 +1409,7 @@ func (g *GUI) extractAudio(videoPath, bitrate string) {
 
 	err := g.converter.Convert(req)
 	if err != nil {
-		dialog.ShowError(fmt.Errorf("failed to extract audio: %w", err), g.window)
+		g.showConversionError(fmt.Errorf("failed to extract audio: %w", err))
 		return
 	}
 
@@ -1054,7 +1432,7 @@ func (g *GUI) convertAudio(audioPath, format string) {
 
 	err := g.converter.Convert(req)
 	if err != nil {
-		dialog.ShowError(fmt.Errorf("failed to convert audio: %w", err), g.window)
+		g.showConversionError(fmt.Errorf("failed to convert audio: %w", err))
 		return
 	}
 
@@ -1074,8 +1452,8 @@ func (g *GUI) getVideoInfo(videoPath string) {
 	}
 
 	infoText := fmt.Sprintf(
-		"⏱️ Duration: %s\n📐 Dimensions: %dx%d\n🎬 Codec: %s\n🎞 Frame Rate: %s",
-		info.Duration, info.Width, info.Height, info.Codec, info.FrameRate,
+		"⏱️ Duration: %s\n📐 Dimensions: %dx%d\n🎬 Codec: %s\n🎞 Frame Rate: %s\n📊 Bitrate: %s",
+		info.Duration, info.Width, info.Height, info.Codec, info.FrameRate, info.Bitrate,
 	)
 
 	dialog.ShowInformation("Video Information", infoText, g.window)
@@ -1109,6 +1487,35 @@ func (g *GUI) generateOutputPath(inputPath, outputFormat, outputDir string) stri
 	return filepath.Join(filepath.Dir(inputPath), baseName+ext)
 }
 
+// showConversionError shows err in a dialog. Tool failures also show the
+// hint, command line and the tail of the tool's output.
+func (g *GUI) showConversionError(err error) {
+	var toolErr *converter.ToolError
+	if !errors.As(err, &toolErr) {
+		dialog.ShowError(err, g.window)
+		return
+	}
+
+	summary := widget.NewLabel(err.Error())
+	summary.Wrapping = fyne.TextWrapWord
+	content := container.NewVBox(summary)
+
+	if hint := converter.ErrorHint(err); hint != "" {
+		hintLabel := widget.NewLabel("💡 " + hint)
+		hintLabel.Wrapping = fyne.TextWrapWord
+		content.Add(hintLabel)
+	}
+
+	details := widget.NewLabel(fmt.Sprintf("Command: %s\nExit code: %d\n\n%s",
+		toolErr.CommandLine(), toolErr.ExitCode, strings.TrimSpace(toolErr.Stderr)))
+	details.TextStyle = fyne.TextStyle{Monospace: true}
+	detailsScroll := container.NewScroll(details)
+	detailsScroll.SetMinSize(fyne.NewSize(640, 240))
+	content.Add(widget.NewAccordion(widget.NewAccordionItem("🔍 Tool output", detailsScroll)))
+
+	dialog.ShowCustom("❌ Conversion failed", "Close", content, g.window)
+}
+
 func (g *GUI) updateStatus(message string) {
 	g.statusLabel.SetText(message)
 }
@@ -1140,12 +1547,30 @@ func (g *GUI) showPreview(filePath string) {
 		return
 	}
 
-	previewInfo, err := g.mediaPlayer.GeneratePreview(filePath)
-	if err != nil {
-		dialog.ShowError(fmt.Errorf("failed to generate preview: %w", err), g.window)
-		return
-	}
+	g.previewFile = filePath
+	g.updateStatus(fmt.Sprintf("⏳ Preparing preview of %s...", filepath.Base(filePath)))
+
+	// Probing and drawing a waveform run ffprobe and ffmpeg, which would
+	// freeze the window; the result is shown with fyne.Do.
+	go func() {
+		previewInfo, err := g.mediaPlayer.GeneratePreview(filePath)
+		fyne.Do(func() {
+			if g.previewFile != filePath {
+				// Another file was picked while this one was loading
+				return
+			}
+			if err != nil {
+				dialog.ShowError(fmt.Errorf("failed to generate preview: %w", err), g.window)
+				g.updateStatus("❌ Preview failed")
+				return
+			}
+			g.renderPreview(filePath, previewInfo)
+		})
+	}()
+}
 
+// renderPreview fills the preview area with the file's details.
+func (g *GUI) renderPreview(filePath string, previewInfo *media.PreviewInfo) {
 	// Clear previous preview
 	g.previewArea.Objects = []fyne.CanvasObject{}
 
@@ -1177,11 +1602,17 @@ func (g *GUI) showPreview(filePath string) {
 
 	previewContent.Add(widget.NewCard("📋 File Information", "", fileInfo))
 
-	// Add thumbnail if available
+	// Add thumbnail if available; audio files get their waveform
 	if previewInfo.Thumbnail != "" {
-		thumbnailImg := widget.NewIcon(theme.FileIcon())
-		thumbnailCard := widget.NewCard("🖼️ Thumbnail", "", thumbnailImg)
-		previewContent.Add(thumbnailCard)
+		thumbnailImg := canvas.NewImageFromFile(previewInfo.Thumbnail)
+		thumbnailImg.FillMode = canvas.ImageFillContain
+		thumbnailImg.SetMinSize(fyne.NewSize(320, 180))
+		title := "🖼️ Thumbnail"
+		if formats.Default().Is(filepath.Ext(filePath), formats.CategoryAudio) {
+			thumbnailImg.SetMinSize(fyne.NewSize(320, 80))
+			title = "🌊 Waveform"
+		}
+		previewContent.Add(widget.NewCard(title, "", thumbnailImg))
 	}
 
 	// Action buttons
@@ -0,0 +1,19 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// Dir returns the goverter configuration directory. GOVERTER_CONFIG_DIR
+// overrides the platform default (e.g. ~/.config/goverter on Linux).
+func Dir() (string, error) {
+	if dir := os.Getenv("GOVERTER_CONFIG_DIR"); dir != "" {
+		return dir, nil
+	}
+	base, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(base, "goverter"), nil
+}
@@ -0,0 +1,98 @@
+package converter
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strconv"
+)
+
+// animatedFormats are the image outputs ffmpeg renders as animations when
+// the input is a video.
+var animatedFormats = map[string]bool{".gif": true, ".webp": true, ".apng": true}
+
+// IsAnimatedFormat reports whether ext is an animated GIF, WebP or APNG.
+func IsAnimatedFormat(ext string) bool {
+	return animatedFormats[canonicalExt(ext)]
+}
+
+// animate turns a video into an animated GIF, WebP or APNG. GIFs get a
+// palette generated from the clip itself, which is what keeps them from
+// banding.
+func (b *ffmpegBackend) animate(ctx context.Context, req ConversionRequest) error {
+	opts, err := ParseImageOptions(req.Options)
+	if err != nil {
+		return err
+	}
+	opts = opts.withDefaults()
+	anim := opts.Animation
+
+	var args []string
+	if anim.Start > 0 {
+		args = append(args, "-ss", formatSeconds(anim.Start))
+	}
+	if anim.End > 0 {
+		args = append(args, "-to", formatSeconds(anim.End))
+	}
+	args = append(args, "-i", req.InputPath)
+
+	// Never raise the frame rate above the source's
+	fps := anim.FPS
+	if info, err := b.prober.Probe(ctx, req.InputPath); err == nil {
+		if stream := info.VideoStream(); stream != nil && stream.FrameRate > 0 && stream.FrameRate < fps {
+			fps = stream.FrameRate
+		}
+	}
+	frames := fmt.Sprintf("fps=%s,%s", formatFPS(fps), limitScale(opts.Width, opts.Height))
+
+	switch ext := canonicalExt(filepath.Ext(req.OutputPath)); ext {
+	case ".gif":
+		graph := fmt.Sprintf("[0:v]%s,split[a][b];[a]palettegen=max_colors=%d:stats_mode=diff[p];[b][p]paletteuse=dither=%s:diff_mode=rectangle",
+			frames, anim.MaxColors, anim.Dither)
+		args = append(args, "-filter_complex", graph, "-loop", strconv.Itoa(gifLoop(anim.Loop)), "-f", "gif")
+	case ".webp":
+		args = append(args, "-vf", frames, "-c:v", "libwebp", "-loop", strconv.Itoa(anim.Loop))
+		args = append(args, QualityArgs("libwebp", opts.Quality)...)
+		args = append(args, "-f", "webp")
+	case ".apng":
+		args = append(args, "-vf", frames, "-plays", strconv.Itoa(anim.Loop), "-f", "apng")
+	default:
+		return fmt.Errorf("%s is not an animated format", ext)
+	}
+
+	args = append(args, "-an", "-y", req.OutputPath)
+	return b.runFFmpeg(ctx, req, args)
+}
+
+// limitScale scales down to fit width and height, keeping the aspect ratio
+// and never upscaling.
+func limitScale(width, height int) string {
+	w, h := "-2", "-2"
+	if width > 0 {
+		w = fmt.Sprintf("'min(%d,iw)'", width)
+	}
+	if height > 0 {
+		h = fmt.Sprintf("'min(%d,ih)'", height)
+	}
+	if width > 0 && height > 0 {
+		return fmt.Sprintf("scale=%s:%s:force_original_aspect_ratio=decrease:flags=lanczos", w, h)
+	}
+	return fmt.Sprintf("scale=%s:%s:flags=lanczos", w, h)
+}
+
+// gifLoop converts a number of plays into the GIF muxer's loop count,
+// which counts repeats and uses -1 for "play once".
+func gifLoop(plays int) int {
+	switch plays {
+	case 0:
+		return 0
+	case 1:
+		return -1
+	default:
+		return plays - 1
+	}
+}
+
+func formatSeconds(seconds float64) string {
+	return strconv.FormatFloat(seconds, 'f', -1, 64)
+}
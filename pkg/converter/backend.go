@@ -0,0 +1,88 @@
+package converter
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Backend converts files with one tool or library. Converter picks the
+// first registered backend that is available and can handle the input and
+// output extensions (lowercase, with the leading dot).
+type Backend interface {
+	Name() string
+	Available() bool
+	CanConvert(inExt, outExt string) bool
+	Convert(ctx context.Context, req ConversionRequest) error
+}
+
+// Registry is an ordered set of backends. Backends registered later take
+// precedence, so a custom backend can override a built-in one for the
+// conversions it claims.
+type Registry struct {
+	mu       sync.RWMutex
+	backends []Backend
+}
+
+func NewRegistry(backends ...Backend) *Registry {
+	r := &Registry{}
+	for _, backend := range backends {
+		r.Register(backend)
+	}
+	return r
+}
+
+func (r *Registry) Register(backend Backend) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.backends = append([]Backend{backend}, r.backends...)
+}
+
+// Backends returns the registered backends in priority order.
+func (r *Registry) Backends() []Backend {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return append([]Backend(nil), r.backends...)
+}
+
+// Find returns the backend to use for inExt -> outExt. When only
+// unavailable backends can handle it, the first of those is returned so
+// that its Convert reports which tool is missing.
+func (r *Registry) Find(inExt, outExt string) (Backend, error) {
+	var unavailable Backend
+	for _, backend := range r.Backends() {
+		if !backend.CanConvert(inExt, outExt) {
+			continue
+		}
+		if backend.Available() {
+			return backend, nil
+		}
+		if unavailable == nil {
+			unavailable = backend
+		}
+	}
+
+	if unavailable != nil {
+		return unavailable, nil
+	}
+	return nil, fmt.Errorf("unsupported conversion: %s to %s", inExt, outExt)
+}
+
+var defaultRegistry = NewRegistry(BuiltinBackends()...)
+
+// BuiltinBackends returns new pandoc, ImageMagick and ffmpeg backends, in
+// registration order, with their tools looked up on PATH now.
+func BuiltinBackends() []Backend {
+	return []Backend{newPandocBackend(), newMagickBackend(), newFFmpegBackend()}
+}
+
+// DefaultRegistry returns the registry used by NewConverter and
+// ValidateTools. It starts with the ffmpeg, ImageMagick and pandoc backends.
+func DefaultRegistry() *Registry {
+	return defaultRegistry
+}
+
+// Register adds backend to the default registry, ahead of the built-ins.
+func Register(backend Backend) {
+	defaultRegistry.Register(backend)
+}
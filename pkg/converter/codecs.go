@@ -0,0 +1,298 @@
+package converter
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Codec is a video or audio codec that can be picked with the video_codec
+// or audio_codec option.
+type Codec struct {
+	Name    string
+	Encoder string // ffmpeg encoder
+	Video   bool
+
+	// Profiles, Levels and Presets list the accepted profile, level and
+	// encoder_preset values; a nil list means the option is not supported.
+	Profiles []string
+	Levels   []string
+	Presets  []string
+
+	// NoTwoPass marks encoders that ffmpeg cannot run in two passes, which
+	// target_size needs.
+	NoTwoPass bool
+}
+
+var x264Presets = []string{"ultrafast", "superfast", "veryfast", "faster", "fast", "medium", "slow", "slower", "veryslow", "placebo"}
+
+var h26xLevels = []string{"1", "1.1", "1.2", "1.3", "2", "2.1", "2.2", "3", "3.1", "3.2", "4", "4.1", "4.2", "5", "5.1", "5.2", "6", "6.1", "6.2"}
+
+var codecs = map[string]Codec{
+	"h264": {Name: "h264", Encoder: "libx264", Video: true,
+		Profiles: []string{"baseline", "main", "high", "high10", "high422", "high444"},
+		Levels:   h26xLevels,
+		Presets:  x264Presets},
+	"hevc": {Name: "hevc", Encoder: "libx265", Video: true,
+		Profiles: []string{"main", "main10", "main12"},
+		Levels:   h26xLevels,
+		Presets:  x264Presets},
+	"vp9": {Name: "vp9", Encoder: "libvpx-vp9", Video: true,
+		Profiles: []string{"0", "1", "2", "3"},
+		Presets:  []string{"realtime", "good", "best"}},
+	"av1": {Name: "av1", Encoder: "libsvtav1", Video: true,
+		Profiles:  []string{"main", "high", "professional"},
+		Presets:   []string{"0", "1", "2", "3", "4", "5", "6", "7", "8", "9", "10", "11", "12", "13"},
+		NoTwoPass: true},
+	"mpeg4": {Name: "mpeg4", Encoder: "mpeg4", Video: true},
+	"flv1":  {Name: "flv1", Encoder: "flv", Video: true},
+	"wmv2":  {Name: "wmv2", Encoder: "wmv2", Video: true},
+
+	"aac":    {Name: "aac", Encoder: "aac"},
+	"mp3":    {Name: "mp3", Encoder: "libmp3lame"},
+	"opus":   {Name: "opus", Encoder: "libopus"},
+	"vorbis": {Name: "vorbis", Encoder: "libvorbis"},
+	"flac":   {Name: "flac", Encoder: "flac"},
+	"pcm":    {Name: "pcm", Encoder: "pcm_s16le"},
+	"ac3":    {Name: "ac3", Encoder: "ac3"},
+	"wmav2":  {Name: "wmav2", Encoder: "wmav2"},
+}
+
+// containerCodecs lists the codecs each output container can hold.
+var containerCodecs = map[string]struct{ video, audio []string }{
+	".mp4":  {video: []string{"h264", "hevc", "av1", "mpeg4"}, audio: []string{"aac", "mp3", "opus", "flac", "ac3"}},
+	".m4v":  {video: []string{"h264", "hevc", "mpeg4"}, audio: []string{"aac", "ac3"}},
+	".mov":  {video: []string{"h264", "hevc", "mpeg4"}, audio: []string{"aac", "mp3", "pcm", "ac3"}},
+	".mkv":  {video: []string{"h264", "hevc", "vp9", "av1", "mpeg4"}, audio: []string{"aac", "mp3", "opus", "vorbis", "flac", "pcm", "ac3"}},
+	".webm": {video: []string{"vp9", "av1"}, audio: []string{"opus", "vorbis"}},
+	".avi":  {video: []string{"mpeg4", "h264"}, audio: []string{"mp3", "pcm", "ac3"}},
+	".flv":  {video: []string{"flv1", "h264"}, audio: []string{"mp3", "aac"}},
+	".wmv":  {video: []string{"wmv2"}, audio: []string{"wmav2"}},
+
+	".mp3":  {audio: []string{"mp3"}},
+	".m4a":  {audio: []string{"aac", "flac"}},
+	".aac":  {audio: []string{"aac"}},
+	".ogg":  {audio: []string{"vorbis", "opus", "flac"}},
+	".opus": {audio: []string{"opus"}},
+	".flac": {audio: []string{"flac"}},
+	".wav":  {audio: []string{"pcm"}},
+}
+
+// codecNames returns the names of the video or audio codecs.
+func codecNames(video bool) []string {
+	var names []string
+	for name, codec := range codecs {
+		if codec.Video == video {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// codecForEncoder finds the codec an encoder implements.
+func codecForEncoder(encoder string) (Codec, bool) {
+	for _, codec := range codecs {
+		if codec.Encoder == encoder {
+			return codec, true
+		}
+	}
+	return Codec{}, false
+}
+
+// resolveCodec returns the codec named by the option, or the one ffmpeg
+// uses by default for outputExt when name is empty.
+func resolveCodec(name, outputExt string, video bool) (Codec, bool) {
+	if name != "" {
+		codec, ok := codecs[name]
+		return codec, ok
+	}
+	container, ok := containerCodecs[canonicalExt(outputExt)]
+	if !ok {
+		return Codec{}, false
+	}
+	list := container.audio
+	if video {
+		list = container.video
+	}
+	if len(list) == 0 {
+		return Codec{}, false
+	}
+	if codec, ok := codecForEncoder(defaultEncoder(outputExt)); ok && codec.Video == video {
+		return codec, true
+	}
+	return codecs[list[0]], true
+}
+
+// checkContainer reports whether a codec fits in the output container.
+func checkContainer(codec Codec, outputExt string) error {
+	ext := canonicalExt(outputExt)
+	container, ok := containerCodecs[ext]
+	if !ok {
+		return nil
+	}
+	list, kind := container.audio, "audio"
+	if codec.Video {
+		list, kind = container.video, "video"
+	}
+	for _, name := range list {
+		if name == codec.Name {
+			return nil
+		}
+	}
+	if len(list) == 0 {
+		return fmt.Errorf("%s cannot hold %s", ext, kind)
+	}
+	return fmt.Errorf("%s %s cannot be stored in %s (supported: %s)", kind, codec.Name, ext, strings.Join(list, ", "))
+}
+
+// checkCodecs validates codec choices against the output container and the
+// chosen codec's profiles, levels, presets and pixel formats.
+func (o VideoOptions) checkCodecs(outputExt string) error {
+	var errs []error
+
+	video, ok := resolveCodec(o.VideoCodec, outputExt, true)
+	if ok {
+		if err := checkContainer(video, outputExt); err != nil {
+			errs = append(errs, err)
+		}
+		errs = append(errs, checkChoice(video, "profile", o.Profile, video.Profiles))
+		errs = append(errs, checkChoice(video, "level", o.Level, video.Levels))
+		errs = append(errs, checkChoice(video, "encoder_preset", o.EncoderPreset, video.Presets))
+		errs = append(errs, checkPixelFormat(video, o.Profile, o.PixelFormat))
+		if o.TargetSize > 0 && video.NoTwoPass {
+			errs = append(errs, fmt.Errorf("target_size needs a two-pass encode, which %s (%s) does not support; choose another video_codec", video.Name, video.Encoder))
+		}
+	} else if o.Profile != "" || o.Level != "" || o.EncoderPreset != "" {
+		errs = append(errs, fmt.Errorf("profile, level and encoder_preset need a video_codec for %s, which has no default codec", canonicalExt(outputExt)))
+	}
+
+	if o.AudioCodec != "" {
+		if err := checkContainer(codecs[o.AudioCodec], outputExt); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (o AudioOptions) checkCodecs(outputExt string) error {
+	if o.Codec == "" {
+		return nil
+	}
+	return checkContainer(codecs[o.Codec], outputExt)
+}
+
+func checkChoice(codec Codec, option, value string, allowed []string) error {
+	if value == "" {
+		return nil
+	}
+	if allowed == nil {
+		return fmt.Errorf("%s does not support %s", codec.Name, option)
+	}
+	for _, a := range allowed {
+		if a == value {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s %s %q is not one of %s", codec.Name, option, value, strings.Join(allowed, ", "))
+}
+
+// pixelFormats are the pixel formats goverter lets through, by bit depth
+// and chroma subsampling.
+var pixelFormats = map[string]struct {
+	depth  int
+	chroma string
+}{
+	"yuv420p":     {8, "420"},
+	"yuv422p":     {8, "422"},
+	"yuv444p":     {8, "444"},
+	"yuv420p10le": {10, "420"},
+	"yuv422p10le": {10, "422"},
+	"yuv444p10le": {10, "444"},
+	"yuv420p12le": {12, "420"},
+}
+
+func pixelFormatNames() []string {
+	names := make([]string, 0, len(pixelFormats))
+	for name := range pixelFormats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// profileLimits is the deepest bit depth and the chroma subsamplings a
+// profile allows.
+var profileLimits = map[string]struct {
+	depth  int
+	chroma []string
+}{
+	"h264/baseline": {8, []string{"420"}},
+	"h264/main":     {8, []string{"420"}},
+	"h264/high":     {8, []string{"420"}},
+	"h264/high10":   {10, []string{"420"}},
+	"h264/high422":  {10, []string{"420", "422"}},
+	"hevc/main":     {8, []string{"420"}},
+	"hevc/main10":   {10, []string{"420"}},
+	"hevc/main12":   {12, []string{"420"}},
+	"vp9/0":         {8, []string{"420"}},
+	"vp9/1":         {8, []string{"420", "422", "444"}},
+	"vp9/2":         {12, []string{"420"}},
+	"av1/main":      {10, []string{"420"}},
+	"av1/high":      {10, []string{"420", "444"}},
+}
+
+func checkPixelFormat(codec Codec, profile, pixelFormat string) error {
+	if pixelFormat == "" || profile == "" {
+		return nil
+	}
+	limits, ok := profileLimits[codec.Name+"/"+profile]
+	if !ok {
+		return nil
+	}
+	format := pixelFormats[pixelFormat]
+	for _, chroma := range limits.chroma {
+		if format.depth <= limits.depth && chroma == format.chroma {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s profile %s does not allow pix_fmt %s", codec.Name, profile, pixelFormat)
+}
+
+// av1Profiles maps profile names to the numbers the encoder expects.
+var av1Profiles = map[string]string{"main": "0", "high": "1", "professional": "2"}
+
+// codecArgs returns the ffmpeg arguments that select and tune the video
+// encoder.
+func (o VideoOptions) codecArgs(codec Codec) []string {
+	var args []string
+	if o.VideoCodec != "" {
+		args = append(args, "-c:v", codec.Encoder)
+	}
+	if o.PixelFormat != "" {
+		args = append(args, "-pix_fmt", o.PixelFormat)
+	}
+	if o.Profile != "" {
+		profile := o.Profile
+		if codec.Name == "av1" {
+			profile = av1Profiles[profile]
+		}
+		args = append(args, "-profile:v", profile)
+	}
+	if o.Level != "" {
+		if codec.Name == "hevc" {
+			args = append(args, "-x265-params", "level-idc="+o.Level)
+		} else {
+			args = append(args, "-level", o.Level)
+		}
+	}
+	if o.EncoderPreset != "" {
+		if codec.Name == "vp9" {
+			args = append(args, "-deadline", o.EncoderPreset)
+		} else {
+			args = append(args, "-preset", o.EncoderPreset)
+		}
+	}
+	return args
+}
@@ -0,0 +1,387 @@
+package converter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"goverter/pkg/formats"
+	"goverter/pkg/probe"
+	"goverter/pkg/utils"
+)
+
+// Transitions are the xfade transitions a crossfade can use.
+var Transitions = []string{
+	"fade", "fadeblack", "fadewhite", "dissolve", "pixelize", "radial",
+	"wipeleft", "wiperight", "wipeup", "wipedown",
+	"slideleft", "slideright", "slideup", "slidedown",
+	"circleopen", "circleclose",
+}
+
+// ConcatRequest joins video or audio files end to end.
+type ConcatRequest struct {
+	// InputPaths are joined in this order.
+	InputPaths []string
+	OutputPath string
+	// Crossfade overlaps each clip with the next for this many seconds,
+	// using Transition for the picture ("fade" when empty).
+	Crossfade  float64
+	Transition string
+	// Options are encoder options as for Convert; they force a re-encode.
+	Options  map[string]string
+	Progress chan float64
+}
+
+// Concat joins the inputs. When they share a container and their streams
+// match, they are joined with the concat demuxer without re-encoding;
+// otherwise every clip is scaled, padded and resampled to the first one's
+// resolution, frame rate and sample rate and the result is re-encoded.
+// Like ConvertContext, it removes the partial output when ctx is cancelled.
+func (c *Converter) Concat(ctx context.Context, req ConcatRequest) error {
+	before := utils.StatFile(req.OutputPath)
+	err := c.concat(ctx, req)
+	if err != nil && ctx.Err() != nil {
+		before.RemoveIfChanged()
+		err = fmt.Errorf("joining into %s stopped: %w", req.OutputPath, ctx.Err())
+	}
+	return err
+}
+
+func (c *Converter) concat(ctx context.Context, req ConcatRequest) error {
+	b := c.ffmpegBackend()
+	if b == nil || !b.Available() {
+		return fmt.Errorf("ffmpeg not found. Please install FFmpeg to join video and audio")
+	}
+	if len(req.InputPaths) < 2 {
+		return errors.New("concat needs at least two inputs")
+	}
+	// The copy path hands ffmpeg the inputs through a list file, so its own
+	// check for an output that is also an input never sees them
+	for _, input := range req.InputPaths {
+		if utils.SamePath(input, req.OutputPath) {
+			return fmt.Errorf("the output %s is also an input; choose another output path", req.OutputPath)
+		}
+	}
+
+	outputExt := strings.ToLower(filepath.Ext(req.OutputPath))
+	category := categoryOf(filepath.Ext(req.InputPaths[0]))
+	if category != formats.CategoryVideo && category != formats.CategoryAudio {
+		return fmt.Errorf("cannot join %s files: only video and audio can be joined", filepath.Ext(req.InputPaths[0]))
+	}
+	for _, input := range req.InputPaths[1:] {
+		if categoryOf(filepath.Ext(input)) != category {
+			return fmt.Errorf("cannot join %s and %s: all inputs must be %s", req.InputPaths[0], input, category)
+		}
+	}
+	if !isOutputFormat(category, outputExt) || categoryOf(outputExt) != category {
+		return fmt.Errorf("cannot join %s inputs into %s: the output must be a %s format", category, outputExt, category)
+	}
+	if req.Crossfade < 0 {
+		return errors.New("crossfade cannot be negative")
+	}
+	if req.Transition != "" && !contains(Transitions, req.Transition) {
+		return fmt.Errorf("unknown transition %q (choose from %s)", req.Transition, strings.Join(Transitions, ", "))
+	}
+	if err := ValidateOptions(outputExt, req.Options); err != nil {
+		return err
+	}
+
+	clips := make([]*probe.Info, len(req.InputPaths))
+	for i, input := range req.InputPaths {
+		info, err := b.prober.Probe(ctx, input)
+		if err != nil {
+			return fmt.Errorf("concat needs to inspect every input: %w", err)
+		}
+		clips[i] = info
+	}
+
+	if req.Crossfade == 0 && len(req.Options) == 0 && canConcatCopy(req.InputPaths, outputExt, clips) {
+		return b.concatCopy(ctx, req, clips)
+	}
+	return b.concatEncode(ctx, req, clips, category, outputExt)
+}
+
+// canConcatCopy reports whether the concat demuxer can join the inputs:
+// they must already be in the output's container with identical streams.
+func canConcatCopy(inputs []string, outputExt string, clips []*probe.Info) bool {
+	for _, input := range inputs {
+		if canonicalExt(filepath.Ext(input)) != canonicalExt(outputExt) {
+			return false
+		}
+	}
+	first := streamSignature(clips[0])
+	for _, clip := range clips[1:] {
+		if streamSignature(clip) != first {
+			return false
+		}
+	}
+	return true
+}
+
+// streamSignature sums up the stream parameters that must match for a
+// stream copy join.
+func streamSignature(info *probe.Info) string {
+	var parts []string
+	if v := info.VideoStream(); v != nil {
+		parts = append(parts, fmt.Sprintf("%s/%s %dx%d rot%d %s %.3ffps",
+			v.CodecName, v.Profile, v.Width, v.Height, v.Rotation, v.PixelFormat, v.FrameRate))
+	}
+	if a := info.AudioStream(); a != nil {
+		parts = append(parts, fmt.Sprintf("%s %dHz %dch", a.CodecName, a.SampleRate, a.Channels))
+	}
+	return strings.Join(parts, "; ")
+}
+
+func (b *ffmpegBackend) concatCopy(ctx context.Context, req ConcatRequest, clips []*probe.Info) error {
+	tmpDir, err := os.MkdirTemp("", "goverter-concat-")
+	if err != nil {
+		return fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	listPath := filepath.Join(tmpDir, "inputs.txt")
+	if err := writeConcatList(listPath, req.InputPaths); err != nil {
+		return err
+	}
+	args := []string{"-f", "concat", "-safe", "0", "-i", listPath, "-map", "0", "-c", "copy", "-y", req.OutputPath}
+	return b.runFFmpegTimed(ctx, req.Progress, totalDuration(clips, 0), args)
+}
+
+// writeConcatList writes a concat demuxer list. The demuxer resolves
+// relative names against the list, so paths are made absolute.
+func writeConcatList(listPath string, files []string) error {
+	var list strings.Builder
+	for _, file := range files {
+		abs, err := filepath.Abs(file)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(&list, "file '%s'\n", strings.ReplaceAll(abs, "'", `'\''`))
+	}
+	return os.WriteFile(listPath, []byte(list.String()), 0644)
+}
+
+// concatTarget is the common format clips are normalised to before they
+// are joined.
+type concatTarget struct {
+	width, height int
+	fps           float64
+	sampleRate    int
+	layout        string
+}
+
+func (b *ffmpegBackend) concatEncode(ctx context.Context, req ConcatRequest, clips []*probe.Info, category, outputExt string) error {
+	video := category == formats.CategoryVideo
+	audio := false
+	for _, clip := range clips {
+		audio = audio || clip.AudioStream() != nil
+	}
+
+	// Crossfades and silence for clips without audio are placed by
+	// duration, so those need every clip's length.
+	for i, clip := range clips {
+		needed := req.Crossfade > 0 || (audio && clip.AudioStream() == nil)
+		if needed && clip.Format.Duration <= 0 {
+			return fmt.Errorf("the duration of %s could not be probed", req.InputPaths[i])
+		}
+		if req.Crossfade > 0 && clip.Format.Duration <= req.Crossfade {
+			return fmt.Errorf("%s is shorter than the %ss crossfade", req.InputPaths[i], formatSeconds(req.Crossfade))
+		}
+	}
+
+	target := concatTarget{fps: 30, sampleRate: 48000, layout: "stereo"}
+	if v := clips[0].VideoStream(); v != nil {
+		target.width, target.height = v.DisplaySize()
+		if v.FrameRate > 0 {
+			target.fps = v.FrameRate
+		}
+	}
+	for _, clip := range clips {
+		if a := clip.AudioStream(); a != nil {
+			if a.SampleRate > 0 {
+				target.sampleRate = a.SampleRate
+			}
+			if a.Channels == 1 {
+				target.layout = "mono"
+			}
+			break
+		}
+	}
+
+	var encode []string
+	if video {
+		opts, err := ParseVideoOptions(req.Options)
+		if err != nil {
+			return err
+		}
+		if opts.TargetSize > 0 || opts.Loudness.Target != 0 {
+			return errors.New("target_size and loudness are not supported when joining")
+		}
+		if err := opts.checkCodecs(outputExt); err != nil {
+			return err
+		}
+		target.width, target.height = fitSize(target.width, target.height, opts.Width, opts.Height)
+		if target.width == 0 || target.height == 0 {
+			return fmt.Errorf("the frame size of %s could not be probed", req.InputPaths[0])
+		}
+		if opts.FPS > 0 {
+			target.fps = opts.FPS
+		}
+		// The graph does the scaling and frame rate conversion
+		opts.Width, opts.Height, opts.FPS = 0, 0, 0
+		encode = videoEncodeArgs(opts, outputExt)
+	} else {
+		opts, err := parseAudio(req.Options, outputExt)
+		if err != nil {
+			return err
+		}
+		if opts.SampleRate > 0 {
+			target.sampleRate = opts.SampleRate
+		}
+		encoder := audioEncoder(opts.Codec, outputExt)
+		encode = append(audioCodecArgs(encoder), audioArgs(encoder, opts)...)
+	}
+
+	var args []string
+	for _, input := range req.InputPaths {
+		args = append(args, "-i", input)
+	}
+	transition := req.Transition
+	if transition == "" {
+		transition = "fade"
+	}
+	args = append(args, "-filter_complex", concatGraph(clips, target, video, audio, req.Crossfade, transition))
+	if video {
+		args = append(args, "-map", "[v]")
+	}
+	if audio {
+		args = append(args, "-map", "[a]")
+	}
+	args = append(args, encode...)
+	args = append(args, "-y", req.OutputPath)
+
+	return b.runFFmpegTimed(ctx, req.Progress, totalDuration(clips, req.Crossfade), args)
+}
+
+// fitSize applies the width and height options to the first clip's size,
+// deriving a missing side from its aspect ratio. Sides are kept even for
+// the sake of 4:2:0 encoders.
+func fitSize(width, height, optWidth, optHeight int) (int, int) {
+	switch {
+	case optWidth > 0 && optHeight > 0:
+		width, height = optWidth, optHeight
+	case optWidth > 0 && width > 0:
+		width, height = optWidth, optWidth*height/width
+	case optHeight > 0 && height > 0:
+		width, height = optHeight*width/height, optHeight
+	}
+	return width &^ 1, height &^ 1
+}
+
+// concatGraph normalises every clip to target and joins them, either with
+// the concat filter or with a chain of crossfades.
+func concatGraph(clips []*probe.Info, target concatTarget, video, audio bool, crossfade float64, transition string) string {
+	var filters, videoLabels, audioLabels []string
+	for i, clip := range clips {
+		if video {
+			label := fmt.Sprintf("[v%d]", i)
+			filters = append(filters, fmt.Sprintf(
+				"[%d:v]scale=%d:%d:force_original_aspect_ratio=decrease,pad=%d:%d:(ow-iw)/2:(oh-ih)/2,setsar=1,fps=%s,format=yuv420p%s",
+				i, target.width, target.height, target.width, target.height, formatFPS(target.fps), label))
+			videoLabels = append(videoLabels, label)
+		}
+		if audio {
+			label := fmt.Sprintf("[a%d]", i)
+			if clip.AudioStream() != nil {
+				filters = append(filters, fmt.Sprintf("[%d:a]aresample=%d,aformat=sample_fmts=fltp:channel_layouts=%s%s",
+					i, target.sampleRate, target.layout, label))
+			} else {
+				// Fill clips without sound with silence so the tracks stay in sync
+				filters = append(filters, fmt.Sprintf("anullsrc=r=%d:cl=%s,atrim=duration=%s%s",
+					target.sampleRate, target.layout, formatSeconds(clip.Format.Duration), label))
+			}
+			audioLabels = append(audioLabels, label)
+		}
+	}
+
+	if crossfade == 0 {
+		var inputs string
+		for i := range clips {
+			if video {
+				inputs += videoLabels[i]
+			}
+			if audio {
+				inputs += audioLabels[i]
+			}
+		}
+		outputs := ""
+		if video {
+			outputs += "[v]"
+		}
+		if audio {
+			outputs += "[a]"
+		}
+		filters = append(filters, fmt.Sprintf("%sconcat=n=%d:v=%d:a=%d%s", inputs, len(clips), boolInt(video), boolInt(audio), outputs))
+		return strings.Join(filters, ";")
+	}
+
+	if video {
+		// Each transition starts crossfade seconds before the end of the
+		// video joined so far.
+		prev, offset := videoLabels[0], 0.0
+		for i := 1; i < len(clips); i++ {
+			offset += clips[i-1].Format.Duration - crossfade
+			out := fmt.Sprintf("[vx%d]", i)
+			if i == len(clips)-1 {
+				out = "[v]"
+			}
+			filters = append(filters, fmt.Sprintf("%s%sxfade=transition=%s:duration=%s:offset=%s%s",
+				prev, videoLabels[i], transition, formatSeconds(crossfade), formatSeconds(offset), out))
+			prev = out
+		}
+	}
+	if audio {
+		prev := audioLabels[0]
+		for i := 1; i < len(clips); i++ {
+			out := fmt.Sprintf("[ax%d]", i)
+			if i == len(clips)-1 {
+				out = "[a]"
+			}
+			filters = append(filters, fmt.Sprintf("%s%sacrossfade=d=%s%s", prev, audioLabels[i], formatSeconds(crossfade), out))
+			prev = out
+		}
+	}
+	return strings.Join(filters, ";")
+}
+
+// totalDuration is the length of the joined output, or 0 if a clip's
+// duration is unknown.
+func totalDuration(clips []*probe.Info, crossfade float64) float64 {
+	var total float64
+	for _, clip := range clips {
+		if clip.Format.Duration <= 0 {
+			return 0
+		}
+		total += clip.Format.Duration
+	}
+	return total - crossfade*float64(len(clips)-1)
+}
+
+func boolInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
@@ -1,10 +1,15 @@
 package converter
 
 import (
+	"context"
 	"fmt"
-	"os/exec"
 	"path/filepath"
+	"runtime"
 	"strings"
+
+	"goverter/pkg/formats"
+	"goverter/pkg/scheduler"
+	"goverter/pkg/utils"
 )
 
 type FormatSupport struct {
@@ -13,27 +18,20 @@ type FormatSupport struct {
 	Category      string
 }
 
-var SupportedFormats = map[string]FormatSupport{
-	"mp4": {
-		InputFormats:  []string{".mp4", ".avi", ".mkv", ".mov", ".wmv", ".flv", ".webm", ".m4v"},
-		OutputFormats: []string{".mp4", ".avi", ".mkv", ".mov", ".wmv", ".flv", ".webm", ".m4v", ".gif", ".mp3"},
-		Category:      "video",
-	},
-	"jpg": {
-		InputFormats:  []string{".jpg", ".jpeg", ".png", ".gif", ".bmp", ".webp", ".tiff", ".svg"},
-		OutputFormats: []string{".jpg", ".jpeg", ".png", ".gif", ".bmp", ".webp", ".tiff", ".pdf"},
-		Category:      "image",
-	},
-	"mp3": {
-		InputFormats:  []string{".mp3", ".wav", ".flac", ".aac", ".ogg", ".m4a", ".wma"},
-		OutputFormats: []string{".mp3", ".wav", ".flac", ".aac", ".ogg", ".m4a"},
-		Category:      "audio",
-	},
-	"pdf": {
-		InputFormats:  []string{".pdf", ".doc", ".docx", ".txt", ".rtf", ".odt"},
-		OutputFormats: []string{".pdf", ".txt", ".html", ".docx", ".jpg", ".png"},
-		Category:      "document",
-	},
+// SupportedFormats summarises the format registry per category ("video",
+// "audio", "image", "document").
+var SupportedFormats = buildSupportedFormats(formats.Default())
+
+func buildSupportedFormats(registry *formats.Registry) map[string]FormatSupport {
+	supported := make(map[string]FormatSupport)
+	for _, category := range registry.Categories() {
+		supported[category] = FormatSupport{
+			InputFormats:  registry.InputExtensions(category),
+			OutputFormats: registry.OutputExtensions(category),
+			Category:      category,
+		}
+	}
+	return supported
 }
 
 type ConversionRequest struct {
@@ -45,179 +43,147 @@ type ConversionRequest struct {
 }
 
 type Converter struct {
-	ffmpegPath string
-	magickPath string
-	pandocPath string
+	registry *Registry
 }
 
+// NewConverter returns a Converter that dispatches through the default
+// backend registry, including any backends added with Register.
 func NewConverter() *Converter {
-	ffmpegPath, _ := exec.LookPath("ffmpeg")
-	magickPath, _ := exec.LookPath("magick")
-	pandocPath, _ := exec.LookPath("pandoc")
-
-	return &Converter{
-		ffmpegPath: ffmpegPath,
-		magickPath: magickPath,
-		pandocPath: pandocPath,
-	}
+	return NewConverterWithRegistry(DefaultRegistry())
 }
 
-func (c *Converter) Convert(req ConversionRequest) error {
-	inputExt := strings.ToLower(filepath.Ext(req.InputPath))
-
-	category := c.getCategory(inputExt)
-
-	switch category {
-	case "video":
-		return c.convertVideo(req)
-	case "image":
-		return c.convertImage(req)
-	case "audio":
-		return c.convertAudio(req)
-	case "document":
-		return c.convertDocument(req)
-	default:
-		return fmt.Errorf("unsupported file type: %s", inputExt)
-	}
+func NewConverterWithRegistry(registry *Registry) *Converter {
+	return &Converter{registry: registry}
 }
 
-func (c *Converter) getCategory(ext string) string {
-	for _, format := range SupportedFormats {
-		for _, inputExt := range format.InputFormats {
-			if ext == inputExt {
-				return format.Category
-			}
+func (c *Converter) Convert(req ConversionRequest) error {
+	return c.ConvertContext(context.Background(), req)
+}
+
+// ConvertContext converts like Convert, but stops the external tool as soon
+// as ctx is cancelled or its deadline passes. The partially written output
+// is removed in that case and the context error is returned; an existing
+// file the conversion never wrote to is kept.
+func (c *Converter) ConvertContext(ctx context.Context, req ConversionRequest) error {
+	before := utils.StatFile(req.OutputPath)
+	err := c.convert(ctx, req)
+	if err != nil && ctx.Err() != nil {
+		before.RemoveIfChanged()
+		err = fmt.Errorf("conversion of %s stopped: %w", req.InputPath, ctx.Err())
+	}
+	if err != nil && req.Error != nil {
+		select {
+		case req.Error <- err:
+		default:
 		}
 	}
-	return ""
+	return err
 }
 
-func (c *Converter) convertVideo(req ConversionRequest) error {
-	if c.ffmpegPath == "" {
-		return fmt.Errorf("ffmpeg not found. Please install FFmpeg for video conversions")
-	}
-
-	args := []string{"-i", req.InputPath}
-
-	// Handle audio extraction (video to audio)
-	if isAudioFormat(filepath.Ext(req.OutputPath)) {
-		args = append(args, "-vn", "-acodec", "libmp3lame")
-		if bitrate, ok := req.Options["bitrate"]; ok {
-			args = append(args, "-ab", bitrate)
-		} else {
-			args = append(args, "-ab", "192k")
-		}
-	} else if filepath.Ext(req.OutputPath) == ".gif" {
-		// Handle GIF creation (video to GIF)
-		fps := "10"
-		if fpsVal, ok := req.Options["fps"]; ok {
-			fps = fpsVal
-		}
-		scale := "480:-1"
-		if width, ok := req.Options["width"]; ok {
-			if height, ok := req.Options["height"]; ok {
-				scale = fmt.Sprintf("%s:%s", width, height)
-			} else {
-				scale = fmt.Sprintf("%s:-1", width)
-			}
-		}
-		args = append(args, "-vf", fmt.Sprintf("fps=%s,scale=%s:flags=lanczos,split[s0][1],palettegen[p1][s0]", fps, scale))
-		args = append(args, "-map", "[p1]", "-f", "gif")
-	} else {
-		// Regular video to video conversion
-		// Add quality settings
-		if quality, ok := req.Options["quality"]; ok {
-			args = append(args, "-crf", quality)
-		}
-
-		// Add bitrate settings
-		if bitrate, ok := req.Options["bitrate"]; ok {
-			args = append(args, "-b:v", bitrate)
-		}
-	}
-
-	args = append(args, "-y", req.OutputPath)
-
-	cmd := exec.Command(c.ffmpegPath, args...)
-	return cmd.Run()
-}
+func (c *Converter) convert(ctx context.Context, req ConversionRequest) error {
+	inputExt := strings.ToLower(filepath.Ext(req.InputPath))
+	outputExt := strings.ToLower(filepath.Ext(req.OutputPath))
 
-func (c *Converter) convertImage(req ConversionRequest) error {
-	if c.magickPath == "" {
-		return fmt.Errorf("ImageMagick not found. Please install ImageMagick for image conversions")
+	plan, err := c.Plan(inputExt, outputExt)
+	if err != nil {
+		return err
 	}
-
-	args := []string{req.InputPath}
-
-	// Add quality settings
-	if quality, ok := req.Options["quality"]; ok {
-		args = append(args, "-quality", quality)
+	// Catch bad options before a multi-step plan has done any work
+	if err := plan.ValidateOptions(req.Options); err != nil {
+		return err
 	}
 
-	// Add resize settings
-	if width, ok := req.Options["width"]; ok {
-		if height, ok := req.Options["height"]; ok {
-			args = append(args, "-resize", fmt.Sprintf("%sx%s", width, height))
-		} else {
-			args = append(args, "-resize", width)
-		}
+	if len(plan.Steps) == 1 {
+		return plan.Steps[0].Backend.Convert(ctx, req)
 	}
+	return c.runPlan(ctx, plan, req)
+}
 
-	args = append(args, req.OutputPath)
-
-	cmd := exec.Command(c.magickPath, args...)
-	return cmd.Run()
+// Backends returns the backends this converter dispatches to, in priority
+// order.
+func (c *Converter) Backends() []Backend {
+	return c.registry.Backends()
 }
 
-func (c *Converter) convertAudio(req ConversionRequest) error {
-	if c.ffmpegPath == "" {
-		return fmt.Errorf("ffmpeg not found. Please install FFmpeg for audio conversions")
-	}
+func (c *Converter) BatchConvert(requests []ConversionRequest) []error {
+	return c.BatchConvertContext(context.Background(), requests)
+}
 
-	args := []string{"-i", req.InputPath}
+// BatchOptions controls how BatchConvertStream schedules requests.
+type BatchOptions struct {
+	// Jobs is the number of conversions run at once; 0 means one per CPU.
+	Jobs int
+	// Limits caps concurrent conversions per backend name. Nil uses
+	// DefaultBatchLimits.
+	Limits   map[string]int
+	FailFast bool
+}
 
-	// Add bitrate settings
-	if bitrate, ok := req.Options["bitrate"]; ok {
-		args = append(args, "-b:a", bitrate)
-	}
+// DefaultBatchLimits keeps ffmpeg, which is multi-threaded itself, from
+// taking every worker when it runs alongside lighter image conversions.
+func DefaultBatchLimits() map[string]int {
+	return map[string]int{"ffmpeg": max(1, runtime.NumCPU()/4)}
+}
 
-	// Add sample rate settings
-	if sampleRate, ok := req.Options["sample_rate"]; ok {
-		args = append(args, "-ar", sampleRate)
+// BatchConvertContext converts requests in parallel with the default
+// options. Once ctx is done the remaining requests are not started and
+// report the context error.
+func (c *Converter) BatchConvertContext(ctx context.Context, requests []ConversionRequest) []error {
+	errs := make([]error, len(requests))
+	for result := range c.BatchConvertStream(ctx, requests, BatchOptions{}) {
+		errs[result.Index] = result.Err
 	}
-
-	args = append(args, "-y", req.OutputPath)
-
-	cmd := exec.Command(c.ffmpegPath, args...)
-	return cmd.Run()
+	return errs
 }
 
-func (c *Converter) convertDocument(req ConversionRequest) error {
-	if c.pandocPath == "" {
-		return fmt.Errorf("pandoc not found. Please install pandoc for document conversions")
+// BatchConvertStream schedules requests on a worker pool and streams one
+// result per request, indexed like requests, as each one finishes.
+func (c *Converter) BatchConvertStream(ctx context.Context, requests []ConversionRequest, opts BatchOptions) <-chan scheduler.Result {
+	s := scheduler.New(opts.Jobs)
+	s.FailFast = opts.FailFast
+	limits := opts.Limits
+	if limits == nil {
+		limits = DefaultBatchLimits()
 	}
-
-	args := []string{req.InputPath, "-o", req.OutputPath}
-
-	// Add PDF-specific options
-	if filepath.Ext(req.OutputPath) == ".pdf" {
-		args = append(args, "--pdf-engine=pdflatex")
+	for name, n := range limits {
+		s.SetLimit(name, n)
 	}
 
-	cmd := exec.Command(c.pandocPath, args...)
-	return cmd.Run()
-}
-
-func (c *Converter) BatchConvert(requests []ConversionRequest) []error {
-	errors := make([]error, len(requests))
-
+	jobs := make([]scheduler.Job, len(requests))
 	for i, req := range requests {
-		if err := c.Convert(req); err != nil {
-			errors[i] = fmt.Errorf("failed to convert %s: %w", req.InputPath, err)
+		req := req
+		jobs[i] = scheduler.Job{
+			Category: c.batchCategory(req),
+			Run: func(ctx context.Context) error {
+				if err := c.ConvertContext(ctx, req); err != nil {
+					return fmt.Errorf("failed to convert %s: %w", req.InputPath, err)
+				}
+				return nil
+			},
 		}
 	}
 
-	return errors
+	results := make(chan scheduler.Result, len(requests))
+	go func() {
+		defer close(results)
+		for result := range s.Run(ctx, jobs) {
+			if result.Skipped() {
+				result.Err = fmt.Errorf("skipped %s: %w", requests[result.Index].InputPath, result.Err)
+			}
+			results <- result
+		}
+	}()
+	return results
+}
+
+// batchCategory is the name of the backend that runs the first step of
+// req, which is the step the per-backend limits apply to.
+func (c *Converter) batchCategory(req ConversionRequest) string {
+	plan, err := c.Plan(filepath.Ext(req.InputPath), filepath.Ext(req.OutputPath))
+	if err != nil {
+		return ""
+	}
+	return plan.Steps[0].Backend.Name()
 }
 
 func (c *Converter) GetSupportedFormats() map[string]FormatSupport {
@@ -225,48 +191,61 @@ func (c *Converter) GetSupportedFormats() map[string]FormatSupport {
 }
 
 func (c *Converter) IsFormatSupported(ext string) bool {
-	ext = strings.ToLower(ext)
-	for _, format := range SupportedFormats {
-		for _, inputExt := range format.InputFormats {
-			if ext == inputExt {
-				return true
-			}
-		}
-	}
-	return false
+	return categoryOf(ext) != ""
 }
 
+// GetOutputFormats returns every extension inputExt can be converted to,
+// directly or through intermediate formats.
 func (c *Converter) GetOutputFormats(inputExt string) []string {
-	inputExt = strings.ToLower(inputExt)
-	for _, format := range SupportedFormats {
-		for _, ext := range format.InputFormats {
-			if inputExt == ext {
-				return format.OutputFormats
-			}
+	if categoryOf(inputExt) == "" {
+		return []string{}
+	}
+	return c.ReachableFormats(inputExt)
+}
+
+// GetInputFormats returns every input extension that can be converted to
+// outputExt.
+func (c *Converter) GetInputFormats(outputExt string) []string {
+	registry := formats.Default()
+	output, ok := registry.Lookup(outputExt)
+	if !ok {
+		return nil
+	}
+
+	var inputs []string
+	for _, category := range registry.Categories() {
+		if output.ProducibleFrom(category) {
+			inputs = append(inputs, registry.InputExtensions(category)...)
 		}
 	}
-	return []string{}
+	return inputs
 }
 
+// ValidateTools reports, for every backend in the default registry, whether
+// the tool behind it is available.
 func ValidateTools() map[string]bool {
-	return map[string]bool{
-		"ffmpeg":      checkTool("ffmpeg"),
-		"imagemagick": checkTool("magick"),
-		"pandoc":      checkTool("pandoc"),
+	tools := make(map[string]bool)
+	for _, backend := range DefaultRegistry().Backends() {
+		tools[backend.Name()] = backend.Available()
 	}
+	return tools
 }
 
 func isAudioFormat(ext string) bool {
-	audioExts := []string{".mp3", ".wav", ".flac", ".aac", ".ogg", ".m4a"}
-	for _, audioExt := range audioExts {
-		if ext == audioExt {
-			return true
-		}
+	return formats.Default().Is(ext, formats.CategoryAudio)
+}
+
+// categoryOf returns the category of ext if it can be read as a conversion
+// input, or "".
+func categoryOf(ext string) string {
+	format, ok := formats.Default().Lookup(ext)
+	if !ok || !format.Read {
+		return ""
 	}
-	return false
+	return format.Category
 }
 
-func checkTool(tool string) bool {
-	_, err := exec.LookPath(tool)
-	return err == nil
+func isOutputFormat(category, ext string) bool {
+	format, ok := formats.Default().Lookup(ext)
+	return ok && format.ProducibleFrom(category)
 }
@@ -0,0 +1,224 @@
+package converter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os/exec"
+	"strings"
+	"syscall"
+
+	"goverter/pkg/utils"
+)
+
+// maxStderrTail is how much of a tool's stderr is kept for error reports.
+// ffmpeg prints its banner and stream mapping first, so the tail is where
+// the actual failure is.
+const maxStderrTail = 8 * 1024
+
+// ToolError is returned when an external tool (ffmpeg, magick, pandoc)
+// exits unsuccessfully.
+type ToolError struct {
+	Tool     string
+	Args     []string // full argv, starting with the tool path
+	ExitCode int      // -1 if the tool did not exit normally
+	Stderr   string   // last maxStderrTail bytes of stderr
+	Err      error
+}
+
+func (e *ToolError) Error() string {
+	msg := fmt.Sprintf("%s failed (exit code %d)", e.Tool, e.ExitCode)
+	if line := e.LastLine(); line != "" {
+		msg += ": " + line
+	}
+	return msg
+}
+
+func (e *ToolError) Unwrap() error {
+	return e.Err
+}
+
+// LastLine returns the last non-empty line of the captured stderr, which is
+// usually the tool's own summary of what went wrong.
+func (e *ToolError) LastLine() string {
+	lines := strings.Split(strings.TrimSpace(e.Stderr), "\n")
+	for i := len(lines) - 1; i >= 0; i-- {
+		if line := strings.TrimSpace(lines[i]); line != "" {
+			return line
+		}
+	}
+	return ""
+}
+
+// CommandLine returns Args joined for display.
+func (e *ToolError) CommandLine() string {
+	return CommandLine(e.Args)
+}
+
+// CommandLine joins argv for display, quoting arguments that contain spaces
+// or quotes.
+func CommandLine(argv []string) string {
+	quoted := make([]string, len(argv))
+	for i, arg := range argv {
+		if strings.ContainsAny(arg, " \t\"'") {
+			arg = fmt.Sprintf("%q", arg)
+		}
+		quoted[i] = arg
+	}
+	return strings.Join(quoted, " ")
+}
+
+// runTool runs path with args, capturing the tail of stderr so that a
+// failure comes back as a *ToolError. stdout may be nil.
+func runTool(ctx context.Context, tool, path string, args []string, stdout io.Writer) error {
+	if recordDryRun(ctx, path, args) {
+		return nil
+	}
+	cmd := utils.CommandContext(ctx, path, args...)
+	cmd.Stdout = stdout
+	stderr := &tailBuffer{limit: maxStderrTail}
+	cmd.Stderr = stderr
+
+	return toolError(tool, cmd, stderr, cmd.Run())
+}
+
+// recordDryRun records the command and reports true when ctx is a dry run
+// (see utils.WithDryRun). Nothing runs in a dry run, so callers that read
+// what a command reports fill in placeholders for it instead.
+func recordDryRun(ctx context.Context, path string, args []string) bool {
+	log := utils.DryRun(ctx)
+	if log == nil {
+		return false
+	}
+	log.Record(path, args)
+	return true
+}
+
+// placeholder stands in for a value a dry run did not measure.
+func placeholder(name string) string {
+	return "<" + name + ">"
+}
+
+// toolError wraps the result of running cmd. It returns nil for a nil err.
+func toolError(tool string, cmd *exec.Cmd, stderr *tailBuffer, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	exitCode := -1
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		exitCode = exitErr.ExitCode()
+	}
+
+	return &ToolError{
+		Tool:     tool,
+		Args:     cmd.Args,
+		ExitCode: exitCode,
+		Stderr:   stderr.String(),
+		Err:      err,
+	}
+}
+
+// tailBuffer is an io.Writer that keeps only the last limit bytes written.
+type tailBuffer struct {
+	limit int
+	buf   []byte
+}
+
+func (t *tailBuffer) Write(p []byte) (int, error) {
+	t.buf = append(t.buf, p...)
+	if len(t.buf) > t.limit {
+		t.buf = t.buf[len(t.buf)-t.limit:]
+	}
+	return len(p), nil
+}
+
+func (t *tailBuffer) String() string {
+	return string(t.buf)
+}
+
+var (
+	missingCodecMarkers = []string{
+		"unknown encoder",
+		"unknown decoder",
+		"encoder not found",
+		"decoder not found",
+		"codec not currently supported",
+		"no decoder for",
+		"unsupported codec",
+	}
+	unsupportedFormatMarkers = []string{
+		"invalid data found when processing input",
+		"unable to find a suitable output format",
+		"unknown input format",
+		"unknown output format",
+		"no decode delegate",
+		"no encode delegate",
+		"improper image header",
+		"not a supported format",
+	}
+	permissionDeniedMarkers = []string{
+		"permission denied",
+		"operation not permitted",
+		"not authorized", // ImageMagick security policy
+	}
+	diskFullMarkers = []string{
+		"no space left on device",
+		"disk quota exceeded",
+	}
+)
+
+// IsMissingCodec reports whether err came from a tool lacking the encoder or
+// decoder the conversion needs.
+func IsMissingCodec(err error) bool {
+	return stderrContains(err, missingCodecMarkers)
+}
+
+// IsUnsupportedFormat reports whether err came from a tool that could not
+// read the input or write the requested output format.
+func IsUnsupportedFormat(err error) bool {
+	return stderrContains(err, unsupportedFormatMarkers)
+}
+
+func IsPermissionDenied(err error) bool {
+	return errors.Is(err, fs.ErrPermission) || stderrContains(err, permissionDeniedMarkers)
+}
+
+func IsDiskFull(err error) bool {
+	return errors.Is(err, syscall.ENOSPC) || stderrContains(err, diskFullMarkers)
+}
+
+// ErrorHint returns a short suggestion for a classified error, or "" if the
+// error is not recognised.
+func ErrorHint(err error) string {
+	switch {
+	case IsMissingCodec(err):
+		return "the installed tool was built without a required codec; install a fuller build or pick another format"
+	case IsUnsupportedFormat(err):
+		return "the input could not be read or the output format is not supported by the tool"
+	case IsPermissionDenied(err):
+		return "check read permission on the input and write permission on the output directory"
+	case IsDiskFull(err):
+		return "the output disk is full; free some space and retry"
+	default:
+		return ""
+	}
+}
+
+func stderrContains(err error, markers []string) bool {
+	var toolErr *ToolError
+	if !errors.As(err, &toolErr) {
+		return false
+	}
+
+	stderr := strings.ToLower(toolErr.Stderr)
+	for _, marker := range markers {
+		if strings.Contains(stderr, marker) {
+			return true
+		}
+	}
+	return false
+}
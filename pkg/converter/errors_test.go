@@ -0,0 +1,39 @@
+package converter
+
+import (
+	"context"
+	"io"
+	"reflect"
+	"testing"
+
+	"goverter/pkg/utils"
+)
+
+func TestCommandLine(t *testing.T) {
+	argv := []string{"/usr/bin/ffmpeg", "-i", "my clip.mov", "-vf", "drawtext=text='hi'", "-y", "out.mp4"}
+	want := `/usr/bin/ffmpeg -i "my clip.mov" -vf "drawtext=text='hi'" -y out.mp4`
+	if got := CommandLine(argv); got != want {
+		t.Errorf("CommandLine = %s, want %s", got, want)
+	}
+}
+
+func TestRunToolDryRun(t *testing.T) {
+	ctx, log := utils.WithDryRun(context.Background())
+	missing := "/nonexistent/ffmpeg"
+
+	if err := runTool(ctx, "ffmpeg", missing, []string{"-i", "in.mov", "out.mp4"}, nil); err != nil {
+		t.Fatalf("dry run executed the tool: %v", err)
+	}
+	want := [][]string{{missing, "-i", "in.mov", "out.mp4"}}
+	if got := log.Commands(); !reflect.DeepEqual(got, want) {
+		t.Errorf("recorded %q, want %q", got, want)
+	}
+
+	// Commands whose output is read are recorded too, not run
+	if err := runTool(ctx, "ffmpeg", missing, []string{"-version"}, io.Discard); err != nil {
+		t.Errorf("dry run executed a command with a stdout reader: %v", err)
+	}
+	if got := log.Commands(); len(got) != 2 {
+		t.Errorf("recorded %q, want both commands", got)
+	}
+}
@@ -0,0 +1,412 @@
+package converter
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"goverter/pkg/formats"
+	"goverter/pkg/probe"
+	"goverter/pkg/utils"
+)
+
+// ffmpegBackend handles video and audio inputs.
+type ffmpegBackend struct {
+	path   string
+	prober *probe.Prober
+}
+
+func newFFmpegBackend() *ffmpegBackend {
+	path, _ := exec.LookPath("ffmpeg")
+	return &ffmpegBackend{path: path, prober: probe.New()}
+}
+
+func (b *ffmpegBackend) Name() string {
+	return "ffmpeg"
+}
+
+func (b *ffmpegBackend) Available() bool {
+	return b.path != ""
+}
+
+func (b *ffmpegBackend) CanConvert(inExt, outExt string) bool {
+	switch category := categoryOf(inExt); category {
+	case formats.CategoryVideo, formats.CategoryAudio:
+		return isOutputFormat(category, outExt)
+	default:
+		return false
+	}
+}
+
+func (b *ffmpegBackend) Convert(ctx context.Context, req ConversionRequest) error {
+	if categoryOf(filepath.Ext(req.InputPath)) == formats.CategoryVideo {
+		return b.convertVideo(ctx, req)
+	}
+	return b.convertAudio(ctx, req)
+}
+
+func (b *ffmpegBackend) convertVideo(ctx context.Context, req ConversionRequest) error {
+	if b.path == "" {
+		return fmt.Errorf("ffmpeg not found. Please install FFmpeg for video conversions")
+	}
+
+	outputExt := filepath.Ext(req.OutputPath)
+	args := []string{"-i", req.InputPath}
+
+	switch {
+	case isAudioFormat(outputExt):
+		// Handle audio extraction (video to audio)
+		opts, err := parseAudio(req.Options, outputExt)
+		if err != nil {
+			return err
+		}
+		encoder := audioEncoder(opts.Codec, outputExt)
+		args = append(args, "-vn")
+		args = append(args, audioCodecArgs(encoder)...)
+		if _, lossy := qualityScales[encoder]; lossy && opts.Bitrate == 0 && opts.Quality == 0 {
+			opts.Bitrate = 192000
+		}
+		args = append(args, audioArgs(encoder, opts)...)
+		if opts.StripSilence {
+			seek, err := b.stripSilenceArgs(ctx, req.InputPath, opts.Silence)
+			if err != nil {
+				return err
+			}
+			args = append(seek, args...)
+		}
+		if opts.Loudness.Target != 0 {
+			norm, err := b.loudnormArgs(ctx, req.InputPath, opts.Loudness, opts.SampleRate)
+			if err != nil {
+				return err
+			}
+			args = append(args, norm...)
+		}
+	case IsAnimatedFormat(outputExt):
+		return b.animate(ctx, req)
+	case formats.Default().Is(outputExt, formats.CategoryImage):
+		// Contact sheet: evenly spaced thumbnails tiled into one image
+		opts, err := ParseImageOptions(req.Options)
+		if err != nil {
+			return err
+		}
+		args = append(args, "-vf", b.contactSheetFilter(ctx, req.InputPath, opts.withDefaults()), "-frames:v", "1")
+		args = append(args, QualityArgs(defaultEncoder(outputExt), opts.Quality)...)
+	default:
+		// Regular video to video conversion
+		opts, err := ParseVideoOptions(req.Options)
+		if err != nil {
+			return err
+		}
+		if err := opts.checkCodecs(outputExt); err != nil {
+			return err
+		}
+		var norm []string
+		if opts.Loudness.Target != 0 {
+			if norm, err = b.loudnormArgs(ctx, req.InputPath, opts.Loudness, 0); err != nil {
+				return err
+			}
+		}
+		if opts.TargetSize > 0 {
+			return b.encodeToSize(ctx, req, opts, norm)
+		}
+		args = append(args, videoEncodeArgs(opts, outputExt)...)
+		args = append(args, norm...)
+	}
+
+	args = append(args, "-y", req.OutputPath)
+
+	return b.runFFmpeg(ctx, req, args)
+}
+
+// videoEncodeArgs selects and tunes the encoders for a video output. With
+// no known codec, as for a user-defined container, ffmpeg picks the encoder
+// but a pix_fmt is still passed on.
+func videoEncodeArgs(opts VideoOptions, outputExt string) []string {
+	var args []string
+	if codec, ok := resolveCodec(opts.VideoCodec, outputExt, true); ok {
+		args = append(args, opts.codecArgs(codec)...)
+	} else if opts.PixelFormat != "" {
+		args = append(args, "-pix_fmt", opts.PixelFormat)
+	}
+	args = append(args, videoArgs(videoEncoder(opts, outputExt), opts)...)
+	if opts.AudioCodec != "" {
+		args = append(args, "-c:a", codecs[opts.AudioCodec].Encoder)
+	}
+	return args
+}
+
+// videoEncoder returns the video encoder videoEncodeArgs selects.
+func videoEncoder(opts VideoOptions, outputExt string) string {
+	if codec, ok := resolveCodec(opts.VideoCodec, outputExt, true); ok {
+		return codec.Encoder
+	}
+	return defaultEncoder(outputExt)
+}
+
+// videoArgs maps typed video options onto ffmpeg arguments for encoder. A
+// bit rate takes precedence over quality.
+func videoArgs(encoder string, opts VideoOptions) []string {
+	var args []string
+	if opts.Bitrate > 0 {
+		args = append(args, "-b:v", formatBitrate(opts.Bitrate))
+	} else {
+		args = append(args, QualityArgs(encoder, opts.Quality)...)
+	}
+	if opts.AudioBitrate > 0 {
+		args = append(args, "-b:a", formatBitrate(opts.AudioBitrate))
+	}
+
+	if opts.Width > 0 || opts.Height > 0 {
+		args = append(args, "-vf", scaleFilter(opts.Width, opts.Height))
+	}
+	if opts.FPS > 0 {
+		args = append(args, "-r", formatFPS(opts.FPS))
+	}
+	return args
+}
+
+// audioArgs maps typed audio options onto ffmpeg arguments for encoder. A
+// bit rate takes precedence over quality.
+func audioArgs(encoder string, opts AudioOptions) []string {
+	var args []string
+	if opts.Bitrate > 0 {
+		args = append(args, "-b:a", formatBitrate(opts.Bitrate))
+	} else {
+		args = append(args, QualityArgs(encoder, opts.Quality)...)
+	}
+	if opts.SampleRate > 0 {
+		args = append(args, "-ar", strconv.Itoa(opts.SampleRate))
+	}
+	if opts.Channels > 0 {
+		args = append(args, "-ac", strconv.Itoa(opts.Channels))
+	}
+	return args
+}
+
+// audioEncodeArgs selects and tunes the encoder for an audio output.
+func audioEncodeArgs(opts AudioOptions, outputExt string) []string {
+	encoder := audioEncoder(opts.Codec, outputExt)
+	var args []string
+	if opts.Codec != "" {
+		args = append(args, "-c:a", encoder)
+	}
+	return append(args, audioArgs(encoder, opts)...)
+}
+
+func parseAudio(options map[string]string, outputExt string) (AudioOptions, error) {
+	opts, err := ParseAudioOptions(options)
+	if err != nil {
+		return opts, err
+	}
+	return opts, opts.checkCodecs(outputExt)
+}
+
+// audioCodecArgs selects encoder. It returns nil when no encoder is known,
+// as for a user-defined format, so that ffmpeg picks the container's own.
+func audioCodecArgs(encoder string) []string {
+	if encoder == "" {
+		return nil
+	}
+	return []string{"-c:a", encoder}
+}
+
+// audioEncoder returns the encoder for the audio_codec option, or the
+// container's default; it is "" for formats goverter has no default for.
+func audioEncoder(codec, outputExt string) string {
+	if codec != "" {
+		return codecs[codec].Encoder
+	}
+	return defaultEncoder(outputExt)
+}
+
+// scaleFilter scales to the requested size; a missing side keeps the
+// aspect ratio.
+func scaleFilter(width, height int) string {
+	return fmt.Sprintf("scale=%s:%s", sideOrAuto(width, "-2"), sideOrAuto(height, "-2"))
+}
+
+func sideOrAuto(value int, auto string) string {
+	if value > 0 {
+		return strconv.Itoa(value)
+	}
+	return auto
+}
+
+func formatFPS(fps float64) string {
+	return strconv.FormatFloat(fps, 'f', -1, 64)
+}
+
+// contactSheetFilter samples columns*rows frames spread over the probed
+// duration and tiles them. Without a duration it falls back to one frame
+// every ten seconds.
+func (b *ffmpegBackend) contactSheetFilter(ctx context.Context, input string, opts ImageOptions) string {
+	rate := "1/10"
+	if duration := b.probeDuration(ctx, input); duration > 0 {
+		rate = strconv.FormatFloat(float64(opts.Columns*opts.Rows)/duration, 'f', 6, 64)
+	}
+
+	return fmt.Sprintf("fps=%s,scale=%d:-1:flags=lanczos,tile=%dx%d", rate, opts.TileWidth, opts.Columns, opts.Rows)
+}
+
+// Cost makes animations an expensive intermediate so the planner routes
+// video to other image formats through a still contact sheet instead.
+func (b *ffmpegBackend) Cost(inExt, outExt string) int {
+	if IsAnimatedFormat(outExt) {
+		return 5
+	}
+	return 1
+}
+
+func (b *ffmpegBackend) convertAudio(ctx context.Context, req ConversionRequest) error {
+	if b.path == "" {
+		return fmt.Errorf("ffmpeg not found. Please install FFmpeg for audio conversions")
+	}
+
+	outputExt := filepath.Ext(req.OutputPath)
+	opts, err := parseAudio(req.Options, outputExt)
+	if err != nil {
+		return err
+	}
+
+	var args []string
+	if opts.StripSilence {
+		if args, err = b.stripSilenceArgs(ctx, req.InputPath, opts.Silence); err != nil {
+			return err
+		}
+	}
+	args = append(args, "-i", req.InputPath)
+	args = append(args, audioEncodeArgs(opts, outputExt)...)
+	if opts.Loudness.Target != 0 {
+		norm, err := b.loudnormArgs(ctx, req.InputPath, opts.Loudness, opts.SampleRate)
+		if err != nil {
+			return err
+		}
+		args = append(args, norm...)
+	}
+	args = append(args, "-y", req.OutputPath)
+
+	return b.runFFmpeg(ctx, req, args)
+}
+
+// runFFmpeg runs ffmpeg with args. When the request has a Progress channel,
+// ffmpeg reports its position on stdout and the completed fraction of the
+// probed input duration is pushed to the channel. Sends never block, so a
+// slow reader only misses intermediate values.
+func (b *ffmpegBackend) runFFmpeg(ctx context.Context, req ConversionRequest, args []string) error {
+	if req.Progress == nil {
+		return runTool(ctx, "ffmpeg", b.path, args, nil)
+	}
+	return b.runFFmpegTimed(ctx, req.Progress, b.probeDuration(ctx, req.InputPath), args)
+}
+
+// runFFmpegTimed runs ffmpeg and reports progress against an output that
+// will be duration seconds long.
+func (b *ffmpegBackend) runFFmpegTimed(ctx context.Context, progress chan float64, duration float64, args []string) error {
+	return b.runFFmpegCapture(ctx, progress, duration, args, nil)
+}
+
+// runFFmpegCapture is runFFmpegTimed that also copies ffmpeg's stderr to
+// stderr, for callers that read what filters report there.
+func (b *ffmpegBackend) runFFmpegCapture(ctx context.Context, progress chan float64, duration float64, args []string, stderr io.Writer) error {
+	tail := &tailBuffer{limit: maxStderrTail}
+	var errOut io.Writer = tail
+	if stderr != nil {
+		errOut = io.MultiWriter(tail, stderr)
+	}
+
+	// Stats lines would crowd stderr, which callers may be parsing
+	if progress == nil {
+		args = append([]string{"-nostats"}, args...)
+		if recordDryRun(ctx, b.path, args) {
+			return nil
+		}
+		cmd := utils.CommandContext(ctx, b.path, args...)
+		cmd.Stderr = errOut
+		return toolError("ffmpeg", cmd, tail, cmd.Run())
+	}
+
+	args = append([]string{"-progress", "pipe:1", "-nostats"}, args...)
+	if recordDryRun(ctx, b.path, args) {
+		return nil
+	}
+	cmd := utils.CommandContext(ctx, b.path, args...)
+	cmd.Stderr = errOut
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return toolError("ffmpeg", cmd, tail, err)
+	}
+
+	scanner := bufio.NewScanner(stdout)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), "=")
+		if !ok {
+			continue
+		}
+
+		switch key {
+		case "out_time_ms", "out_time_us":
+			// Both keys are reported in microseconds.
+			micros, err := strconv.ParseInt(value, 10, 64)
+			if err != nil || duration <= 0 {
+				continue
+			}
+			fraction := float64(micros) / 1e6 / duration
+			if fraction > 1 {
+				fraction = 1
+			}
+			sendProgress(progress, fraction)
+		case "progress":
+			if value == "end" {
+				sendProgress(progress, 1)
+			}
+		}
+	}
+
+	return toolError("ffmpeg", cmd, tail, cmd.Wait())
+}
+
+// probeDuration returns the duration of path in seconds, or 0 if it cannot
+// be determined.
+func (b *ffmpegBackend) probeDuration(ctx context.Context, path string) float64 {
+	info, err := b.prober.Probe(ctx, path)
+	if err != nil {
+		return 0
+	}
+	return info.Format.Duration
+}
+
+func sendProgress(progress chan float64, value float64) {
+	select {
+	case progress <- value:
+	default:
+	}
+}
+
+// scaleProgress returns a channel whose values are forwarded to progress
+// as offset+value*share, and a function to call once the step is over.
+func scaleProgress(progress chan float64, offset, share float64) (chan float64, func()) {
+	if progress == nil {
+		return nil, func() {}
+	}
+
+	step := make(chan float64, 16)
+	forwarded := make(chan struct{})
+	go func() {
+		defer close(forwarded)
+		for value := range step {
+			sendProgress(progress, offset+value*share)
+		}
+	}()
+	return step, func() {
+		close(step)
+		<-forwarded
+	}
+}
@@ -0,0 +1,24 @@
+package converter
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestVideoEncodeArgsPixelFormat(t *testing.T) {
+	tests := []struct {
+		ext  string
+		opts VideoOptions
+		want string
+	}{
+		{".mp4", VideoOptions{PixelFormat: "yuv420p"}, "-pix_fmt yuv420p"},
+		{".mkv", VideoOptions{VideoCodec: "hevc", PixelFormat: "yuv420p10le"}, "-c:v libx265 -pix_fmt yuv420p10le"},
+		// No codec is known for the container; ffmpeg picks the encoder
+		{".ts", VideoOptions{PixelFormat: "yuv422p"}, "-pix_fmt yuv422p"},
+	}
+	for _, tt := range tests {
+		if got := strings.Join(videoEncodeArgs(tt.opts, tt.ext), " "); !strings.Contains(got, tt.want) {
+			t.Errorf("%s %+v: args %q, want them to contain %q", tt.ext, tt.opts, got, tt.want)
+		}
+	}
+}
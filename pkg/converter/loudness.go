@@ -0,0 +1,150 @@
+package converter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"goverter/pkg/formats"
+	"goverter/pkg/utils"
+)
+
+// Loudness is an EBU R128 measurement of a file's audio.
+type Loudness struct {
+	Integrated float64 `json:"integrated_lufs"`
+	TruePeak   float64 `json:"true_peak_dbtp"`
+	Range      float64 `json:"lra_lu"`
+	Threshold  float64 `json:"threshold_lufs"`
+
+	// offset is loudnorm's suggested gain offset for the target the
+	// measurement was taken with.
+	offset float64
+}
+
+// MeasureLoudness reports the loudness of a video or audio file without
+// converting it.
+func (c *Converter) MeasureLoudness(ctx context.Context, path string, progress chan float64) (*Loudness, error) {
+	b := c.ffmpegBackend()
+	if b == nil || !b.Available() {
+		return nil, fmt.Errorf("ffmpeg not found. Please install FFmpeg to measure loudness")
+	}
+	switch category := categoryOf(filepath.Ext(path)); category {
+	case formats.CategoryVideo, formats.CategoryAudio:
+	default:
+		return nil, fmt.Errorf("cannot measure the loudness of %s files", filepath.Ext(path))
+	}
+	return b.measureLoudness(ctx, path, LoudnessOptions{}, progress)
+}
+
+// measureLoudness runs loudnorm's analysis pass over the audio of input.
+// The measurement is the same for any target, but the offset it suggests
+// is only valid for the target it was taken with. A dry run only records
+// the pass and returns a nil measurement.
+func (b *ffmpegBackend) measureLoudness(ctx context.Context, input string, target LoudnessOptions, progress chan float64) (*Loudness, error) {
+	filter := "loudnorm=print_format=json"
+	if target.Target != 0 {
+		filter = fmt.Sprintf("loudnorm=%s:print_format=json", loudnormTarget(target))
+	}
+	args := []string{"-hide_banner", "-i", input, "-vn", "-sn", "-dn", "-af", filter, "-f", "null", os.DevNull}
+
+	stderr := &tailBuffer{limit: maxStderrTail}
+	if err := b.runFFmpegCapture(ctx, progress, b.probeDuration(ctx, input), args, stderr); err != nil {
+		return nil, err
+	}
+	if utils.DryRun(ctx) != nil {
+		return nil, nil
+	}
+	l, err := parseLoudnorm(stderr.String())
+	if err != nil {
+		return nil, err
+	}
+	if math.IsInf(l.Integrated, 0) || math.IsInf(l.TruePeak, 0) {
+		return nil, fmt.Errorf("%s is silent, so it has no loudness to measure", input)
+	}
+	return l, nil
+}
+
+// parseLoudnorm reads the JSON block loudnorm prints when ffmpeg exits.
+func parseLoudnorm(output string) (*Loudness, error) {
+	start, end := strings.LastIndex(output, "{"), strings.LastIndex(output, "}")
+	if start < 0 || end < start {
+		return nil, errors.New("ffmpeg printed no loudness measurement; does the input have audio?")
+	}
+
+	var raw struct {
+		InputI       string `json:"input_i"`
+		InputTP      string `json:"input_tp"`
+		InputLRA     string `json:"input_lra"`
+		InputThresh  string `json:"input_thresh"`
+		TargetOffset string `json:"target_offset"`
+	}
+	if err := json.Unmarshal([]byte(output[start:end+1]), &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse the loudness measurement: %w", err)
+	}
+
+	var errs []error
+	value := func(name, s string) float64 {
+		v, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid %s %q", name, s))
+		}
+		return v
+	}
+	l := &Loudness{
+		Integrated: value("input_i", raw.InputI),
+		TruePeak:   value("input_tp", raw.InputTP),
+		Range:      value("input_lra", raw.InputLRA),
+		Threshold:  value("input_thresh", raw.InputThresh),
+		offset:     value("target_offset", raw.TargetOffset),
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, fmt.Errorf("failed to parse the loudness measurement: %w", err)
+	}
+	return l, nil
+}
+
+// loudnormArgs measures the input and returns the audio filter that brings
+// it to the target in a second pass. Measuring reads the whole input and
+// reports no progress. loudnorm resamples to 192 kHz, so unless the caller
+// sets a sample rate the source's rate is asked for back.
+func (b *ffmpegBackend) loudnormArgs(ctx context.Context, input string, target LoudnessOptions, sampleRate int) ([]string, error) {
+	rate := 48000
+	if info, err := b.prober.Probe(ctx, input); err == nil {
+		stream := info.AudioStream()
+		if stream == nil {
+			return nil, fmt.Errorf("%s has no audio to normalise", input)
+		}
+		if stream.SampleRate > 0 {
+			rate = stream.SampleRate
+		}
+	}
+
+	m, err := b.measureLoudness(ctx, input, target, nil)
+	if err != nil {
+		return nil, err
+	}
+	var measured string
+	if m != nil {
+		measured = fmt.Sprintf("measured_I=%.2f:measured_TP=%.2f:measured_LRA=%.2f:measured_thresh=%.2f:offset=%.2f",
+			m.Integrated, m.TruePeak, m.Range, m.Threshold, m.offset)
+	} else {
+		measured = fmt.Sprintf("measured_I=%s:measured_TP=%s:measured_LRA=%s:measured_thresh=%s:offset=%s",
+			placeholder("I"), placeholder("TP"), placeholder("LRA"), placeholder("thresh"), placeholder("offset"))
+	}
+	filter := fmt.Sprintf("loudnorm=%s:%s:linear=true", loudnormTarget(target), measured)
+	args := []string{"-af", filter}
+	if sampleRate == 0 {
+		args = append(args, "-ar", strconv.Itoa(rate))
+	}
+	return args, nil
+}
+
+func loudnormTarget(target LoudnessOptions) string {
+	return fmt.Sprintf("I=%g:TP=%g:LRA=%g", target.Target, target.TruePeak, target.Range)
+}
@@ -0,0 +1,68 @@
+package converter
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+
+	"goverter/pkg/formats"
+)
+
+// magickBackend handles image inputs through ImageMagick.
+type magickBackend struct {
+	path string
+}
+
+func newMagickBackend() *magickBackend {
+	path, _ := exec.LookPath("magick")
+	return &magickBackend{path: path}
+}
+
+func (b *magickBackend) Name() string {
+	return "imagemagick"
+}
+
+func (b *magickBackend) Available() bool {
+	return b.path != ""
+}
+
+func (b *magickBackend) CanConvert(inExt, outExt string) bool {
+	if canonicalExt(inExt) == ".pdf" {
+		// Rasterising a PDF page; PDF to PDF is left to pandoc.
+		return formats.Default().Is(outExt, formats.CategoryImage) && isOutputFormat(formats.CategoryImage, outExt)
+	}
+	return categoryOf(inExt) == formats.CategoryImage && isOutputFormat(formats.CategoryImage, outExt)
+}
+
+func (b *magickBackend) Convert(ctx context.Context, req ConversionRequest) error {
+	if b.path == "" {
+		return fmt.Errorf("ImageMagick not found. Please install ImageMagick for image conversions")
+	}
+
+	opts, err := ParseImageOptions(req.Options)
+	if err != nil {
+		return err
+	}
+
+	args := []string{req.InputPath}
+	if canonicalExt(filepath.Ext(req.InputPath)) == ".pdf" {
+		// Render the first page at a readable resolution
+		args = []string{"-density", "150", req.InputPath + "[0]"}
+	}
+
+	// ImageMagick's -quality is already on a 1-100 scale
+	if opts.Quality > 0 {
+		args = append(args, "-quality", strconv.Itoa(opts.Quality))
+	}
+
+	// Fit within the requested size; a missing side keeps the aspect ratio
+	if opts.Width > 0 || opts.Height > 0 {
+		args = append(args, "-resize", fmt.Sprintf("%sx%s", sideOrAuto(opts.Width, ""), sideOrAuto(opts.Height, "")))
+	}
+
+	args = append(args, req.OutputPath)
+
+	return runTool(ctx, "magick", b.path, args, nil)
+}
@@ -0,0 +1,561 @@
+package converter
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"goverter/pkg/formats"
+)
+
+// ConversionRequest.Options is a string map so that it can come straight
+// from flags, job files, presets and JSON. Backends parse it into the typed
+// options of the output's category, which checks ranges and units and
+// rejects keys the category does not use.
+
+// VideoOptions apply to video outputs. Zero values mean "encoder default".
+type VideoOptions struct {
+	Quality      int   // 1-100, mapped to the encoder's own scale
+	Bitrate      int64 // bits per second
+	AudioBitrate int64
+	Width        int // a missing side keeps the aspect ratio
+	Height       int
+	FPS          float64
+
+	// TargetSize, in bytes, switches to a two-pass encode whose bit rate is
+	// derived from the duration. It excludes Bitrate and Quality.
+	TargetSize int64
+
+	// Codec choices; see codecs.go for the accepted values. Empty means
+	// the container's default.
+	VideoCodec    string
+	AudioCodec    string
+	PixelFormat   string
+	Profile       string
+	Level         string
+	EncoderPreset string
+
+	Loudness LoudnessOptions
+}
+
+// AudioOptions apply to audio outputs, including audio extracted from video.
+type AudioOptions struct {
+	Quality    int // 1-100, used when no bitrate is given
+	Bitrate    int64
+	SampleRate int // Hz
+	Channels   int
+	Codec      string
+	Loudness   LoudnessOptions
+
+	// StripSilence cuts leading and trailing silence, as detected with
+	// Silence.
+	StripSilence bool
+	Silence      SilenceOptions
+}
+
+// SilenceOptions tune silence detection: audio that stays below Threshold
+// dB for at least MinLength seconds is silence.
+type SilenceOptions struct {
+	Threshold float64
+	MinLength float64
+}
+
+// LoudnessOptions turn on two-pass EBU R128 loudness normalisation of the
+// audio. A zero Target leaves the loudness alone.
+type LoudnessOptions struct {
+	Target   float64 // integrated loudness in LUFS
+	TruePeak float64 // ceiling in dBTP
+	Range    float64 // loudness range in LU
+}
+
+// ImageOptions apply to image outputs. Columns, Rows and TileWidth lay out
+// contact sheets made from video; Animation applies to animated GIF, WebP
+// and APNG outputs made from video.
+type ImageOptions struct {
+	Quality   int // 1-100
+	Width     int
+	Height    int
+	Columns   int
+	Rows      int
+	TileWidth int
+	Animation AnimationOptions
+}
+
+// AnimationOptions shape an animation made from video. FPS and the image's
+// Width are upper limits: the source is never upscaled or sped up.
+type AnimationOptions struct {
+	FPS       float64
+	Dither    string // GIF palette dithering
+	MaxColors int    // GIF palette size, 2-256
+	Start     float64
+	End       float64 // seconds into the source; 0 means the end
+	Loop      int     // number of plays, 0 for forever
+}
+
+// DocumentOptions apply to document outputs.
+type DocumentOptions struct {
+	PDFEngine string // pandoc --pdf-engine for PDF outputs
+	TOC       bool   // include a table of contents
+}
+
+func (o ImageOptions) withDefaults() ImageOptions {
+	if o.Animation.FPS == 0 {
+		o.Animation.FPS = 10
+	}
+	if o.Animation.Dither == "" {
+		o.Animation.Dither = "sierra2_4a"
+	}
+	if o.Animation.MaxColors == 0 {
+		o.Animation.MaxColors = 256
+	}
+	if o.Width == 0 && o.Height == 0 {
+		o.Width = 480
+	}
+	if o.Columns == 0 {
+		o.Columns = 4
+	}
+	if o.Rows == 0 {
+		o.Rows = 4
+	}
+	if o.TileWidth == 0 {
+		o.TileWidth = 320
+	}
+	return o
+}
+
+func (o SilenceOptions) withDefaults() SilenceOptions {
+	if o.Threshold == 0 {
+		o.Threshold = -35
+	}
+	if o.MinLength == 0 {
+		o.MinLength = 0.5
+	}
+	return o
+}
+
+func (o DocumentOptions) withDefaults() DocumentOptions {
+	if o.PDFEngine == "" {
+		o.PDFEngine = "pdflatex"
+	}
+	return o
+}
+
+func ParseVideoOptions(options map[string]string) (VideoOptions, error) {
+	p := newOptionParser(options)
+	o := p.video()
+	return o, p.err()
+}
+
+func (p *optionParser) video() VideoOptions {
+	o := VideoOptions{
+		Quality:      p.quality(),
+		Bitrate:      p.bitrate("bitrate"),
+		AudioBitrate: p.bitrate("audio_bitrate"),
+		Width:        p.int("width", 1, 16384),
+		Height:       p.int("height", 1, 16384),
+		FPS:          p.float("fps", 0.01, 1000),
+		TargetSize:   p.size("target_size"),
+
+		VideoCodec:    p.oneOf("video_codec", codecNames(true)...),
+		AudioCodec:    p.oneOf("audio_codec", codecNames(false)...),
+		PixelFormat:   p.oneOf("pix_fmt", pixelFormatNames()...),
+		Profile:       strings.ToLower(p.string("profile")),
+		Level:         p.string("level"),
+		EncoderPreset: strings.ToLower(p.string("encoder_preset")),
+		Loudness:      p.loudness(),
+	}
+	if o.TargetSize > 0 && (o.Bitrate > 0 || o.Quality > 0) {
+		p.errs = append(p.errs, errors.New("target_size cannot be combined with bitrate or quality"))
+	}
+	return o
+}
+
+func ParseAudioOptions(options map[string]string) (AudioOptions, error) {
+	p := newOptionParser(options)
+	o := p.audio()
+	return o, p.err()
+}
+
+func (p *optionParser) audio() AudioOptions {
+	o := AudioOptions{
+		Quality:    p.quality(),
+		Bitrate:    p.bitrate("bitrate"),
+		SampleRate: int(p.rate("sample_rate", 8000, 384000)),
+		Channels:   p.int("channels", 1, 8),
+		Codec:      p.oneOf("audio_codec", codecNames(false)...),
+		Loudness:   p.loudness(),
+
+		StripSilence: p.bool("strip_silence"),
+		Silence: SilenceOptions{
+			Threshold: p.float("silence_threshold", -90, -10),
+			MinLength: p.float("min_silence", 0.05, 60),
+		},
+	}
+	if !o.StripSilence && (o.Silence != SilenceOptions{}) {
+		p.errs = append(p.errs, errors.New("silence_threshold and min_silence need strip_silence"))
+	}
+	return o
+}
+
+func ParseImageOptions(options map[string]string) (ImageOptions, error) {
+	p := newOptionParser(options)
+	o := p.image()
+	return o, p.err()
+}
+
+func (p *optionParser) image() ImageOptions {
+	o := ImageOptions{
+		Quality:   p.quality(),
+		Width:     p.int("width", 1, 16384),
+		Height:    p.int("height", 1, 16384),
+		Columns:   p.int("columns", 1, 32),
+		Rows:      p.int("rows", 1, 32),
+		TileWidth: p.int("tile_width", 16, 4096),
+		Animation: AnimationOptions{
+			FPS:       p.float("fps", 0.01, 100),
+			Dither:    p.oneOf("dither", ditherModes...),
+			MaxColors: p.int("max_colors", 2, 256),
+			Start:     p.timestamp("start"),
+			End:       p.timestamp("end"),
+			Loop:      p.int("loop", 0, 65535),
+		},
+	}
+	if o.Animation.End > 0 && o.Animation.End <= o.Animation.Start {
+		p.errs = append(p.errs, errors.New("end must be after start"))
+	}
+	return o
+}
+
+var ditherModes = []string{"none", "bayer", "floyd_steinberg", "sierra2", "sierra2_4a"}
+
+var pdfEngines = []string{"pdflatex", "xelatex", "lualatex", "wkhtmltopdf", "weasyprint", "typst"}
+
+func ParseDocumentOptions(options map[string]string) (DocumentOptions, error) {
+	p := newOptionParser(options)
+	o := p.document()
+	return o, p.err()
+}
+
+func (p *optionParser) document() DocumentOptions {
+	// Documents have nothing to tune with quality, but it is still checked
+	// and accepted so that one quality setting works for mixed batches
+	p.quality()
+	o := DocumentOptions{
+		PDFEngine: p.oneOf("pdf_engine", pdfEngines...),
+		TOC:       p.bool("toc"),
+	}
+	return o
+}
+
+// optionKeys returns the options that outputs of category understand.
+func optionKeys(category string) map[string]bool {
+	p := newOptionParser(nil)
+	switch category {
+	case formats.CategoryVideo:
+		p.video()
+	case formats.CategoryAudio:
+		p.audio()
+	case formats.CategoryImage:
+		p.image()
+	case formats.CategoryDocument:
+		p.document()
+	}
+	return p.used
+}
+
+// ValidateOptions parses options for the category of outputExt and checks
+// codec choices against the container, so that callers can reject bad
+// options before queueing any work.
+func ValidateOptions(outputExt string, options map[string]string) error {
+	var err error
+	switch categoryOf(outputExt) {
+	case formats.CategoryVideo:
+		var opts VideoOptions
+		if opts, err = ParseVideoOptions(options); err == nil {
+			err = opts.checkCodecs(outputExt)
+		}
+	case formats.CategoryAudio:
+		var opts AudioOptions
+		if opts, err = ParseAudioOptions(options); err == nil {
+			err = opts.checkCodecs(outputExt)
+		}
+	case formats.CategoryImage:
+		_, err = ParseImageOptions(options)
+	case formats.CategoryDocument:
+		_, err = ParseDocumentOptions(options)
+	}
+	return err
+}
+
+// ParseBitrate parses a bit rate in bits per second with an optional k, M or
+// G suffix: "128k", "2M", "1.5M", "800000".
+func ParseBitrate(s string) (int64, error) {
+	value, err := parseUnits(s, 1000)
+	if err != nil || value <= 0 {
+		return 0, fmt.Errorf("invalid bit rate %q: expected a number with an optional k, M or G suffix", s)
+	}
+	return value, nil
+}
+
+// ParseSize parses a file size in bytes. k, M and G are powers of 1000 and
+// Ki, Mi and Gi powers of 1024; a trailing B is optional: "25MB", "8MiB",
+// "500k".
+func ParseSize(s string) (int64, error) {
+	number := strings.TrimSuffix(strings.TrimSpace(s), "B")
+	base := 1000.0
+	if strings.HasSuffix(number, "i") {
+		number, base = strings.TrimSuffix(number, "i"), 1024
+	}
+	value, err := parseUnits(number, base)
+	if err != nil || value <= 0 {
+		return 0, fmt.Errorf("invalid size %q: expected a number with an optional k, M or G suffix", s)
+	}
+	return value, nil
+}
+
+// ParseTimestamp parses "90", "1:30", "00:01:30" or "00:01:30.5" into
+// seconds.
+func ParseTimestamp(s string) (float64, error) {
+	parts := strings.Split(strings.TrimSpace(s), ":")
+	if len(parts) > 3 {
+		return 0, fmt.Errorf("invalid timestamp %q", s)
+	}
+	var seconds float64
+	for i, part := range parts {
+		value, err := strconv.ParseFloat(part, 64)
+		if err != nil || math.IsNaN(value) || math.IsInf(value, 0) || value < 0 || (i > 0 && value >= 60) {
+			return 0, fmt.Errorf("invalid timestamp %q", s)
+		}
+		seconds = seconds*60 + value
+	}
+	return seconds, nil
+}
+
+// parseUnits parses a number with an optional k, M or G multiplier of base.
+// A trailing "b", "bps" or "Hz" is ignored.
+func parseUnits(s string, base float64) (int64, error) {
+	s = strings.TrimSpace(s)
+	for _, suffix := range []string{"bps", "b", "Hz", "hz"} {
+		s = strings.TrimSuffix(s, suffix)
+	}
+
+	multiplier := 1.0
+	if s != "" {
+		switch s[len(s)-1] {
+		case 'k', 'K':
+			multiplier = base
+		case 'm', 'M':
+			multiplier = base * base
+		case 'g', 'G':
+			multiplier = base * base * base
+		}
+		if multiplier != 1 {
+			s = s[:len(s)-1]
+		}
+	}
+
+	number, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(number) || math.IsInf(number, 0) {
+		return 0, fmt.Errorf("invalid number %q", s)
+	}
+	return int64(math.Round(number * multiplier)), nil
+}
+
+// formatBitrate renders bits per second the way ffmpeg accepts them.
+func formatBitrate(bits int64) string {
+	if bits%1000 == 0 {
+		return strconv.FormatInt(bits/1000, 10) + "k"
+	}
+	return strconv.FormatInt(bits, 10)
+}
+
+// optionParser reads typed values out of an options map, collecting every
+// problem and noting which keys were used.
+type optionParser struct {
+	options map[string]string
+	used    map[string]bool
+	errs    []error
+}
+
+func newOptionParser(options map[string]string) *optionParser {
+	return &optionParser{options: options, used: make(map[string]bool)}
+}
+
+func (p *optionParser) lookup(key string) (string, bool) {
+	p.used[key] = true
+	value, ok := p.options[key]
+	return strings.TrimSpace(value), ok && strings.TrimSpace(value) != ""
+}
+
+func (p *optionParser) fail(key, value, format string, args ...any) {
+	p.errs = append(p.errs, fmt.Errorf("option %s=%q: %s", key, value, fmt.Sprintf(format, args...)))
+}
+
+func (p *optionParser) int(key string, min, max int) int {
+	value, ok := p.lookup(key)
+	if !ok {
+		return 0
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < min || n > max {
+		p.fail(key, value, "must be a whole number between %d and %d", min, max)
+		return 0
+	}
+	return n
+}
+
+func (p *optionParser) quality() int {
+	return p.int("quality", 1, 100)
+}
+
+func (p *optionParser) float(key string, min, max float64) float64 {
+	value, ok := p.lookup(key)
+	if !ok {
+		return 0
+	}
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil || n < min || n > max {
+		p.fail(key, value, "must be a number between %g and %g", min, max)
+		return 0
+	}
+	return n
+}
+
+func (p *optionParser) bitrate(key string) int64 {
+	value, ok := p.lookup(key)
+	if !ok {
+		return 0
+	}
+	bits, err := ParseBitrate(value)
+	if err != nil {
+		p.fail(key, value, "expected a bit rate such as 128k or 2M")
+		return 0
+	}
+	return bits
+}
+
+func (p *optionParser) size(key string) int64 {
+	value, ok := p.lookup(key)
+	if !ok {
+		return 0
+	}
+	size, err := ParseSize(value)
+	if err != nil {
+		p.fail(key, value, "expected a size such as 25MB or 8MiB")
+		return 0
+	}
+	return size
+}
+
+// timestamp parses a position as seconds, "MM:SS" or "HH:MM:SS", with
+// optional fractional seconds.
+func (p *optionParser) timestamp(key string) float64 {
+	value, ok := p.lookup(key)
+	if !ok {
+		return 0
+	}
+	seconds, err := ParseTimestamp(value)
+	if err != nil {
+		p.fail(key, value, "expected seconds or HH:MM:SS")
+		return 0
+	}
+	return seconds
+}
+
+// rate parses a frequency such as "44100" or "48k".
+func (p *optionParser) rate(key string, min, max int64) int64 {
+	value, ok := p.lookup(key)
+	if !ok {
+		return 0
+	}
+	n, err := parseUnits(value, 1000)
+	if err != nil || n < min || n > max {
+		p.fail(key, value, "must be between %d and %d Hz", min, max)
+		return 0
+	}
+	return n
+}
+
+// loudness parses the loudness target and its limits. The defaults are
+// filled in here rather than in withDefaults because 0 dBTP is a valid
+// true peak.
+func (p *optionParser) loudness() LoudnessOptions {
+	_, targetSet := p.lookup("loudness")
+	_, peakSet := p.lookup("true_peak")
+	_, rangeSet := p.lookup("lra")
+	o := LoudnessOptions{
+		Target:   p.float("loudness", -70, -5),
+		TruePeak: p.float("true_peak", -9, 0),
+		Range:    p.float("lra", 1, 50),
+	}
+	if o.Target == 0 {
+		if !targetSet && (peakSet || rangeSet) {
+			p.errs = append(p.errs, errors.New("true_peak and lra need a loudness target"))
+		}
+		return LoudnessOptions{}
+	}
+	if !peakSet {
+		o.TruePeak = -1.5
+	}
+	if o.Range == 0 {
+		o.Range = 11
+	}
+	return o
+}
+
+func (p *optionParser) string(key string) string {
+	value, _ := p.lookup(key)
+	return value
+}
+
+func (p *optionParser) bool(key string) bool {
+	value, ok := p.lookup(key)
+	if !ok {
+		return false
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		p.fail(key, value, "must be true or false")
+	}
+	return b
+}
+
+func (p *optionParser) oneOf(key string, allowed ...string) string {
+	value, ok := p.lookup(key)
+	if !ok {
+		return ""
+	}
+	for _, a := range allowed {
+		if strings.EqualFold(value, a) {
+			return a
+		}
+	}
+	p.fail(key, value, "must be one of %s", strings.Join(allowed, ", "))
+	return ""
+}
+
+// err reports the collected problems, plus any keys that were never read.
+func (p *optionParser) err() error {
+	var unknown []string
+	for key := range p.options {
+		if !p.used[key] {
+			unknown = append(unknown, key)
+		}
+	}
+	sort.Strings(unknown)
+
+	errs := p.errs
+	if len(unknown) > 0 {
+		known := make([]string, 0, len(p.used))
+		for key := range p.used {
+			known = append(known, key)
+		}
+		sort.Strings(known)
+		errs = append(errs, fmt.Errorf("unknown option(s) %s; expected %s",
+			strings.Join(unknown, ", "), strings.Join(known, ", ")))
+	}
+	return errors.Join(errs...)
+}
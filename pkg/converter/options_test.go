@@ -0,0 +1,161 @@
+package converter
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateOptions(t *testing.T) {
+	tests := []struct {
+		ext     string
+		options map[string]string
+		wantErr string // substring; empty means no error
+	}{
+		{".pdf", map[string]string{"quality": "80"}, ""},
+		{".docx", map[string]string{"quality": "80", "toc": "true"}, ""},
+		{".pdf", map[string]string{"quality": "0"}, "quality"},
+		{".pdf", map[string]string{"bitrate": "1M"}, "unknown option"},
+		{".mp4", map[string]string{"quality": "80", "width": "1280"}, ""},
+		{".mp4", map[string]string{"video_codec": "vp9"}, "vp9"},
+		{".mp4", map[string]string{"target_size": "8MB", "video_codec": "hevc"}, ""},
+		{".mkv", map[string]string{"target_size": "8MB", "video_codec": "av1"}, "two-pass"},
+		{".ts", map[string]string{"pix_fmt": "yuv420p"}, ""},
+		{".ts", map[string]string{"profile": "high"}, "video_codec for .ts"},
+		{".mp3", map[string]string{"bitrate": "192k", "sample_rate": "44.1k"}, ""},
+		{".mp3", map[string]string{"fps": "30"}, "unknown option"},
+		{".png", map[string]string{"quality": "80", "columns": "3"}, ""},
+	}
+	for _, tt := range tests {
+		err := ValidateOptions(tt.ext, tt.options)
+		switch {
+		case tt.wantErr == "" && err != nil:
+			t.Errorf("ValidateOptions(%s, %v) = %v, want nil", tt.ext, tt.options, err)
+		case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+			t.Errorf("ValidateOptions(%s, %v) = %v, want error containing %q", tt.ext, tt.options, err, tt.wantErr)
+		}
+	}
+}
+
+func TestParseBitrate(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    int64
+		wantErr bool
+	}{
+		{"800000", 800000, false},
+		{"128k", 128000, false},
+		{"128K", 128000, false},
+		{"1.5M", 1500000, false},
+		{"2G", 2000000000, false},
+		{"192kbps", 192000, false},
+		{" 96k ", 96000, false},
+		{"0", 0, true},
+		{"-128k", 0, true},
+		{"fast", 0, true},
+		{"", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseBitrate(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseBitrate(%q) = %d, %v; want %d, error %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    int64
+		wantErr bool
+	}{
+		{"1048576", 1048576, false},
+		{"500k", 500000, false},
+		{"25MB", 25000000, false},
+		{"8MiB", 8 << 20, false},
+		{"1.5GiB", 3 << 29, false},
+		{"2KiB", 2048, false},
+		{"0MB", 0, true},
+		{"MB", 0, true},
+		{"8XB", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseSize(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseSize(%q) = %d, %v; want %d, error %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestParseTimestamp(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    float64
+		wantErr bool
+	}{
+		{"90", 90, false},
+		{"2.5", 2.5, false},
+		{"1:30", 90, false},
+		{"00:01:30", 90, false},
+		{"00:01:30.5", 90.5, false},
+		{"1:00:00", 3600, false},
+		{"1:60", 0, true},
+		{"1:2:3:4", 0, true},
+		{"-5", 0, true},
+		{"1:-5", 0, true},
+		{"NaN", 0, true},
+		{"", 0, true},
+		{"1:", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseTimestamp(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseTimestamp(%q) = %g, %v; want %g, error %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestQualityScaleNative(t *testing.T) {
+	tests := []struct {
+		encoder string
+		quality int
+		want    string
+	}{
+		{"libx264", 1, "40"},
+		{"libx264", 100, "17"},
+		{"libx264", 0, "40"},
+		{"libx264", 150, "17"},
+		{"libx265", 50, "31"},
+		{"libmp3lame", 100, "0"},
+		{"libvorbis", 100, "10"},
+		{"aac", 1, "64k"},
+		{"aac", 50, "159k"},
+		{"aac", 100, "256k"},
+		{"libopus", 100, "192k"},
+	}
+	for _, tt := range tests {
+		if got := qualityScales[tt.encoder].Native(tt.quality); got != tt.want {
+			t.Errorf("%s Native(%d) = %q, want %q", tt.encoder, tt.quality, got, tt.want)
+		}
+	}
+}
+
+func TestUnknownOptions(t *testing.T) {
+	parsers := map[string]func(map[string]string) error{
+		"video":    func(o map[string]string) error { _, err := ParseVideoOptions(o); return err },
+		"audio":    func(o map[string]string) error { _, err := ParseAudioOptions(o); return err },
+		"image":    func(o map[string]string) error { _, err := ParseImageOptions(o); return err },
+		"document": func(o map[string]string) error { _, err := ParseDocumentOptions(o); return err },
+	}
+	for name, parse := range parsers {
+		if err := parse(map[string]string{"quality": "80"}); err != nil {
+			t.Errorf("%s: quality alone = %v, want nil", name, err)
+		}
+		err := parse(map[string]string{"quality": "80", "qualty": "80", "colour": "red"})
+		if err == nil || !strings.Contains(err.Error(), "unknown option(s) colour, qualty;") {
+			t.Errorf("%s: misspelt options = %v, want both reported as unknown", name, err)
+		}
+		if err != nil && !strings.Contains(err.Error(), "expected") {
+			t.Errorf("%s: error %q does not list the known options", name, err)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package converter
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+
+	"goverter/pkg/formats"
+)
+
+// pandocBackend handles document inputs.
+type pandocBackend struct {
+	path string
+}
+
+func newPandocBackend() *pandocBackend {
+	path, _ := exec.LookPath("pandoc")
+	return &pandocBackend{path: path}
+}
+
+func (b *pandocBackend) Name() string {
+	return "pandoc"
+}
+
+func (b *pandocBackend) Available() bool {
+	return b.path != ""
+}
+
+func (b *pandocBackend) CanConvert(inExt, outExt string) bool {
+	return categoryOf(inExt) == formats.CategoryDocument && isOutputFormat(formats.CategoryDocument, outExt)
+}
+
+func (b *pandocBackend) Convert(ctx context.Context, req ConversionRequest) error {
+	if b.path == "" {
+		return fmt.Errorf("pandoc not found. Please install pandoc for document conversions")
+	}
+
+	opts, err := ParseDocumentOptions(req.Options)
+	if err != nil {
+		return err
+	}
+	opts = opts.withDefaults()
+
+	args := []string{req.InputPath, "-o", req.OutputPath}
+	if opts.TOC {
+		args = append(args, "--toc", "--standalone")
+	}
+
+	// Add PDF-specific options
+	if filepath.Ext(req.OutputPath) == ".pdf" {
+		args = append(args, "--pdf-engine="+opts.PDFEngine)
+	}
+
+	return runTool(ctx, "pandoc", b.path, args, nil)
+}
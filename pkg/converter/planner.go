@@ -0,0 +1,313 @@
+package converter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"goverter/pkg/formats"
+)
+
+// CostEstimator can be implemented by a Backend to weight its conversions in
+// the planner. Backends without it cost 1 per conversion, so the planner
+// prefers the fewest hops.
+type CostEstimator interface {
+	Cost(inExt, outExt string) int
+}
+
+// PlanStep is one conversion in a Plan.
+type PlanStep struct {
+	Backend Backend
+	From    string
+	To      string
+}
+
+// Plan is the cheapest chain of conversions from one format to another.
+type Plan struct {
+	Steps []PlanStep
+	Cost  int
+}
+
+func (p *Plan) String() string {
+	if len(p.Steps) == 0 {
+		return "(empty plan)"
+	}
+
+	var b strings.Builder
+	b.WriteString(p.Steps[0].From)
+	for _, step := range p.Steps {
+		fmt.Fprintf(&b, " -[%s]-> %s", step.Backend.Name(), step.To)
+	}
+	return b.String()
+}
+
+// ValidateOptions checks options for every step of the plan. Each step is
+// given the options its output category understands, so a video to PDF
+// plan through an image accepts columns and rows for the contact sheet;
+// an option no step understands is an error.
+func (p *Plan) ValidateOptions(options map[string]string) error {
+	last := p.Steps[len(p.Steps)-1].To
+	if len(p.Steps) == 1 {
+		return ValidateOptions(last, options)
+	}
+
+	var errs []error
+	known := make(map[string]bool)
+	for _, step := range p.Steps {
+		for key := range optionKeys(categoryOf(step.To)) {
+			known[key] = true
+		}
+		if err := ValidateOptions(step.To, stepOptions(options, step.To)); err != nil {
+			errs = append(errs, fmt.Errorf("step %s -> %s: %w", step.From, step.To, err))
+		}
+	}
+
+	var unknown []string
+	for key := range options {
+		if !known[key] {
+			unknown = append(unknown, key)
+		}
+	}
+	if len(unknown) > 0 {
+		expected := make([]string, 0, len(known))
+		for key := range known {
+			expected = append(expected, key)
+		}
+		sort.Strings(unknown)
+		sort.Strings(expected)
+		errs = append(errs, fmt.Errorf("unknown option(s) %s; expected %s",
+			strings.Join(unknown, ", "), strings.Join(expected, ", ")))
+	}
+	return errors.Join(errs...)
+}
+
+// stepOptions returns the options that a step writing ext understands.
+func stepOptions(options map[string]string, ext string) map[string]string {
+	known := optionKeys(categoryOf(ext))
+	subset := make(map[string]string, len(options))
+	for key, value := range options {
+		if known[key] {
+			subset[key] = value
+		}
+	}
+	return subset
+}
+
+// Plan finds the cheapest way to convert inExt to outExt, chaining backends
+// through intermediate formats when no single backend can do it. Available
+// backends are preferred; if they cannot reach outExt the plan may include
+// unavailable ones, whose Convert then reports the missing tool.
+func (c *Converter) Plan(inExt, outExt string) (*Plan, error) {
+	inExt = formats.NormalizeExt(inExt)
+	outExt = formats.NormalizeExt(outExt)
+
+	if plan := c.shortestPath(inExt, outExt, true); plan != nil {
+		return plan, nil
+	}
+	if plan := c.shortestPath(inExt, outExt, false); plan != nil {
+		return plan, nil
+	}
+	return nil, fmt.Errorf("unsupported conversion: %s to %s", inExt, outExt)
+}
+
+// ReachableFormats returns every extension inExt can be converted to, in one
+// or more steps.
+func (c *Converter) ReachableFormats(inExt string) []string {
+	inExt = formats.NormalizeExt(inExt)
+	edges := c.edges(false)
+
+	seen := map[string]bool{canonicalExt(inExt): true}
+	queue := []string{canonicalExt(inExt)}
+	var reachable []string
+
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+		for _, edge := range edges[node] {
+			if seen[edge.to] {
+				continue
+			}
+			seen[edge.to] = true
+			queue = append(queue, edge.to)
+			if format, ok := formats.Default().Lookup(edge.to); ok {
+				reachable = append(reachable, format.Extensions()...)
+			}
+		}
+	}
+	return reachable
+}
+
+type planEdge struct {
+	to      string
+	backend Backend
+	cost    int
+}
+
+// edges builds the conversion graph over the canonical extensions of the
+// format registry.
+func (c *Converter) edges(availableOnly bool) map[string][]planEdge {
+	all := formats.Default().Formats()
+	edges := make(map[string][]planEdge)
+
+	for _, backend := range c.registry.Backends() {
+		if availableOnly && !backend.Available() {
+			continue
+		}
+		for _, in := range all {
+			if !in.Read {
+				continue
+			}
+			for _, out := range all {
+				if !out.Write || in.Ext == out.Ext || !backend.CanConvert(in.Ext, out.Ext) {
+					continue
+				}
+				edges[in.Ext] = append(edges[in.Ext], planEdge{
+					to:      out.Ext,
+					backend: backend,
+					cost:    backendCost(backend, in.Ext, out.Ext),
+				})
+			}
+		}
+	}
+	return edges
+}
+
+// shortestPath runs Dijkstra's algorithm from inExt to outExt. A direct
+// conversion by a single backend is always tried first so that aliases and
+// same-format conversions (e.g. re-encoding .mp4 to .mp4) keep working.
+func (c *Converter) shortestPath(inExt, outExt string, availableOnly bool) *Plan {
+	for _, backend := range c.registry.Backends() {
+		if availableOnly && !backend.Available() {
+			continue
+		}
+		if backend.CanConvert(inExt, outExt) {
+			return &Plan{
+				Steps: []PlanStep{{Backend: backend, From: inExt, To: outExt}},
+				Cost:  backendCost(backend, inExt, outExt),
+			}
+		}
+	}
+
+	start, target := canonicalExt(inExt), canonicalExt(outExt)
+	if start == target {
+		return nil
+	}
+	edges := c.edges(availableOnly)
+
+	type hop struct {
+		from    string
+		backend Backend
+	}
+	dist := map[string]int{start: 0}
+	prev := make(map[string]hop)
+	done := make(map[string]bool)
+
+	for {
+		// Pick the closest unfinished node; the graph is small enough that
+		// a linear scan beats maintaining a heap.
+		node, best := "", -1
+		for candidate, d := range dist {
+			if done[candidate] {
+				continue
+			}
+			if best < 0 || d < best || (d == best && candidate < node) {
+				node, best = candidate, d
+			}
+		}
+		if best < 0 {
+			return nil
+		}
+		if node == target {
+			break
+		}
+		done[node] = true
+
+		for _, edge := range edges[node] {
+			next := dist[node] + edge.cost
+			if d, ok := dist[edge.to]; !ok || next < d {
+				dist[edge.to] = next
+				prev[edge.to] = hop{from: node, backend: edge.backend}
+			}
+		}
+	}
+
+	var steps []PlanStep
+	for node := target; node != start; node = prev[node].from {
+		steps = append([]PlanStep{{Backend: prev[node].backend, From: prev[node].from, To: node}}, steps...)
+	}
+	// Keep the caller's spelling of the extensions at both ends.
+	steps[0].From = inExt
+	steps[len(steps)-1].To = outExt
+
+	return &Plan{Steps: steps, Cost: dist[target]}
+}
+
+// runPlan executes a multi-step plan. Intermediate files live in a temporary
+// directory that is removed afterwards; the final step writes
+// req.OutputPath. Each step gets the request's options for its output
+// category (see Plan.ValidateOptions).
+func (c *Converter) runPlan(ctx context.Context, plan *Plan, req ConversionRequest) error {
+	tmpDir, err := os.MkdirTemp("", "goverter-plan-")
+	if err != nil {
+		return fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	input := req.InputPath
+	for i, step := range plan.Steps {
+		stepReq := ConversionRequest{InputPath: input, Options: stepOptions(req.Options, step.To)}
+
+		last := i == len(plan.Steps)-1
+		if last {
+			stepReq.OutputPath = req.OutputPath
+		} else {
+			stepReq.OutputPath = filepath.Join(tmpDir, fmt.Sprintf("step%d%s", i+1, step.To))
+		}
+
+		err := runStep(ctx, step.Backend, stepReq, req.Progress, i, len(plan.Steps))
+		if err != nil {
+			return fmt.Errorf("step %d (%s %s -> %s): %w", i+1, step.Backend.Name(), step.From, step.To, err)
+		}
+		input = stepReq.OutputPath
+	}
+
+	return nil
+}
+
+// runStep runs one plan step, scaling its progress into the step's share of
+// the overall progress.
+func runStep(ctx context.Context, backend Backend, req ConversionRequest, progress chan float64, index, total int) error {
+	if progress == nil {
+		return backend.Convert(ctx, req)
+	}
+
+	var done func()
+	req.Progress, done = scaleProgress(progress, float64(index)/float64(total), 1/float64(total))
+	err := backend.Convert(ctx, req)
+	done()
+
+	if err == nil {
+		sendProgress(progress, float64(index+1)/float64(total))
+	}
+	return err
+}
+
+func backendCost(backend Backend, inExt, outExt string) int {
+	if estimator, ok := backend.(CostEstimator); ok {
+		if cost := estimator.Cost(inExt, outExt); cost > 0 {
+			return cost
+		}
+	}
+	return 1
+}
+
+func canonicalExt(ext string) string {
+	if format, ok := formats.Default().Lookup(ext); ok {
+		return format.Ext
+	}
+	return ext
+}
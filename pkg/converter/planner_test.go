@@ -0,0 +1,243 @@
+package converter
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeBackend converts between the extension pairs in costs by writing its
+// name into the output file.
+type fakeBackend struct {
+	name        string
+	unavailable bool
+	costs       map[[2]string]int // {in, out} -> cost; 0 means the default
+
+	mu    sync.Mutex
+	calls []ConversionRequest
+}
+
+func (b *fakeBackend) Name() string    { return b.name }
+func (b *fakeBackend) Available() bool { return !b.unavailable }
+
+func (b *fakeBackend) CanConvert(inExt, outExt string) bool {
+	_, ok := b.costs[[2]string{inExt, outExt}]
+	return ok
+}
+
+func (b *fakeBackend) Cost(inExt, outExt string) int {
+	return b.costs[[2]string{inExt, outExt}]
+}
+
+func (b *fakeBackend) Convert(ctx context.Context, req ConversionRequest) error {
+	b.mu.Lock()
+	b.calls = append(b.calls, req)
+	b.mu.Unlock()
+	return os.WriteFile(req.OutputPath, []byte(b.name), 0644)
+}
+
+func TestPlan(t *testing.T) {
+	tests := []struct {
+		name     string
+		backends []*fakeBackend
+		in, out  string
+		want     string // Plan.String(), or "" for no plan
+		wantCost int
+	}{
+		{
+			name: "direct",
+			backends: []*fakeBackend{
+				{name: "a", costs: map[[2]string]int{{".mp4", ".gif"}: 3}},
+			},
+			in: ".mp4", out: ".gif",
+			want: ".mp4 -[a]-> .gif", wantCost: 3,
+		},
+		{
+			name: "direct wins over a cheaper chain",
+			backends: []*fakeBackend{
+				{name: "a", costs: map[[2]string]int{{".mp4", ".pdf"}: 10, {".mp4", ".png"}: 1}},
+				{name: "b", costs: map[[2]string]int{{".png", ".pdf"}: 1}},
+			},
+			in: ".mp4", out: ".pdf",
+			want: ".mp4 -[a]-> .pdf", wantCost: 10,
+		},
+		{
+			name: "chain across backends",
+			backends: []*fakeBackend{
+				{name: "ffmpeg", costs: map[[2]string]int{{".mp4", ".gif"}: 0}},
+				{name: "magick", costs: map[[2]string]int{{".gif", ".pdf"}: 0}},
+			},
+			in: ".mp4", out: ".pdf",
+			want: ".mp4 -[ffmpeg]-> .gif -[magick]-> .pdf", wantCost: 2,
+		},
+		{
+			name: "cheapest chain",
+			backends: []*fakeBackend{
+				{name: "a", costs: map[[2]string]int{{".mkv", ".mp4"}: 1, {".mkv", ".gif"}: 5}},
+				{name: "b", costs: map[[2]string]int{{".mp4", ".png"}: 1, {".gif", ".png"}: 1}},
+			},
+			in: ".mkv", out: ".png",
+			want: ".mkv -[a]-> .mp4 -[b]-> .png", wantCost: 2,
+		},
+		{
+			name: "fewer hops beat a longer chain of equal cost per hop",
+			backends: []*fakeBackend{
+				{name: "a", costs: map[[2]string]int{{".mkv", ".mp4"}: 0, {".mp4", ".gif"}: 0, {".mkv", ".gif"}: 0}},
+				{name: "b", costs: map[[2]string]int{{".gif", ".pdf"}: 0}},
+			},
+			in: ".mkv", out: ".pdf",
+			want: ".mkv -[a]-> .gif -[b]-> .pdf", wantCost: 2,
+		},
+		{
+			name: "cost ties go to the alphabetically first intermediate",
+			backends: []*fakeBackend{
+				{name: "a", costs: map[[2]string]int{{".mkv", ".mp4"}: 1, {".mkv", ".gif"}: 1}},
+				{name: "b", costs: map[[2]string]int{{".mp4", ".png"}: 1, {".gif", ".png"}: 1}},
+			},
+			in: ".mkv", out: ".png",
+			want: ".mkv -[a]-> .gif -[b]-> .png", wantCost: 2,
+		},
+		{
+			name: "available chain beats an unavailable direct backend",
+			backends: []*fakeBackend{
+				{name: "a", costs: map[[2]string]int{{".mp4", ".png"}: 0}},
+				{name: "b", costs: map[[2]string]int{{".png", ".pdf"}: 0}},
+				{name: "missing", unavailable: true, costs: map[[2]string]int{{".mp4", ".pdf"}: 0}},
+			},
+			in: ".mp4", out: ".pdf",
+			want: ".mp4 -[a]-> .png -[b]-> .pdf", wantCost: 2,
+		},
+		{
+			name: "unavailable backend when nothing else reaches",
+			backends: []*fakeBackend{
+				{name: "a", costs: map[[2]string]int{{".mp4", ".png"}: 0}},
+				{name: "missing", unavailable: true, costs: map[[2]string]int{{".png", ".pdf"}: 0}},
+			},
+			in: ".mp4", out: ".pdf",
+			want: ".mp4 -[a]-> .png -[missing]-> .pdf", wantCost: 2,
+		},
+		{
+			name: "aliases keep the caller's spelling",
+			backends: []*fakeBackend{
+				{name: "a", costs: map[[2]string]int{{".jpg", ".png"}: 0}},
+				{name: "b", costs: map[[2]string]int{{".png", ".tiff"}: 0}},
+			},
+			in: ".JPEG", out: ".tif",
+			want: ".jpeg -[a]-> .png -[b]-> .tif", wantCost: 2,
+		},
+		{
+			name: "unreachable",
+			backends: []*fakeBackend{
+				{name: "a", costs: map[[2]string]int{{".mp4", ".png"}: 0}},
+			},
+			in: ".png", out: ".mp4",
+		},
+	}
+	for _, tt := range tests {
+		registry := NewRegistry()
+		for _, backend := range tt.backends {
+			registry.Register(backend)
+		}
+		plan, err := NewConverterWithRegistry(registry).Plan(tt.in, tt.out)
+		if tt.want == "" {
+			if err == nil {
+				t.Errorf("%s: Plan = %s, want an error", tt.name, plan)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: Plan failed: %v", tt.name, err)
+			continue
+		}
+		if got := plan.String(); got != tt.want || plan.Cost != tt.wantCost {
+			t.Errorf("%s: Plan = %s (cost %d), want %s (cost %d)", tt.name, got, plan.Cost, tt.want, tt.wantCost)
+		}
+	}
+}
+
+func TestRunPlan(t *testing.T) {
+	first := &fakeBackend{name: "first", costs: map[[2]string]int{{".mp4", ".png"}: 0}}
+	second := &fakeBackend{name: "second", costs: map[[2]string]int{{".png", ".pdf"}: 0}}
+	c := NewConverterWithRegistry(NewRegistry(first, second))
+
+	dir := t.TempDir()
+	output := dir + "/out.pdf"
+	err := c.Convert(ConversionRequest{InputPath: dir + "/in.mp4", OutputPath: output})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if data, err := os.ReadFile(output); err != nil || string(data) != "second" {
+		t.Errorf("output = %q, %v; want it written by the second step", data, err)
+	}
+	if len(first.calls) != 1 || len(second.calls) != 1 {
+		t.Fatalf("calls = %d, %d; want one per step", len(first.calls), len(second.calls))
+	}
+	if intermediate := first.calls[0].OutputPath; second.calls[0].InputPath != intermediate {
+		t.Errorf("second step read %s, want the first step's output %s", second.calls[0].InputPath, intermediate)
+	}
+	if _, err := os.Stat(first.calls[0].OutputPath); !os.IsNotExist(err) {
+		t.Errorf("intermediate %s was left behind", first.calls[0].OutputPath)
+	}
+}
+
+func TestPlanValidateOptions(t *testing.T) {
+	sheet := &fakeBackend{name: "sheet", costs: map[[2]string]int{{".mkv", ".png"}: 0}}
+	pdf := &fakeBackend{name: "pdf", costs: map[[2]string]int{{".png", ".pdf"}: 0}}
+	c := NewConverterWithRegistry(NewRegistry(sheet, pdf))
+	twoStep, err := c.Plan(".mkv", ".pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	oneStep, err := c.Plan(".png", ".pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		plan    *Plan
+		options map[string]string
+		wantErr string
+	}{
+		{twoStep, map[string]string{"columns": "3", "rows": "2", "quality": "80"}, ""},
+		{twoStep, map[string]string{"columns": "3", "toc": "true"}, ""},
+		{twoStep, map[string]string{"columns": "3", "bitrate": "1M"}, "unknown option(s) bitrate;"},
+		{twoStep, map[string]string{"columns": "99"}, "step .mkv -> .png"},
+		{oneStep, map[string]string{"columns": "3"}, "unknown option(s) columns;"},
+	}
+	for _, tt := range tests {
+		err := tt.plan.ValidateOptions(tt.options)
+		switch {
+		case tt.wantErr == "" && err != nil:
+			t.Errorf("%s with %v: %v, want nil", tt.plan, tt.options, err)
+		case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+			t.Errorf("%s with %v: %v, want error containing %q", tt.plan, tt.options, err, tt.wantErr)
+		}
+	}
+}
+
+func TestRunPlanStepOptions(t *testing.T) {
+	sheet := &fakeBackend{name: "sheet", costs: map[[2]string]int{{".mkv", ".png"}: 0}}
+	pdf := &fakeBackend{name: "pdf", costs: map[[2]string]int{{".png", ".pdf"}: 0}}
+	c := NewConverterWithRegistry(NewRegistry(sheet, pdf))
+
+	dir := t.TempDir()
+	err := c.Convert(ConversionRequest{
+		InputPath:  dir + "/talk.mkv",
+		OutputPath: dir + "/talk.pdf",
+		Options:    map[string]string{"columns": "3", "rows": "2", "toc": "true"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := map[string]string{"columns": "3", "rows": "2"}; !reflect.DeepEqual(sheet.calls[0].Options, want) {
+		t.Errorf("image step got %v, want %v", sheet.calls[0].Options, want)
+	}
+	if want := map[string]string{"toc": "true"}; !reflect.DeepEqual(pdf.calls[0].Options, want) {
+		t.Errorf("document step got %v, want %v", pdf.calls[0].Options, want)
+	}
+}
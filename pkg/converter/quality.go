@@ -0,0 +1,91 @@
+package converter
+
+import (
+	"math"
+	"strconv"
+)
+
+// QualityScale maps the generic 1-100 quality onto an encoder's own
+// setting. Worst is the native value for quality 1 and Best for quality
+// 100; either may be the larger number.
+type QualityScale struct {
+	Flag  string
+	Worst float64
+	Best  float64
+	// Bitrate scales are in bits per second and are passed with a k suffix.
+	Bitrate bool
+}
+
+// qualityScales covers the encoders goverter picks by default. The ranges
+// stop short of the extremes: quality 100 is visually lossless rather than
+// mathematically lossless, and quality 1 is still watchable.
+var qualityScales = map[string]QualityScale{
+	"libx264":    {Flag: "-crf", Worst: 40, Best: 17},
+	"libx265":    {Flag: "-crf", Worst: 42, Best: 19},
+	"libvpx-vp9": {Flag: "-crf", Worst: 50, Best: 15},
+	"libvpx":     {Flag: "-crf", Worst: 50, Best: 10},
+	"libsvtav1":  {Flag: "-crf", Worst: 55, Best: 20},
+	"mpeg4":      {Flag: "-q:v", Worst: 31, Best: 2},
+	"flv":        {Flag: "-q:v", Worst: 31, Best: 2},
+	"wmv2":       {Flag: "-q:v", Worst: 31, Best: 2},
+	"mjpeg":      {Flag: "-q:v", Worst: 31, Best: 2},
+	"libwebp":    {Flag: "-q:v", Worst: 1, Best: 100},
+	"libmp3lame": {Flag: "-q:a", Worst: 9, Best: 0},
+	"libvorbis":  {Flag: "-q:a", Worst: 0, Best: 10},
+	"aac":        {Flag: "-b:a", Worst: 64000, Best: 256000, Bitrate: true},
+	"libopus":    {Flag: "-b:a", Worst: 32000, Best: 192000, Bitrate: true},
+}
+
+// Native returns the encoder value for a 1-100 quality.
+func (s QualityScale) Native(quality int) string {
+	q := math.Max(1, math.Min(100, float64(quality)))
+	value := s.Worst + (s.Best-s.Worst)*(q-1)/99
+	if s.Bitrate {
+		return formatBitrate(int64(math.Round(value/1000)) * 1000)
+	}
+	return strconv.Itoa(int(math.Round(value)))
+}
+
+// QualityArgs returns the arguments that set quality on encoder, or nil if
+// the encoder has no quality setting (lossless codecs, for example).
+func QualityArgs(encoder string, quality int) []string {
+	scale, ok := qualityScales[encoder]
+	if !ok || quality == 0 {
+		return nil
+	}
+	args := []string{scale.Flag, scale.Native(quality)}
+	if encoder == "libvpx-vp9" || encoder == "libvpx" {
+		// Without a zero target bit rate libvpx treats crf as a cap
+		args = append(args, "-b:v", "0")
+	}
+	return args
+}
+
+// defaultEncoders is the encoder ffmpeg uses for each output container.
+var defaultEncoders = map[string]string{
+	".mp4":  "libx264",
+	".mov":  "libx264",
+	".mkv":  "libx264",
+	".m4v":  "libx264",
+	".webm": "libvpx-vp9",
+	".avi":  "mpeg4",
+	".flv":  "flv",
+	".wmv":  "wmv2",
+	".mp3":  "libmp3lame",
+	".ogg":  "libvorbis",
+	".aac":  "aac",
+	".m4a":  "aac",
+	".opus": "libopus",
+	".flac": "flac",
+	".wav":  "pcm_s16le",
+	".jpg":  "mjpeg",
+	".png":  "png",
+	".gif":  "gif",
+	".webp": "libwebp",
+	".apng": "apng",
+}
+
+// defaultEncoder returns the encoder ffmpeg picks for outputExt, or "".
+func defaultEncoder(outputExt string) string {
+	return defaultEncoders[canonicalExt(outputExt)]
+}
@@ -0,0 +1,133 @@
+package converter
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"goverter/pkg/formats"
+	"goverter/pkg/utils"
+)
+
+// DetectSilence lists the silent stretches of a video or audio file's
+// audio in order. A silence that lasts until the end has an End of 0.
+func (c *Converter) DetectSilence(ctx context.Context, path string, opts SilenceOptions, progress chan float64) ([]Segment, error) {
+	b := c.ffmpegBackend()
+	if b == nil || !b.Available() {
+		return nil, fmt.Errorf("ffmpeg not found. Please install FFmpeg to detect silence")
+	}
+	switch categoryOf(filepath.Ext(path)) {
+	case formats.CategoryVideo, formats.CategoryAudio:
+	default:
+		return nil, fmt.Errorf("cannot detect silence in %s files", filepath.Ext(path))
+	}
+	return b.detectSilence(ctx, path, opts, progress)
+}
+
+func (b *ffmpegBackend) detectSilence(ctx context.Context, input string, opts SilenceOptions, progress chan float64) ([]Segment, error) {
+	opts = opts.withDefaults()
+	filter := fmt.Sprintf("silencedetect=noise=%gdB:d=%g", opts.Threshold, opts.MinLength)
+	args := []string{"-hide_banner", "-i", input, "-vn", "-sn", "-dn", "-af", filter, "-f", "null", os.DevNull}
+
+	// A long recording can have more silences than the error tail keeps,
+	// so they are parsed as ffmpeg prints them.
+	pr, pw := io.Pipe()
+	parsed := make(chan []Segment, 1)
+	go func() {
+		parsed <- parseSilences(pr)
+	}()
+	err := b.runFFmpegCapture(ctx, progress, b.probeDuration(ctx, input), args, pw)
+	pw.Close()
+	silences := <-parsed
+	if err != nil {
+		return nil, err
+	}
+	return silences, nil
+}
+
+// parseSilences reads silencedetect's "silence_start: 1.5" and
+// "silence_end: 3.2 | silence_duration: 1.7" log lines.
+func parseSilences(r io.Reader) []Segment {
+	var silences []Segment
+	open := false
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.Contains(line, "silencedetect") {
+			continue
+		}
+		if value, ok := logValue(line, "silence_start:"); ok {
+			silences = append(silences, Segment{Start: value})
+			open = true
+		} else if value, ok := logValue(line, "silence_end:"); ok && open {
+			silences[len(silences)-1].End = value
+			open = false
+		}
+	}
+	// Drain the rest so ffmpeg never blocks on a full pipe
+	io.Copy(io.Discard, r)
+	return silences
+}
+
+func logValue(line, key string) (float64, bool) {
+	_, rest, ok := strings.Cut(line, key)
+	if !ok {
+		return 0, false
+	}
+	fields := strings.Fields(rest)
+	if len(fields) == 0 {
+		return 0, false
+	}
+	value, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return 0, false
+	}
+	if value < 0 {
+		value = 0
+	}
+	return value, true
+}
+
+// soundBounds returns the part of a recording between its leading and
+// trailing silence. End is 0 when there is no trailing silence.
+func soundBounds(silences []Segment, duration float64) (Segment, error) {
+	// Silences within this distance of either end count as touching it
+	const edge = 0.05
+
+	var bounds Segment
+	if len(silences) > 0 && silences[0].Start <= edge {
+		if silences[0].End == 0 {
+			return Segment{}, fmt.Errorf("the audio is silent throughout")
+		}
+		bounds.Start = silences[0].End
+	}
+	if n := len(silences); n > 0 {
+		last := silences[n-1]
+		if last.Start > bounds.Start && (last.End == 0 || (duration > 0 && last.End >= duration-edge)) {
+			bounds.End = last.Start
+		}
+	}
+	return bounds, nil
+}
+
+// stripSilenceArgs are the input options that skip the leading and
+// trailing silence of input.
+func (b *ffmpegBackend) stripSilenceArgs(ctx context.Context, input string, opts SilenceOptions) ([]string, error) {
+	silences, err := b.detectSilence(ctx, input, opts, nil)
+	if err != nil {
+		return nil, err
+	}
+	if utils.DryRun(ctx) != nil {
+		return []string{"-ss", placeholder("sound_start"), "-to", placeholder("sound_end")}, nil
+	}
+	bounds, err := soundBounds(silences, b.probeDuration(ctx, input))
+	if err != nil {
+		return nil, fmt.Errorf("cannot strip silence from %s: %w", input, err)
+	}
+	return seekArgs(bounds), nil
+}
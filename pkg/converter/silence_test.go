@@ -0,0 +1,56 @@
+package converter
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// silencedetectLog is ffmpeg's stderr for "-af silencedetect -f null -" on
+// a 30 second recording, trimmed to the interesting lines.
+const silencedetectLog = `Input #0, wav, from 'episode.wav':
+  Metadata:
+    comment         : silence_start: 3 is not a log line
+  Duration: 00:00:30.00, bitrate: 1411 kb/s
+  Stream #0:0: Audio: pcm_s16le ([1][0][0][0] / 0x0001), 44100 Hz, stereo, s16, 1411 kb/s
+[silencedetect @ 0x55d0c8a1b2c0] silence_start: -0.0123
+[silencedetect @ 0x55d0c8a1b2c0] silence_end: 1.2 | silence_duration: 1.2123
+size=N/A time=00:00:05.00 bitrate=N/A speed= 250x
+[silencedetect @ 0x55d0c8a1b2c0] silence_start: 4.00002
+[silencedetect @ 0x55d0c8a1b2c0] silence_end: 5.1 | silence_duration: 1.09998
+[silencedetect @ 0x55d0c8a1b2c0] silence_start: 28.5
+[out#0/null @ 0x55d0c8a2c700] video:0kB audio:5168kB subtitle:0kB other streams:0kB global headers:0kB muxing overhead: unknown
+size=N/A time=00:00:30.00 bitrate=N/A speed= 312x
+`
+
+func TestParseSilences(t *testing.T) {
+	got := parseSilences(strings.NewReader(silencedetectLog))
+	want := []Segment{{0, 1.2}, {4.00002, 5.1}, {28.5, 0}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseSilences = %v, want %v", got, want)
+	}
+}
+
+func TestSoundBounds(t *testing.T) {
+	tests := []struct {
+		name     string
+		silences []Segment
+		duration float64
+		want     Segment
+		wantErr  bool
+	}{
+		{"both ends", []Segment{{0, 1.2}, {4, 5.1}, {28.5, 0}}, 30, Segment{1.2, 28.5}, false},
+		{"no silence", nil, 30, Segment{}, false},
+		{"pause in the middle only", []Segment{{4, 5}}, 30, Segment{}, false},
+		{"trailing silence ending at the end", []Segment{{25, 29.98}}, 30, Segment{0, 25}, false},
+		{"trailing silence, duration unknown", []Segment{{25, 29.98}}, 0, Segment{}, false},
+		{"leading silence only", []Segment{{0.02, 2}}, 30, Segment{2, 0}, false},
+		{"silent throughout", []Segment{{0, 0}}, 30, Segment{}, true},
+	}
+	for _, tt := range tests {
+		got, err := soundBounds(tt.silences, tt.duration)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("%s: soundBounds = %v, %v; want %v, error %v", tt.name, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,335 @@
+package converter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"goverter/pkg/formats"
+)
+
+// SplitRequest cuts a recording into chunks at its silences, or at its
+// chapter markers when ByChapters is set.
+type SplitRequest struct {
+	InputPath string
+	// OutputDir receives the chunks, named after the input with a
+	// sequence number; it defaults to the input's directory.
+	OutputDir string
+	// Format is the chunks' format, such as "mp3"; it defaults to the
+	// input's. Splitting at silences always writes audio.
+	Format  string
+	Silence SilenceOptions
+	// MinChunk and MaxChunk bound chunk lengths in seconds; 0 means no
+	// bound. A chunk is cut short at MaxChunk when no silence comes first.
+	MinChunk float64
+	MaxChunk float64
+	// ByChapters writes one chunk per chapter instead, named by
+	// NameTemplate. Chunks in the input's format are stream copied unless
+	// Options are given.
+	ByChapters   bool
+	NameTemplate string
+	// Options are encoder options for the chunks, as for Convert.
+	Options map[string]string
+	// ManifestPath, if set, receives a JSON list of the chunks.
+	ManifestPath string
+	Progress     chan float64
+}
+
+// Chunk is one file written by Split.
+type Chunk struct {
+	Path  string  `json:"path"`
+	Title string  `json:"title,omitempty"`
+	Start float64 `json:"start"`
+	End   float64 `json:"end"`
+}
+
+// ChapterName is available to SplitRequest.NameTemplate, a text/template
+// for the chunk's file name relative to the output directory.
+type ChapterName struct {
+	Name   string // input file name without extension
+	Title  string // chapter title, made safe for a file name
+	Number int    // 1-based chapter number
+	Ext    string // output extension, with the dot
+}
+
+// DefaultChapterTemplate names chunks "01 - Title.ext".
+const DefaultChapterTemplate = `{{printf "%02d" .Number}} - {{.Title}}{{.Ext}}`
+
+// Manifest is the JSON written to SplitRequest.ManifestPath.
+type Manifest struct {
+	Input  string  `json:"input"`
+	Chunks []Chunk `json:"chunks"`
+}
+
+// Split runs silence detection over the input and writes a chunk for each
+// stretch between silences that satisfies the chunk length limits. Cuts
+// fall in the middle of a silence, so every chunk keeps a little of it.
+func (c *Converter) Split(ctx context.Context, req SplitRequest) ([]Chunk, error) {
+	b := c.ffmpegBackend()
+	if b == nil || !b.Available() {
+		return nil, fmt.Errorf("ffmpeg not found. Please install FFmpeg to split audio")
+	}
+
+	inputExt := strings.ToLower(filepath.Ext(req.InputPath))
+	switch categoryOf(inputExt) {
+	case formats.CategoryVideo, formats.CategoryAudio:
+	default:
+		return nil, fmt.Errorf("cannot split %s files: only video and audio can be split", inputExt)
+	}
+	outputExt := inputExt
+	if req.Format != "" {
+		outputExt = "." + strings.TrimPrefix(strings.ToLower(req.Format), ".")
+	}
+	if req.ByChapters {
+		return b.splitChapters(ctx, req, outputExt)
+	}
+	if !isOutputFormat(formats.CategoryAudio, outputExt) {
+		return nil, fmt.Errorf("split writes audio chunks, and %s is not an audio format", outputExt)
+	}
+	if req.MinChunk < 0 || req.MaxChunk < 0 || (req.MaxChunk > 0 && req.MaxChunk < req.MinChunk) {
+		return nil, errors.New("chunk lengths must be positive, with the maximum above the minimum")
+	}
+
+	opts, err := parseAudio(req.Options, outputExt)
+	if err != nil {
+		return nil, err
+	}
+	if opts.StripSilence || opts.Loudness.Target != 0 {
+		return nil, errors.New("strip_silence and loudness are not supported when splitting")
+	}
+
+	duration := b.probeDuration(ctx, req.InputPath)
+	if duration <= 0 {
+		return nil, fmt.Errorf("split needs the duration of %s, which could not be probed", req.InputPath)
+	}
+
+	detectProgress, done := scaleProgress(req.Progress, 0, 0.5)
+	silences, err := b.detectSilence(ctx, req.InputPath, req.Silence, detectProgress)
+	done()
+	if err != nil {
+		return nil, err
+	}
+	bounds := chunkBounds(silences, duration, req.MinChunk, req.MaxChunk)
+
+	outDir, err := splitDir(req)
+	if err != nil {
+		return nil, err
+	}
+	base := strings.TrimSuffix(filepath.Base(req.InputPath), filepath.Ext(req.InputPath))
+
+	chunks := make([]Chunk, 0, len(bounds))
+	for i, bound := range bounds {
+		path := filepath.Join(outDir, fmt.Sprintf("%s_%03d%s", base, i+1, outputExt))
+		args := append(seekArgs(bound), "-i", req.InputPath, "-vn")
+		args = append(args, audioEncodeArgs(opts, outputExt)...)
+		args = append(args, "-y", path)
+		if err := runTool(ctx, "ffmpeg", b.path, args, nil); err != nil {
+			return chunks, err
+		}
+		chunks = append(chunks, Chunk{Path: path, Start: bound.Start, End: bound.End})
+		sendProgress(req.Progress, 0.5+0.5*float64(i+1)/float64(len(bounds)))
+	}
+
+	if req.ManifestPath != "" {
+		if err := writeManifest(req.ManifestPath, req.InputPath, chunks); err != nil {
+			return chunks, err
+		}
+	}
+	return chunks, nil
+}
+
+// splitChapters writes one chunk per chapter of the input. Chunks are
+// stream copied when they keep the input's container and no options are
+// given, so video cuts land on the keyframe before each chapter starts.
+func (b *ffmpegBackend) splitChapters(ctx context.Context, req SplitRequest, outputExt string) ([]Chunk, error) {
+	inputExt := strings.ToLower(filepath.Ext(req.InputPath))
+	category := categoryOf(outputExt)
+	if !isOutputFormat(categoryOf(inputExt), outputExt) || (category != formats.CategoryVideo && category != formats.CategoryAudio) {
+		return nil, fmt.Errorf("cannot split %s into %s chapters", inputExt, outputExt)
+	}
+	if err := ValidateOptions(outputExt, req.Options); err != nil {
+		return nil, err
+	}
+	if err := checkChapterOptions(req.Options, category, outputExt); err != nil {
+		return nil, err
+	}
+	copyStreams := len(req.Options) == 0 && canonicalExt(outputExt) == canonicalExt(inputExt)
+
+	info, err := b.prober.Probe(ctx, req.InputPath)
+	if err != nil {
+		return nil, err
+	}
+	if len(info.Chapters) == 0 {
+		return nil, fmt.Errorf("%s has no chapters", req.InputPath)
+	}
+
+	pattern := req.NameTemplate
+	if pattern == "" {
+		pattern = DefaultChapterTemplate
+	}
+	tmpl, err := template.New("name").Option("missingkey=error").Parse(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("name template: %w", err)
+	}
+	outDir, err := splitDir(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Name every chunk first so that a template that gives two chapters
+	// the same name fails before anything is written
+	base := strings.TrimSuffix(filepath.Base(req.InputPath), filepath.Ext(req.InputPath))
+	chunks := make([]Chunk, len(info.Chapters))
+	seen := make(map[string]int)
+	for i, chapter := range info.Chapters {
+		title := strings.TrimSpace(chapter.Title)
+		if title == "" {
+			title = fmt.Sprintf("Chapter %d", i+1)
+		}
+		safeTitle := safeFileName(title)
+		if safeTitle == "" {
+			safeTitle = fmt.Sprintf("Chapter %d", i+1)
+		}
+		var name strings.Builder
+		data := ChapterName{Name: base, Title: safeTitle, Number: i + 1, Ext: outputExt}
+		if err := tmpl.Execute(&name, data); err != nil {
+			return nil, fmt.Errorf("name template: %w", err)
+		}
+		path := filepath.Join(outDir, name.String())
+		if prev, ok := seen[path]; ok {
+			return nil, fmt.Errorf("chapters %d and %d are both named %s; add {{.Number}} to the name template", prev, i+1, path)
+		}
+		seen[path] = i + 1
+		chunks[i] = Chunk{Path: path, Title: title, Start: chapter.Start, End: chapter.End}
+	}
+
+	for i, chunk := range chunks {
+		if err := os.MkdirAll(filepath.Dir(chunk.Path), 0755); err != nil {
+			return chunks[:i], err
+		}
+		args := append(seekArgs(Segment{Start: chunk.Start, End: chunk.End}), "-i", req.InputPath)
+		if copyStreams {
+			args = append(args, "-c", "copy", "-avoid_negative_ts", "make_zero")
+		} else {
+			if category == formats.CategoryAudio {
+				args = append(args, "-vn")
+			}
+			encode, err := trimEncodeArgs(req.Options, category, outputExt, nil)
+			if err != nil {
+				return chunks[:i], err
+			}
+			args = append(args, encode...)
+		}
+		args = append(args, "-map_chapters", "-1", "-metadata", "title="+chunk.Title, "-y", chunk.Path)
+		if err := runTool(ctx, "ffmpeg", b.path, args, nil); err != nil {
+			return chunks[:i], err
+		}
+		sendProgress(req.Progress, float64(i+1)/float64(len(chunks)))
+	}
+
+	if req.ManifestPath != "" {
+		if err := writeManifest(req.ManifestPath, req.InputPath, chunks); err != nil {
+			return chunks, err
+		}
+	}
+	return chunks, nil
+}
+
+// checkChapterOptions rejects the options that only make sense for a whole
+// file.
+func checkChapterOptions(options map[string]string, category, outputExt string) error {
+	if category == formats.CategoryAudio {
+		opts, err := parseAudio(options, outputExt)
+		if err != nil {
+			return err
+		}
+		if opts.StripSilence || opts.Loudness.Target != 0 {
+			return errors.New("strip_silence and loudness are not supported when splitting")
+		}
+		return nil
+	}
+	opts, err := ParseVideoOptions(options)
+	if err != nil {
+		return err
+	}
+	if opts.TargetSize > 0 || opts.Loudness.Target != 0 {
+		return errors.New("target_size and loudness are not supported when splitting")
+	}
+	return nil
+}
+
+func splitDir(req SplitRequest) (string, error) {
+	dir := req.OutputDir
+	if dir == "" {
+		dir = filepath.Dir(req.InputPath)
+	}
+	return dir, os.MkdirAll(dir, 0755)
+}
+
+// safeFileName replaces the characters that are not allowed in file names
+// on some platform, and trims the dots and spaces Windows drops.
+func safeFileName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if r < ' ' || strings.ContainsRune(`/\:*?"<>|`, r) {
+			return '_'
+		}
+		return r
+	}, name)
+	return strings.Trim(name, ". ")
+}
+
+// chunkBounds places cuts in the middle of each silence once the current
+// chunk is at least minLen long, and forces a cut at maxLen when no
+// silence comes in time. A final chunk shorter than minLen is merged into
+// the one before it if that stays within maxLen.
+func chunkBounds(silences []Segment, duration, minLen, maxLen float64) []Segment {
+	// Silence at either end stays with the first and last chunk rather
+	// than becoming a chunk of its own
+	const edge = 0.05
+	var cuts []float64
+	for _, s := range silences {
+		if s.Start <= edge || s.End == 0 || s.End >= duration-edge {
+			continue
+		}
+		cuts = append(cuts, (s.Start+s.End)/2)
+	}
+	cuts = append(cuts, duration)
+
+	var chunks []Segment
+	start := 0.0
+	for _, cut := range cuts {
+		for maxLen > 0 && cut-start > maxLen {
+			chunks = append(chunks, Segment{Start: start, End: start + maxLen})
+			start += maxLen
+		}
+		if cut-start >= minLen && cut > start {
+			chunks = append(chunks, Segment{Start: start, End: cut})
+			start = cut
+		}
+	}
+	if start < duration {
+		chunks = append(chunks, Segment{Start: start, End: duration})
+	}
+
+	if n := len(chunks); n > 1 {
+		last, prev := chunks[n-1], chunks[n-2]
+		if last.End-last.Start < minLen && (maxLen == 0 || last.End-prev.Start <= maxLen) {
+			chunks[n-2].End = last.End
+			chunks = chunks[:n-1]
+		}
+	}
+	return chunks
+}
+
+func writeManifest(path, input string, chunks []Chunk) error {
+	data, err := json.MarshalIndent(Manifest{Input: input, Chunks: chunks}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
@@ -0,0 +1,38 @@
+package converter
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestChunkBounds(t *testing.T) {
+	// Leading silence, two pauses and trailing silence that runs to the end
+	silences := []Segment{{0, 1.2}, {4, 5}, {12, 13}, {28.5, 0}}
+
+	tests := []struct {
+		name           string
+		silences       []Segment
+		duration       float64
+		minLen, maxLen float64
+		want           []Segment
+	}{
+		{"cut at every pause", silences, 30, 0, 0,
+			[]Segment{{0, 4.5}, {4.5, 12.5}, {12.5, 30}}},
+		{"skip pauses before min", silences, 30, 5, 0,
+			[]Segment{{0, 12.5}, {12.5, 30}}},
+		{"force cuts at max", silences, 30, 0, 10,
+			[]Segment{{0, 4.5}, {4.5, 12.5}, {12.5, 22.5}, {22.5, 30}}},
+		{"no silence", nil, 25, 0, 10,
+			[]Segment{{0, 10}, {10, 20}, {20, 25}}},
+		{"short tail kept when merging would pass max", nil, 25, 6, 10,
+			[]Segment{{0, 10}, {10, 20}, {20, 25}}},
+		{"short tail merged", []Segment{{9, 11}}, 12, 3, 0,
+			[]Segment{{0, 12}}},
+	}
+	for _, tt := range tests {
+		got := chunkBounds(tt.silences, tt.duration, tt.minLen, tt.maxLen)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: chunkBounds = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,344 @@
+package converter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"goverter/pkg/formats"
+	"goverter/pkg/utils"
+)
+
+// Segment is a stretch of a video or audio file in seconds. An End of 0
+// runs to the end of the input.
+type Segment struct {
+	Start float64
+	End   float64
+}
+
+func (s Segment) String() string {
+	if s.End == 0 {
+		return formatSeconds(s.Start) + "-"
+	}
+	return formatSeconds(s.Start) + "-" + formatSeconds(s.End)
+}
+
+// ParseSegments parses a comma-separated list of segments. Each one is
+// START-END, START+DURATION or START- (to the end), with positions in
+// seconds or HH:MM:SS.
+func ParseSegments(s string) ([]Segment, error) {
+	var segments []Segment
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		segment, err := parseSegment(part)
+		if err != nil {
+			return nil, fmt.Errorf("invalid segment %q: %w", part, err)
+		}
+		segments = append(segments, segment)
+	}
+	if len(segments) == 0 {
+		return nil, fmt.Errorf("no segments in %q", s)
+	}
+	return segments, nil
+}
+
+func parseSegment(s string) (Segment, error) {
+	if start, length, ok := strings.Cut(s, "+"); ok {
+		from, err := ParseTimestamp(start)
+		if err != nil {
+			return Segment{}, err
+		}
+		duration, err := ParseTimestamp(length)
+		if err != nil {
+			return Segment{}, err
+		}
+		if duration == 0 {
+			return Segment{}, errors.New("duration must be above 0")
+		}
+		return Segment{Start: from, End: from + duration}, nil
+	}
+
+	start, end, ok := strings.Cut(s, "-")
+	if !ok {
+		return Segment{}, errors.New("expected START-END or START+DURATION")
+	}
+	var segment Segment
+	var err error
+	if strings.TrimSpace(start) != "" {
+		if segment.Start, err = ParseTimestamp(start); err != nil {
+			return Segment{}, err
+		}
+	}
+	if strings.TrimSpace(end) != "" {
+		if segment.End, err = ParseTimestamp(end); err != nil {
+			return Segment{}, err
+		}
+		if segment.End <= segment.Start {
+			return Segment{}, errors.New("end must be after start")
+		}
+	}
+	return segment, nil
+}
+
+// TrimRequest cuts a video or audio file down to some of its segments.
+type TrimRequest struct {
+	InputPath  string
+	OutputPath string
+	// Segments are the parts to keep, or the parts to cut out when Remove
+	// is set. Kept parts are joined in time order.
+	Segments []Segment
+	Remove   bool
+	// Accurate re-encodes so that cuts land on the exact frame. Otherwise
+	// the streams are copied, which is fast and lossless but starts each
+	// segment at the keyframe before its start.
+	Accurate bool
+	// Options are encoder options as for Convert; they need Accurate.
+	Options  map[string]string
+	Progress chan float64
+}
+
+// Trim writes the kept segments of the input to the output. Like
+// ConvertContext, it removes the partial output when ctx is cancelled.
+func (c *Converter) Trim(ctx context.Context, req TrimRequest) error {
+	before := utils.StatFile(req.OutputPath)
+	err := c.trim(ctx, req)
+	if err != nil && ctx.Err() != nil {
+		before.RemoveIfChanged()
+		err = fmt.Errorf("trimming of %s stopped: %w", req.InputPath, ctx.Err())
+	}
+	return err
+}
+
+func (c *Converter) trim(ctx context.Context, req TrimRequest) error {
+	b := c.ffmpegBackend()
+	if b == nil || !b.Available() {
+		return fmt.Errorf("ffmpeg not found. Please install FFmpeg to trim video and audio")
+	}
+
+	inputExt := strings.ToLower(filepath.Ext(req.InputPath))
+	outputExt := strings.ToLower(filepath.Ext(req.OutputPath))
+	category := categoryOf(inputExt)
+	if category != formats.CategoryVideo && category != formats.CategoryAudio {
+		return fmt.Errorf("cannot trim %s files: only video and audio can be trimmed", inputExt)
+	}
+	if !isOutputFormat(category, outputExt) || categoryOf(outputExt) != category {
+		return fmt.Errorf("cannot trim %s into %s: the output must be a %s format (use convert to change the media type)", inputExt, outputExt, category)
+	}
+	if !req.Accurate && len(req.Options) > 0 {
+		return errors.New("encoder options need an accurate trim; a fast trim copies the streams as they are")
+	}
+	if err := ValidateOptions(outputExt, req.Options); err != nil {
+		return err
+	}
+
+	duration := b.probeDuration(ctx, req.InputPath)
+	keep, err := keepSegments(req.Segments, req.Remove, duration)
+	if err != nil {
+		return err
+	}
+
+	if !req.Accurate && len(keep) > 1 {
+		return b.copySegments(ctx, req, keep)
+	}
+
+	var args []string
+	if len(keep) == 1 {
+		args = append(args, seekArgs(keep[0])...)
+	}
+	args = append(args, "-i", req.InputPath)
+	if req.Accurate {
+		encode, err := trimEncodeArgs(req.Options, category, outputExt, keep)
+		if err != nil {
+			return err
+		}
+		args = append(args, encode...)
+	} else {
+		args = append(args, "-c", "copy", "-avoid_negative_ts", "make_zero")
+	}
+	args = append(args, "-y", req.OutputPath)
+
+	return b.runFFmpegTimed(ctx, req.Progress, keptDuration(keep, duration), args)
+}
+
+// ffmpegBackend returns the converter's ffmpeg backend, or nil if its
+// registry has none.
+func (c *Converter) ffmpegBackend() *ffmpegBackend {
+	for _, backend := range c.registry.Backends() {
+		if b, ok := backend.(*ffmpegBackend); ok {
+			return b
+		}
+	}
+	return nil
+}
+
+// keepSegments sorts and checks segments and, when remove is set, turns
+// them into the parts between them. duration is 0 when it is unknown.
+func keepSegments(segments []Segment, remove bool, duration float64) ([]Segment, error) {
+	if len(segments) == 0 {
+		return nil, errors.New("no segments given")
+	}
+	sorted := append([]Segment(nil), segments...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Start < sorted[j].Start })
+
+	for i, s := range sorted {
+		if s.End != 0 && s.End <= s.Start {
+			return nil, fmt.Errorf("segment %s ends before it starts", s)
+		}
+		if duration > 0 && s.Start >= duration {
+			return nil, fmt.Errorf("segment %s starts after the end of the input (%ss)", s, formatSeconds(duration))
+		}
+		if i > 0 {
+			if prev := sorted[i-1]; prev.End == 0 || prev.End > s.Start {
+				return nil, fmt.Errorf("segments %s and %s overlap", prev, s)
+			}
+		}
+	}
+	if !remove {
+		return sorted, nil
+	}
+
+	var keep []Segment
+	cursor, tail := 0.0, true
+	for _, s := range sorted {
+		if s.Start > cursor {
+			keep = append(keep, Segment{Start: cursor, End: s.Start})
+		}
+		if s.End == 0 {
+			tail = false
+			break
+		}
+		cursor = s.End
+	}
+	if tail && (duration == 0 || cursor < duration) {
+		keep = append(keep, Segment{Start: cursor})
+	}
+	if len(keep) == 0 {
+		return nil, errors.New("removing these segments leaves nothing")
+	}
+	return keep, nil
+}
+
+// keptDuration is the length of the output, or 0 if it cannot be known.
+func keptDuration(keep []Segment, duration float64) float64 {
+	var total float64
+	for _, s := range keep {
+		end := s.End
+		if end == 0 || (duration > 0 && end > duration) {
+			if duration == 0 {
+				return 0
+			}
+			end = duration
+		}
+		total += end - s.Start
+	}
+	return total
+}
+
+// seekArgs are the input options that limit reading to one segment.
+func seekArgs(s Segment) []string {
+	var args []string
+	if s.Start > 0 {
+		args = append(args, "-ss", formatSeconds(s.Start))
+	}
+	if s.End > 0 {
+		args = append(args, "-to", formatSeconds(s.End))
+	}
+	return args
+}
+
+// trimEncodeArgs re-encodes the output. With several segments, select and
+// aselect drop everything outside them and the timestamps are rebuilt so
+// the kept parts play back to back.
+func trimEncodeArgs(options map[string]string, category, outputExt string, keep []Segment) ([]string, error) {
+	var videoFilters, audioFilters []string
+	if len(keep) > 1 {
+		expr := selectExpr(keep)
+		videoFilters = append(videoFilters, fmt.Sprintf("select='%s'", expr), "setpts=N/FRAME_RATE/TB")
+		audioFilters = append(audioFilters, fmt.Sprintf("aselect='%s'", expr), "asetpts=N/SR/TB")
+	}
+
+	var args []string
+	if category == formats.CategoryAudio {
+		opts, err := parseAudio(options, outputExt)
+		if err != nil {
+			return nil, err
+		}
+		encoder := audioEncoder(opts.Codec, outputExt)
+		args = append(args, audioCodecArgs(encoder)...)
+		args = append(args, audioArgs(encoder, opts)...)
+	} else {
+		opts, err := ParseVideoOptions(options)
+		if err != nil {
+			return nil, err
+		}
+		if opts.TargetSize > 0 || opts.Loudness.Target != 0 {
+			return nil, errors.New("target_size and loudness are not supported when trimming")
+		}
+		if err := opts.checkCodecs(outputExt); err != nil {
+			return nil, err
+		}
+		// Scaling joins the select filters instead of adding its own -vf
+		if opts.Width > 0 || opts.Height > 0 {
+			videoFilters = append(videoFilters, scaleFilter(opts.Width, opts.Height))
+			opts.Width, opts.Height = 0, 0
+		}
+		args = append(args, videoEncodeArgs(opts, outputExt)...)
+		if len(videoFilters) > 0 {
+			args = append(args, "-vf", strings.Join(videoFilters, ","))
+		}
+	}
+	if len(audioFilters) > 0 {
+		args = append(args, "-af", strings.Join(audioFilters, ","))
+	}
+	return args, nil
+}
+
+func selectExpr(keep []Segment) string {
+	terms := make([]string, len(keep))
+	for i, s := range keep {
+		if s.End == 0 {
+			terms[i] = fmt.Sprintf("gte(t,%s)", formatSeconds(s.Start))
+		} else {
+			terms[i] = fmt.Sprintf("between(t,%s,%s)", formatSeconds(s.Start), formatSeconds(s.End))
+		}
+	}
+	return strings.Join(terms, "+")
+}
+
+// copySegments stream-copies each segment into a temporary file and joins
+// them with the concat demuxer.
+func (b *ffmpegBackend) copySegments(ctx context.Context, req TrimRequest, keep []Segment) error {
+	tmpDir, err := os.MkdirTemp("", "goverter-trim-")
+	if err != nil {
+		return fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	parts := make([]string, len(keep))
+	for i, s := range keep {
+		parts[i] = filepath.Join(tmpDir, fmt.Sprintf("part%03d%s", i, filepath.Ext(req.OutputPath)))
+		args := append(seekArgs(s), "-i", req.InputPath, "-c", "copy", "-avoid_negative_ts", "make_zero", "-y", parts[i])
+		if err := runTool(ctx, "ffmpeg", b.path, args, nil); err != nil {
+			return err
+		}
+		sendProgress(req.Progress, float64(i+1)/float64(len(keep)+1))
+	}
+
+	listPath := filepath.Join(tmpDir, "parts.txt")
+	if err := writeConcatList(listPath, parts); err != nil {
+		return err
+	}
+	args := []string{"-f", "concat", "-safe", "0", "-i", listPath, "-c", "copy", "-y", req.OutputPath}
+	if err := runTool(ctx, "ffmpeg", b.path, args, nil); err != nil {
+		return err
+	}
+	sendProgress(req.Progress, 1)
+	return nil
+}
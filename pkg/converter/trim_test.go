@@ -0,0 +1,102 @@
+package converter
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"goverter/pkg/formats"
+)
+
+func TestParseSegments(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    []Segment
+		wantErr string
+	}{
+		{"10-20", []Segment{{10, 20}}, ""},
+		{"1:00+30", []Segment{{60, 90}}, ""},
+		{"90-", []Segment{{90, 0}}, ""},
+		{"-30", []Segment{{0, 30}}, ""},
+		{"0:10-0:20, 1:00-", []Segment{{10, 20}, {60, 0}}, ""},
+		{"20-10", nil, "end must be after start"},
+		{"10+0", nil, "duration must be above 0"},
+		{"10", nil, "expected START-END"},
+		{"1:75-2:00", nil, "invalid timestamp"},
+		{" , ", nil, "no segments"},
+	}
+	for _, tt := range tests {
+		got, err := ParseSegments(tt.in)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ParseSegments(%q) = %v, %v; want error containing %q", tt.in, got, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseSegments(%q) = %v, %v; want %v", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestKeepSegments(t *testing.T) {
+	tests := []struct {
+		name     string
+		segments []Segment
+		remove   bool
+		duration float64
+		want     []Segment
+		wantErr  string
+	}{
+		{"keep sorts", []Segment{{30, 40}, {10, 20}}, false, 60, []Segment{{10, 20}, {30, 40}}, ""},
+		{"remove middle", []Segment{{10, 20}, {30, 40}}, true, 60, []Segment{{0, 10}, {20, 30}, {40, 0}}, ""},
+		{"remove both ends", []Segment{{0, 5}, {50, 0}}, true, 60, []Segment{{5, 50}}, ""},
+		{"remove up to the end", []Segment{{10, 60}}, true, 60, []Segment{{0, 10}}, ""},
+		{"remove, duration unknown", []Segment{{10, 20}}, true, 0, []Segment{{0, 10}, {20, 0}}, ""},
+		{"overlap", []Segment{{10, 30}, {20, 40}}, false, 60, nil, "overlap"},
+		{"overlap with open end", []Segment{{20, 30}, {10, 0}}, true, 60, nil, "overlap"},
+		{"past the end", []Segment{{70, 80}}, false, 60, nil, "starts after the end"},
+		{"remove everything", []Segment{{0, 0}}, true, 60, nil, "leaves nothing"},
+		{"none", nil, false, 60, nil, "no segments"},
+	}
+	for _, tt := range tests {
+		got, err := keepSegments(tt.segments, tt.remove, tt.duration)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s: got %v, %v; want error containing %q", tt.name, got, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %v, %v; want %v", tt.name, got, err, tt.want)
+		}
+	}
+}
+
+func TestTrimEncodeArgsAudioCodec(t *testing.T) {
+	tests := []struct {
+		ext  string
+		want string // the -c:a value, or "" for none
+	}{
+		{".mp3", "libmp3lame"},
+		{".ogg", "libvorbis"},
+		// A user-defined format has no default encoder; ffmpeg picks one
+		{".xyz", ""},
+	}
+	for _, tt := range tests {
+		args, err := trimEncodeArgs(nil, formats.CategoryAudio, tt.ext, []Segment{{10, 20}})
+		if err != nil {
+			t.Errorf("%s: %v", tt.ext, err)
+			continue
+		}
+		got := ""
+		for i, arg := range args {
+			if arg == "-c:a" && i+1 < len(args) {
+				got = args[i+1]
+			}
+		}
+		if got != tt.want || (tt.want == "" && contains(args, "-c:a")) {
+			t.Errorf("%s: args %q, want -c:a %q", tt.ext, args, tt.want)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package converter
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"goverter/pkg/utils"
+)
+
+const (
+	// sizeAttempts is how many two-pass encodes encodeToSize runs before
+	// giving up on an output that keeps overshooting.
+	sizeAttempts = 3
+	// containerOverhead is the share of the target size kept free for the
+	// container's own data.
+	containerOverhead = 0.03
+	defaultAudioBits  = 128000
+	minAudioBits      = 32000
+	minVideoBits      = 20000
+)
+
+// encodeToSize runs a two-pass encode whose video bit rate is derived from
+// the target size and the probed duration. If the output still comes out
+// too large, the bit rate is lowered in proportion and the encode rerun.
+// audio holds extra audio arguments for the second pass.
+func (b *ffmpegBackend) encodeToSize(ctx context.Context, req ConversionRequest, opts VideoOptions, audio []string) error {
+	duration := b.probeDuration(ctx, req.InputPath)
+	// A dry run may plan an input that an earlier step has yet to write;
+	// its bit rates are shown as placeholders
+	unprobed := duration <= 0 && utils.DryRun(ctx) != nil
+	if unprobed {
+		duration = 1
+	} else if duration <= 0 {
+		return fmt.Errorf("target_size needs the duration of %s, which could not be probed", req.InputPath)
+	}
+
+	target := opts.TargetSize
+	budget := float64(target) * 8 * (1 - containerOverhead) / duration
+
+	audioBits := float64(opts.AudioBitrate)
+	if audioBits == 0 {
+		// Give small targets most of the budget for the picture
+		audioBits = math.Max(minAudioBits, math.Min(defaultAudioBits, budget/4))
+	}
+	videoBits := budget - audioBits
+	if videoBits < minVideoBits {
+		return fmt.Errorf("target size %s is too small for %.0f seconds of video", utils.FormatFileSize(target), duration)
+	}
+
+	tmpDir, err := os.MkdirTemp("", "goverter-2pass-")
+	if err != nil {
+		return fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	passLog := filepath.Join(tmpDir, "ffmpeg2pass")
+	outputExt := filepath.Ext(req.OutputPath)
+	encoder := videoEncoder(opts, outputExt)
+
+	var size int64
+	for attempt := 1; attempt <= sizeAttempts; attempt++ {
+		pass := opts
+		pass.TargetSize = 0
+		pass.Bitrate = int64(videoBits/1000) * 1000
+		pass.AudioBitrate = int64(audioBits/1000) * 1000
+		encode := videoEncodeArgs(pass, outputExt)
+		if unprobed {
+			setArg(encode, "-b:v", placeholder("video_bitrate"))
+			if opts.AudioBitrate == 0 {
+				setArg(encode, "-b:a", placeholder("audio_bitrate"))
+			}
+		}
+
+		first := append([]string{"-i", req.InputPath}, encode...)
+		first = passArgs(first, encoder, 1, passLog)
+		first = append(first, "-an", "-f", "null", "-y", os.DevNull)
+		if err := b.runPass(ctx, req, first, 0, 0.5); err != nil {
+			return err
+		}
+
+		second := append([]string{"-i", req.InputPath}, encode...)
+		second = append(second, audio...)
+		second = passArgs(second, encoder, 2, passLog)
+		second = append(second, "-y", req.OutputPath)
+		if err := b.runPass(ctx, req, second, 0.5, 0.5); err != nil {
+			return err
+		}
+		if utils.DryRun(ctx) != nil {
+			// Nothing was written to measure
+			return nil
+		}
+
+		stat, err := os.Stat(req.OutputPath)
+		if err != nil {
+			return err
+		}
+		size = stat.Size()
+		if size <= target {
+			return nil
+		}
+
+		// Scale the video share down by the overshoot, with some margin
+		videoBits = (float64(target)/float64(size)*(videoBits+audioBits) - audioBits) * 0.95
+		if videoBits < minVideoBits {
+			break
+		}
+	}
+
+	os.Remove(req.OutputPath)
+	return fmt.Errorf("could not fit %s into %s: the smallest encode was %s",
+		req.InputPath, utils.FormatFileSize(target), utils.FormatFileSize(size))
+}
+
+// setArg replaces the value that follows flag in args.
+func setArg(args []string, flag, value string) {
+	for i := 0; i+1 < len(args); i++ {
+		if args[i] == flag {
+			args[i+1] = value
+		}
+	}
+}
+
+// passArgs adds the arguments that run pass 1 or 2 of encoder with its
+// statistics in logFile. libx265 ignores -pass and takes them in
+// -x265-params instead, which may already carry the level.
+func passArgs(args []string, encoder string, pass int, logFile string) []string {
+	if encoder != "libx265" {
+		return append(args, "-pass", strconv.Itoa(pass), "-passlogfile", logFile)
+	}
+
+	// x265-params is a key=value list split on colons; escape the path so
+	// that a Windows drive letter survives
+	stats := strings.NewReplacer(`\`, `\\`, ":", `\:`).Replace(logFile)
+	params := fmt.Sprintf("pass=%d:stats=%s", pass, stats)
+	for i := 0; i+1 < len(args); i++ {
+		if args[i] == "-x265-params" {
+			args[i+1] += ":" + params
+			return args
+		}
+	}
+	return append(args, "-x265-params", params)
+}
+
+// runPass runs one ffmpeg pass, reporting its progress as the share of the
+// request's progress that starts at offset.
+func (b *ffmpegBackend) runPass(ctx context.Context, req ConversionRequest, args []string, offset, share float64) error {
+	if req.Progress == nil {
+		return b.runFFmpeg(ctx, req, args)
+	}
+
+	passReq := req
+	var done func()
+	passReq.Progress, done = scaleProgress(req.Progress, offset, share)
+	err := b.runFFmpeg(ctx, passReq, args)
+	done()
+	return err
+}
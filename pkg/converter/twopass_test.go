@@ -0,0 +1,42 @@
+package converter
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPassArgs(t *testing.T) {
+	tests := []struct {
+		args    []string
+		encoder string
+		pass    int
+		logFile string
+		want    []string
+	}{
+		{
+			[]string{"-b:v", "800k"}, "libx264", 1, "/tmp/2pass/ffmpeg2pass",
+			[]string{"-b:v", "800k", "-pass", "1", "-passlogfile", "/tmp/2pass/ffmpeg2pass"},
+		},
+		{
+			[]string{"-c:v", "libvpx-vp9", "-b:v", "800k"}, "libvpx-vp9", 2, "/tmp/2pass/ffmpeg2pass",
+			[]string{"-c:v", "libvpx-vp9", "-b:v", "800k", "-pass", "2", "-passlogfile", "/tmp/2pass/ffmpeg2pass"},
+		},
+		{
+			[]string{"-c:v", "libx265", "-b:v", "800k"}, "libx265", 1, "/tmp/2pass/ffmpeg2pass",
+			[]string{"-c:v", "libx265", "-b:v", "800k", "-x265-params", "pass=1:stats=/tmp/2pass/ffmpeg2pass"},
+		},
+		{
+			[]string{"-c:v", "libx265", "-x265-params", "level-idc=4.1", "-b:v", "800k"}, "libx265", 2, "/tmp/2pass/ffmpeg2pass",
+			[]string{"-c:v", "libx265", "-x265-params", "level-idc=4.1:pass=2:stats=/tmp/2pass/ffmpeg2pass", "-b:v", "800k"},
+		},
+		{
+			nil, "libx265", 1, `C:\Temp\ffmpeg2pass`,
+			[]string{"-x265-params", `pass=1:stats=C\:\\Temp\\ffmpeg2pass`},
+		},
+	}
+	for _, tt := range tests {
+		if got := passArgs(tt.args, tt.encoder, tt.pass, tt.logFile); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("passArgs(%q, %s, %d) = %q, want %q", tt.args, tt.encoder, tt.pass, got, tt.want)
+		}
+	}
+}
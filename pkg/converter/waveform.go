@@ -0,0 +1,134 @@
+package converter
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"goverter/pkg/utils"
+)
+
+const (
+	StyleWaveform    = "waveform"
+	StyleSpectrogram = "spectrogram"
+)
+
+// ColorSchemes are the spectrogram colour schemes ffmpeg's showspectrumpic
+// offers.
+var ColorSchemes = []string{
+	"intensity", "channel", "rainbow", "moreland", "nebulae", "fire", "fiery",
+	"fruit", "cool", "magma", "green", "viridis", "plasma", "cividis", "terrain",
+}
+
+// WaveformRequest describes a waveform or spectrogram image of a video or
+// audio file. The output is a PNG or JPEG, chosen by its extension.
+type WaveformRequest struct {
+	InputPath  string
+	OutputPath string
+	Style      string // StyleWaveform (default) or StyleSpectrogram
+	Width      int    // default 1200
+	Height     int    // default 240 for a waveform, 480 for a spectrogram
+	// Color is the waveform colour as a name or #RRGGBB[AA] (default
+	// #3b82f6), or one of ColorSchemes for a spectrogram (default
+	// intensity).
+	Color string
+	// Background fills behind a waveform; it is transparent by default,
+	// which shows as black in a JPEG.
+	Background string
+	// SplitChannels draws each channel of a waveform in its own row.
+	SplitChannels bool
+	// Legend adds frequency and time axes around a spectrogram, which
+	// makes the image larger than Width x Height.
+	Legend bool
+}
+
+var colorPattern = regexp.MustCompile(`^(#[0-9A-Fa-f]{6}([0-9A-Fa-f]{2})?|[A-Za-z]+)$`)
+
+// DrawWaveform draws the first audio stream of a file as a single picture.
+// If ctx is cancelled first, ffmpeg is killed and the partial image
+// removed.
+func (c *Converter) DrawWaveform(ctx context.Context, req WaveformRequest) error {
+	b := c.ffmpegBackend()
+	if b == nil || !b.Available() {
+		return fmt.Errorf("ffmpeg not found. Please install FFmpeg to draw waveforms")
+	}
+	switch ext := strings.ToLower(filepath.Ext(req.OutputPath)); ext {
+	case ".png", ".jpg", ".jpeg":
+	default:
+		return fmt.Errorf("cannot draw a %s image: the output must be .png or .jpg", ext)
+	}
+
+	filter, err := waveformFilter(req)
+	if err != nil {
+		return err
+	}
+	args := []string{"-hide_banner", "-i", req.InputPath, "-filter_complex", filter, "-frames:v", "1", "-y", req.OutputPath}
+
+	before := utils.StatFile(req.OutputPath)
+	if err := runTool(ctx, "ffmpeg", b.path, args, nil); err != nil {
+		if ctx.Err() != nil {
+			before.RemoveIfChanged()
+			return fmt.Errorf("drawing %s stopped: %w", req.InputPath, ctx.Err())
+		}
+		return err
+	}
+	return nil
+}
+
+// waveformFilter checks the request and builds the filter graph that turns
+// the first audio stream into a single picture.
+func waveformFilter(req WaveformRequest) (string, error) {
+	width, height := req.Width, req.Height
+	if width == 0 {
+		width = 1200
+	}
+	if width < 16 || width > 8192 || height < 0 || height > 8192 || (height > 0 && height < 16) {
+		return "", fmt.Errorf("image size must be between 16 and 8192 pixels a side, got %dx%d", width, height)
+	}
+
+	switch req.Style {
+	case "", StyleWaveform:
+		if height == 0 {
+			height = 240
+		}
+		if req.Legend {
+			return "", fmt.Errorf("a legend is only drawn on spectrograms")
+		}
+		color := req.Color
+		if color == "" {
+			color = "#3b82f6"
+		}
+		if !colorPattern.MatchString(color) {
+			return "", fmt.Errorf("invalid colour %q: expected a name or #RRGGBB", color)
+		}
+		filter := fmt.Sprintf("[0:a:0]showwavespic=s=%dx%d:colors=%s:split_channels=%d", width, height, color, boolInt(req.SplitChannels))
+		if req.Background == "" {
+			return filter, nil
+		}
+		if !colorPattern.MatchString(req.Background) {
+			return "", fmt.Errorf("invalid background %q: expected a name or #RRGGBB", req.Background)
+		}
+		return fmt.Sprintf("color=c=%s:s=%dx%d[bg];%s[fg];[bg][fg]overlay=format=auto:shortest=1", req.Background, width, height, filter), nil
+
+	case StyleSpectrogram:
+		if height == 0 {
+			height = 480
+		}
+		if req.SplitChannels || req.Background != "" {
+			return "", fmt.Errorf("split channels and a background only apply to waveforms")
+		}
+		scheme := req.Color
+		if scheme == "" {
+			scheme = "intensity"
+		}
+		if !contains(ColorSchemes, scheme) {
+			return "", fmt.Errorf("unknown spectrogram colour scheme %q: expected one of %s", scheme, strings.Join(ColorSchemes, ", "))
+		}
+		return fmt.Sprintf("[0:a:0]showspectrumpic=s=%dx%d:color=%s:legend=%d", width, height, scheme, boolInt(req.Legend)), nil
+
+	default:
+		return "", fmt.Errorf("unknown style %q: expected %s or %s", req.Style, StyleWaveform, StyleSpectrogram)
+	}
+}
@@ -0,0 +1,41 @@
+package converter
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWaveformFilter(t *testing.T) {
+	tests := []struct {
+		req     WaveformRequest
+		want    string
+		wantErr string
+	}{
+		{WaveformRequest{}, "[0:a:0]showwavespic=s=1200x240:colors=#3b82f6:split_channels=0", ""},
+		{WaveformRequest{Width: 640, Height: 160, Color: "white", SplitChannels: true},
+			"[0:a:0]showwavespic=s=640x160:colors=white:split_channels=1", ""},
+		{WaveformRequest{Width: 640, Height: 160, Background: "#000000"},
+			"color=c=#000000:s=640x160[bg];[0:a:0]showwavespic=s=640x160:colors=#3b82f6:split_channels=0[fg];[bg][fg]overlay=format=auto:shortest=1", ""},
+		{WaveformRequest{Style: StyleSpectrogram, Color: "magma", Legend: true},
+			"[0:a:0]showspectrumpic=s=1200x480:color=magma:legend=1", ""},
+		{WaveformRequest{Width: 8}, "", "between 16 and 8192"},
+		{WaveformRequest{Color: "#12345"}, "", "invalid colour"},
+		{WaveformRequest{Color: "red;drawtext"}, "", "invalid colour"},
+		{WaveformRequest{Legend: true}, "", "only drawn on spectrograms"},
+		{WaveformRequest{Style: StyleSpectrogram, Color: "sepia"}, "", "colour scheme"},
+		{WaveformRequest{Style: StyleSpectrogram, SplitChannels: true}, "", "only apply to waveforms"},
+		{WaveformRequest{Style: "bars"}, "", "unknown style"},
+	}
+	for _, tt := range tests {
+		got, err := waveformFilter(tt.req)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("waveformFilter(%+v) = %q, %v; want error containing %q", tt.req, got, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("waveformFilter(%+v) = %q, %v; want %q", tt.req, got, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package formats
+
+var fromVideo = []string{CategoryVideo}
+
+// Builtin is the format table goverter ships with. A formats file in the
+// config directory can add to or override it.
+var Builtin = []Format{
+	// Video
+	{Ext: ".mp4", Name: "MP4", Description: "Modern video format with good compression", MIME: "video/mp4", Category: CategoryVideo, Read: true, Write: true},
+	{Ext: ".avi", Name: "AVI", Description: "Classic video format, large file sizes", MIME: "video/x-msvideo", Category: CategoryVideo, Read: true, Write: true},
+	{Ext: ".mkv", Name: "Matroska", Description: "Flexible container for multiple tracks", MIME: "video/x-matroska", Category: CategoryVideo, Read: true, Write: true},
+	{Ext: ".mov", Name: "QuickTime", Description: "Apple QuickTime video format", MIME: "video/quicktime", Category: CategoryVideo, Read: true, Write: true},
+	{Ext: ".wmv", Name: "WMV", Description: "Windows Media video", MIME: "video/x-ms-wmv", Category: CategoryVideo, Read: true, Write: true},
+	{Ext: ".flv", Name: "FLV", Description: "Flash video", MIME: "video/x-flv", Category: CategoryVideo, Read: true, Write: true},
+	{Ext: ".webm", Name: "WebM", Description: "Open web video format", MIME: "video/webm", Category: CategoryVideo, Read: true, Write: true},
+	{Ext: ".m4v", Name: "M4V", Description: "Apple MPEG-4 video", MIME: "video/x-m4v", Category: CategoryVideo, Read: true, Write: true},
+	{Ext: ".3gp", Name: "3GP", Description: "Mobile video format", MIME: "video/3gpp", Category: CategoryVideo, Read: true},
+	{Ext: ".ogv", Name: "Ogg Video", Description: "Ogg Theora video", MIME: "video/ogg", Category: CategoryVideo, Read: true},
+	{Ext: ".ts", Name: "MPEG-TS", Description: "MPEG transport stream", MIME: "video/mp2t", Category: CategoryVideo, Read: true},
+	{Ext: ".mts", Name: "AVCHD", Description: "AVCHD camcorder video", MIME: "video/mp2t", Category: CategoryVideo, Aliases: []string{".m2ts"}, Read: true},
+
+	// Audio
+	{Ext: ".mp3", Name: "MP3", Description: "Compressed audio format", MIME: "audio/mpeg", Category: CategoryAudio, Read: true, Write: true, From: fromVideo},
+	{Ext: ".wav", Name: "WAV", Description: "Uncompressed audio format", MIME: "audio/wav", Category: CategoryAudio, Read: true, Write: true, From: fromVideo},
+	{Ext: ".flac", Name: "FLAC", Description: "Lossless audio compression", MIME: "audio/flac", Category: CategoryAudio, Read: true, Write: true, From: fromVideo},
+	{Ext: ".aac", Name: "AAC", Description: "Advanced Audio Coding", MIME: "audio/aac", Category: CategoryAudio, Read: true, Write: true, From: fromVideo},
+	{Ext: ".ogg", Name: "Ogg Vorbis", Description: "Open compressed audio format", MIME: "audio/ogg", Category: CategoryAudio, Read: true, Write: true, From: fromVideo},
+	{Ext: ".m4a", Name: "M4A", Description: "MPEG-4 audio", MIME: "audio/mp4", Category: CategoryAudio, Aliases: []string{".m4b"}, Read: true, Write: true, From: fromVideo},
+	{Ext: ".wma", Name: "WMA", Description: "Windows Media audio", MIME: "audio/x-ms-wma", Category: CategoryAudio, Read: true},
+	{Ext: ".opus", Name: "Opus", Description: "Low-latency compressed audio", MIME: "audio/opus", Category: CategoryAudio, Read: true},
+	{Ext: ".aiff", Name: "AIFF", Description: "Uncompressed Apple audio", MIME: "audio/aiff", Category: CategoryAudio, Aliases: []string{".aif"}, Read: true},
+	{Ext: ".au", Name: "AU", Description: "Sun audio", MIME: "audio/basic", Category: CategoryAudio, Read: true},
+	{Ext: ".ra", Name: "RealAudio", Description: "RealAudio stream", MIME: "audio/vnd.rn-realaudio", Category: CategoryAudio, Read: true},
+	{Ext: ".amr", Name: "AMR", Description: "Adaptive multi-rate speech audio", MIME: "audio/amr", Category: CategoryAudio, Read: true},
+	{Ext: ".ac3", Name: "AC-3", Description: "Dolby Digital audio", MIME: "audio/ac3", Category: CategoryAudio, Read: true},
+
+	// Image
+	{Ext: ".jpg", Name: "JPEG", Description: "Compressed image format for photos", MIME: "image/jpeg", Category: CategoryImage, Aliases: []string{".jpeg"}, Read: true, Write: true, From: fromVideo},
+	{Ext: ".png", Name: "PNG", Description: "Lossless image format with transparency", MIME: "image/png", Category: CategoryImage, Read: true, Write: true, From: fromVideo},
+	{Ext: ".gif", Name: "GIF", Description: "Animated image format", MIME: "image/gif", Category: CategoryImage, Read: true, Write: true, From: fromVideo},
+	{Ext: ".bmp", Name: "BMP", Description: "Uncompressed bitmap image", MIME: "image/bmp", Category: CategoryImage, Read: true, Write: true},
+	{Ext: ".webp", Name: "WebP", Description: "Modern web image format", MIME: "image/webp", Category: CategoryImage, Read: true, Write: true, From: fromVideo},
+	{Ext: ".apng", Name: "APNG", Description: "Animated PNG", MIME: "image/apng", Category: CategoryImage, Read: true, Write: true, From: fromVideo},
+	{Ext: ".tiff", Name: "TIFF", Description: "High quality image format for print", MIME: "image/tiff", Category: CategoryImage, Aliases: []string{".tif"}, Read: true, Write: true},
+	{Ext: ".svg", Name: "SVG", Description: "Scalable vector graphics", MIME: "image/svg+xml", Category: CategoryImage, Read: true},
+
+	// Document
+	{Ext: ".pdf", Name: "PDF", Description: "Document format for sharing", MIME: "application/pdf", Category: CategoryDocument, Read: true, Write: true, From: []string{CategoryImage}},
+	{Ext: ".doc", Name: "Word 97", Description: "Legacy Microsoft Word document", MIME: "application/msword", Category: CategoryDocument, Read: true},
+	{Ext: ".docx", Name: "Word", Description: "Microsoft Word document", MIME: "application/vnd.openxmlformats-officedocument.wordprocessingml.document", Category: CategoryDocument, Read: true, Write: true},
+	{Ext: ".txt", Name: "Text", Description: "Plain text document", MIME: "text/plain", Category: CategoryDocument, Read: true, Write: true},
+	{Ext: ".rtf", Name: "RTF", Description: "Rich text format", MIME: "application/rtf", Category: CategoryDocument, Read: true},
+	{Ext: ".odt", Name: "ODT", Description: "OpenDocument text", MIME: "application/vnd.oasis.opendocument.text", Category: CategoryDocument, Read: true},
+	{Ext: ".html", Name: "HTML", Description: "Web page", MIME: "text/html", Category: CategoryDocument, Aliases: []string{".htm"}, Read: true, Write: true},
+}
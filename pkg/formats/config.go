@@ -0,0 +1,106 @@
+package formats
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+
+	"goverter/internal/config"
+)
+
+// ConfigFileNames are looked up, in order, in the goverter config directory.
+var ConfigFileNames = []string{"formats.toml", "formats.yaml", "formats.yml"}
+
+// configFile is the layout of a formats file:
+//
+//	[[formats]]
+//	ext = ".heic"
+//	name = "HEIC"
+//	mime = "image/heic"
+//	category = "image"
+//	aliases = [".heif"]
+//	read = true
+type configFile struct {
+	Formats []Format `toml:"formats" yaml:"formats"`
+}
+
+// LoadFile adds the formats in a TOML or YAML file to r. An entry whose ext
+// is already registered replaces that format.
+func (r *Registry) LoadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var file configFile
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".toml":
+		err = toml.Unmarshal(data, &file)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &file)
+	default:
+		return fmt.Errorf("unsupported formats file %s: expected .toml, .yaml or .yml", path)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	for i, format := range file.Formats {
+		if format.Ext == "" || format.Category == "" {
+			return fmt.Errorf("%s: format #%d needs both ext and category", path, i+1)
+		}
+	}
+	for _, format := range file.Formats {
+		r.Add(format)
+	}
+	return nil
+}
+
+// ConfigPath returns the formats file in the config directory, or "" if
+// there is none.
+func ConfigPath() string {
+	dir, err := config.Dir()
+	if err != nil {
+		return ""
+	}
+	for _, name := range ConfigFileNames {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+var (
+	defaultOnce     sync.Once
+	defaultRegistry *Registry
+	defaultErr      error
+)
+
+// Default returns the shared registry: the builtin table extended by the
+// user's formats file, if any. If that file is invalid the builtin table is
+// used on its own and the problem is reported by DefaultLoadError.
+func Default() *Registry {
+	defaultOnce.Do(func() {
+		defaultRegistry = NewRegistry(Builtin...)
+		if path := ConfigPath(); path != "" {
+			user := NewRegistry(defaultRegistry.Formats()...)
+			if defaultErr = user.LoadFile(path); defaultErr == nil {
+				defaultRegistry = user
+			}
+		}
+	})
+	return defaultRegistry
+}
+
+// DefaultLoadError returns the error from loading the user's formats file.
+func DefaultLoadError() error {
+	Default()
+	return defaultErr
+}
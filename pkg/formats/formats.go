@@ -0,0 +1,202 @@
+package formats
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	CategoryVideo    = "video"
+	CategoryAudio    = "audio"
+	CategoryImage    = "image"
+	CategoryDocument = "document"
+)
+
+// Format describes one file format. Extensions are lowercase with the
+// leading dot.
+type Format struct {
+	Ext         string   `toml:"ext" yaml:"ext" json:"ext"`
+	Name        string   `toml:"name" yaml:"name" json:"name"`
+	Description string   `toml:"description" yaml:"description" json:"description,omitempty"`
+	MIME        string   `toml:"mime" yaml:"mime" json:"mime"`
+	Category    string   `toml:"category" yaml:"category" json:"category"`
+	Aliases     []string `toml:"aliases" yaml:"aliases" json:"aliases,omitempty"`
+
+	// Read and Write say whether the format can be a conversion input or
+	// output. From lists other categories that can be converted into it,
+	// e.g. "video" for .gif and .mp3.
+	Read  bool     `toml:"read" yaml:"read" json:"read"`
+	Write bool     `toml:"write" yaml:"write" json:"write"`
+	From  []string `toml:"from" yaml:"from" json:"from,omitempty"`
+}
+
+// Extensions returns the canonical extension followed by the aliases.
+func (f Format) Extensions() []string {
+	return append([]string{f.Ext}, f.Aliases...)
+}
+
+// ProducibleFrom reports whether a file of the given category can be
+// converted into f.
+func (f Format) ProducibleFrom(category string) bool {
+	if !f.Write {
+		return false
+	}
+	if f.Category == category {
+		return true
+	}
+	for _, from := range f.From {
+		if from == category {
+			return true
+		}
+	}
+	return false
+}
+
+// Registry indexes formats by extension and alias.
+type Registry struct {
+	mu      sync.RWMutex
+	formats []Format
+	byExt   map[string]int
+}
+
+func NewRegistry(formats ...Format) *Registry {
+	r := &Registry{byExt: make(map[string]int)}
+	for _, format := range formats {
+		r.Add(format)
+	}
+	return r
+}
+
+// Add registers format, replacing any format with the same canonical
+// extension.
+func (r *Registry) Add(format Format) {
+	format.Ext = NormalizeExt(format.Ext)
+	format.Aliases = append([]string(nil), format.Aliases...)
+	for i, alias := range format.Aliases {
+		format.Aliases[i] = NormalizeExt(alias)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if i, ok := r.byExt[format.Ext]; ok && r.formats[i].Ext == format.Ext {
+		r.formats[i] = format
+	} else {
+		r.formats = append(r.formats, format)
+	}
+	r.reindex()
+}
+
+func (r *Registry) reindex() {
+	r.byExt = make(map[string]int, len(r.formats))
+	// Aliases first so that a canonical extension always wins.
+	for i, format := range r.formats {
+		for _, alias := range format.Aliases {
+			r.byExt[alias] = i
+		}
+	}
+	for i, format := range r.formats {
+		r.byExt[format.Ext] = i
+	}
+}
+
+// Lookup finds a format by extension or alias. ext may omit the dot and is
+// matched case-insensitively.
+func (r *Registry) Lookup(ext string) (Format, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	i, ok := r.byExt[NormalizeExt(ext)]
+	if !ok {
+		return Format{}, false
+	}
+	return r.formats[i], true
+}
+
+// Same reports whether a and b name the same format, such as .jpg and
+// .jpeg. Unknown extensions only match themselves.
+func (r *Registry) Same(a, b string) bool {
+	canonical := func(ext string) string {
+		if format, ok := r.Lookup(ext); ok {
+			return format.Ext
+		}
+		return NormalizeExt(ext)
+	}
+	return canonical(a) == canonical(b)
+}
+
+// Category returns the category of ext, or "" if it is unknown.
+func (r *Registry) Category(ext string) string {
+	format, _ := r.Lookup(ext)
+	return format.Category
+}
+
+// Is reports whether ext is a known format of category.
+func (r *Registry) Is(ext, category string) bool {
+	return r.Category(ext) == category
+}
+
+// Formats returns every registered format in registration order.
+func (r *Registry) Formats() []Format {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return append([]Format(nil), r.formats...)
+}
+
+// Categories returns the categories that have at least one format, sorted.
+func (r *Registry) Categories() []string {
+	seen := make(map[string]bool)
+	var categories []string
+	for _, format := range r.Formats() {
+		if !seen[format.Category] {
+			seen[format.Category] = true
+			categories = append(categories, format.Category)
+		}
+	}
+	sort.Strings(categories)
+	return categories
+}
+
+// Extensions returns every extension and alias of category.
+func (r *Registry) Extensions(category string) []string {
+	var exts []string
+	for _, format := range r.Formats() {
+		if format.Category == category {
+			exts = append(exts, format.Extensions()...)
+		}
+	}
+	return exts
+}
+
+// InputExtensions returns the readable extensions of category.
+func (r *Registry) InputExtensions(category string) []string {
+	var exts []string
+	for _, format := range r.Formats() {
+		if format.Category == category && format.Read {
+			exts = append(exts, format.Extensions()...)
+		}
+	}
+	return exts
+}
+
+// OutputExtensions returns the extensions a file of category can be
+// converted to, including other categories' formats that list it in From.
+func (r *Registry) OutputExtensions(category string) []string {
+	var exts []string
+	for _, format := range r.Formats() {
+		if format.ProducibleFrom(category) {
+			exts = append(exts, format.Extensions()...)
+		}
+	}
+	return exts
+}
+
+// NormalizeExt lowercases ext and makes sure it starts with a dot.
+func NormalizeExt(ext string) string {
+	ext = strings.ToLower(strings.TrimSpace(ext))
+	if ext != "" && !strings.HasPrefix(ext, ".") {
+		ext = "." + ext
+	}
+	return ext
+}
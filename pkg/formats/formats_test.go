@@ -0,0 +1,22 @@
+package formats
+
+import "testing"
+
+func TestSame(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{".jpeg", ".jpg", true},
+		{".JPEG", "jpg", true},
+		{".tif", ".tiff", true},
+		{".png", ".jpg", false},
+		{".xyz", "xyz", true},
+		{".xyz", ".abc", false},
+	}
+	for _, tt := range tests {
+		if got := Default().Same(tt.a, tt.b); got != tt.want {
+			t.Errorf("Same(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package image
 
 import (
+	"context"
 	"fmt"
 	"image"
 	"image/jpeg"
@@ -10,6 +11,8 @@ import (
 	"strings"
 
 	"github.com/disintegration/imaging"
+	"goverter/pkg/scheduler"
+	"goverter/pkg/utils"
 )
 
 type CropRequest struct {
@@ -34,31 +37,43 @@ func NewProcessor() *Processor {
 }
 
 func (p *Processor) Crop(req CropRequest) error {
-	img, err := imaging.Open(req.InputPath)
+	return p.CropContext(context.Background(), req)
+}
+
+func (p *Processor) CropContext(ctx context.Context, req CropRequest) error {
+	img, err := p.openImage(ctx, req.InputPath)
 	if err != nil {
-		return fmt.Errorf("failed to open image: %w", err)
+		return err
 	}
 
 	cropped := imaging.Crop(img, image.Rect(req.X, req.Y, req.X+req.Width, req.Y+req.Height))
 
-	return p.saveImage(cropped, req.OutputPath, req.Quality)
+	return p.saveImageContext(ctx, cropped, req.OutputPath, req.Quality)
 }
 
 func (p *Processor) Resize(req ResizeRequest) error {
-	img, err := imaging.Open(req.InputPath)
+	return p.ResizeContext(context.Background(), req)
+}
+
+func (p *Processor) ResizeContext(ctx context.Context, req ResizeRequest) error {
+	img, err := p.openImage(ctx, req.InputPath)
 	if err != nil {
-		return fmt.Errorf("failed to open image: %w", err)
+		return err
 	}
 
 	resized := imaging.Resize(img, req.Width, req.Height, imaging.Lanczos)
 
-	return p.saveImage(resized, req.OutputPath, req.Quality)
+	return p.saveImageContext(ctx, resized, req.OutputPath, req.Quality)
 }
 
 func (p *Processor) Rotate(inputPath, outputPath string, degrees float64, quality int) error {
-	img, err := imaging.Open(inputPath)
+	return p.RotateContext(context.Background(), inputPath, outputPath, degrees, quality)
+}
+
+func (p *Processor) RotateContext(ctx context.Context, inputPath, outputPath string, degrees float64, quality int) error {
+	img, err := p.openImage(ctx, inputPath)
 	if err != nil {
-		return fmt.Errorf("failed to open image: %w", err)
+		return err
 	}
 
 	var rotated *image.NRGBA
@@ -73,13 +88,17 @@ func (p *Processor) Rotate(inputPath, outputPath string, degrees float64, qualit
 		rotated = imaging.Rotate(img, degrees, image.Transparent)
 	}
 
-	return p.saveImage(rotated, outputPath, quality)
+	return p.saveImageContext(ctx, rotated, outputPath, quality)
 }
 
 func (p *Processor) Flip(inputPath, outputPath string, horizontal bool, quality int) error {
-	img, err := imaging.Open(inputPath)
+	return p.FlipContext(context.Background(), inputPath, outputPath, horizontal, quality)
+}
+
+func (p *Processor) FlipContext(ctx context.Context, inputPath, outputPath string, horizontal bool, quality int) error {
+	img, err := p.openImage(ctx, inputPath)
 	if err != nil {
-		return fmt.Errorf("failed to open image: %w", err)
+		return err
 	}
 
 	var flipped *image.NRGBA
@@ -89,7 +108,23 @@ func (p *Processor) Flip(inputPath, outputPath string, horizontal bool, quality
 		flipped = imaging.FlipV(img)
 	}
 
-	return p.saveImage(flipped, outputPath, quality)
+	return p.saveImageContext(ctx, flipped, outputPath, quality)
+}
+
+// openImage decodes path unless ctx is already done. Decoding and the
+// imaging transforms are not interruptible, so cancellation is checked
+// around them rather than during.
+func (p *Processor) openImage(ctx context.Context, path string) (image.Image, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	img, err := imaging.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open image: %w", err)
+	}
+
+	return img, nil
 }
 
 func (p *Processor) GetImageInfo(imagePath string) (*ImageInfo, error) {
@@ -118,10 +153,32 @@ func (p *Processor) GetImageInfo(imagePath string) (*ImageInfo, error) {
 }
 
 type ImageInfo struct {
-	Width    int
-	Height   int
-	Format   string
-	FileSize int64
+	Width    int    `json:"width,omitempty"`
+	Height   int    `json:"height,omitempty"`
+	Format   string `json:"format,omitempty"`
+	FileSize int64  `json:"file_size,omitempty"`
+}
+
+// saveImageContext writes img unless ctx finished while it was being
+// transformed. If ctx finishes or encoding fails part way through, the
+// output is removed only if this call created or rewrote it.
+func (p *Processor) saveImageContext(ctx context.Context, img image.Image, outputPath string, quality int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	before := utils.StatFile(outputPath)
+	if err := p.saveImage(img, outputPath, quality); err != nil {
+		before.RemoveIfChanged()
+		return err
+	}
+
+	if err := ctx.Err(); err != nil {
+		before.RemoveIfChanged()
+		return err
+	}
+
+	return nil
 }
 
 func (p *Processor) saveImage(img image.Image, outputPath string, quality int) error {
@@ -147,22 +204,34 @@ func (p *Processor) saveImage(img image.Image, outputPath string, quality int) e
 }
 
 func (p *Processor) BatchProcess(requests []interface{}) []error {
-	errors := make([]error, len(requests))
+	return p.BatchProcessContext(context.Background(), requests, 0)
+}
 
+// BatchProcessContext runs requests on up to workers goroutines (one per
+// CPU if workers is 0). Errors are indexed like requests.
+func (p *Processor) BatchProcessContext(ctx context.Context, requests []interface{}, workers int) []error {
+	jobs := make([]scheduler.Job, len(requests))
 	for i, req := range requests {
-		switch r := req.(type) {
-		case CropRequest:
-			if err := p.Crop(r); err != nil {
-				errors[i] = fmt.Errorf("failed to crop %s: %w", r.InputPath, err)
-			}
-		case ResizeRequest:
-			if err := p.Resize(r); err != nil {
-				errors[i] = fmt.Errorf("failed to resize %s: %w", r.InputPath, err)
-			}
-		default:
-			errors[i] = fmt.Errorf("unsupported request type at index %d", i)
+		i, req := i, req
+		jobs[i] = scheduler.Job{
+			Category: "image",
+			Run: func(ctx context.Context) error {
+				switch r := req.(type) {
+				case CropRequest:
+					if err := p.CropContext(ctx, r); err != nil {
+						return fmt.Errorf("failed to crop %s: %w", r.InputPath, err)
+					}
+				case ResizeRequest:
+					if err := p.ResizeContext(ctx, r); err != nil {
+						return fmt.Errorf("failed to resize %s: %w", r.InputPath, err)
+					}
+				default:
+					return fmt.Errorf("unsupported request type at index %d", i)
+				}
+				return nil
+			},
 		}
 	}
 
-	return errors
+	return scheduler.New(workers).RunAll(ctx, jobs)
 }
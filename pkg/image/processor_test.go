@@ -0,0 +1,34 @@
+package image
+
+import (
+	"context"
+	"image"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSaveImageContextKeepsUntouchedOutput(t *testing.T) {
+	dir := t.TempDir()
+	img := image.NewRGBA(image.Rect(0, 0, 4, 4))
+
+	// os.Create fails on a directory, which must survive the failure
+	existing := filepath.Join(dir, "existing.png")
+	if err := os.Mkdir(existing, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := NewProcessor().saveImageContext(context.Background(), img, existing, 0); err == nil {
+		t.Fatal("saving over a directory succeeded, want an error")
+	}
+	if _, err := os.Stat(existing); err != nil {
+		t.Errorf("output the save never wrote was removed: %v", err)
+	}
+
+	written := filepath.Join(dir, "new.png")
+	if err := NewProcessor().saveImageContext(context.Background(), img, written, 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(written); err != nil {
+		t.Errorf("saved image is missing: %v", err)
+	}
+}
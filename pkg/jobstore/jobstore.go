@@ -0,0 +1,461 @@
+package jobstore
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"goverter/internal/config"
+	"goverter/pkg/utils"
+)
+
+const (
+	StatusPending = "pending"
+	StatusRunning = "running"
+	StatusDone    = "done"
+	StatusFailed  = "failed"
+)
+
+// FileName is the journal kept in the config directory by default.
+const FileName = "jobs.jsonl"
+
+// WatchPrefix starts the batch names of watched directories. Such a batch
+// never finishes, so Open prunes it job by job instead of as a whole.
+const WatchPrefix = "watch:"
+
+// Open compacts the journal, forgetting finished batches last updated more
+// than KeepFinished ago or beyond the newest MaxFinishedBatches.
+var (
+	KeepFinished       = 30 * 24 * time.Hour
+	MaxFinishedBatches = 200
+)
+
+// Job is one conversion and its last known state.
+type Job struct {
+	ID      string            `json:"id"`
+	Batch   string            `json:"batch,omitempty"`
+	Input   string            `json:"input"`
+	Output  string            `json:"output"`
+	Options map[string]string `json:"options,omitempty"`
+
+	// InputSize and InputModTime identify the input, so a different file
+	// with the same name is not mistaken for one already converted.
+	InputSize    int64     `json:"input_size"`
+	InputModTime time.Time `json:"input_mod_time"`
+
+	Status   string    `json:"status"`
+	Attempts int       `json:"attempts"`
+	Error    string    `json:"error,omitempty"`
+	Checksum string    `json:"checksum,omitempty"` // sha256 of the output once done
+	Created  time.Time `json:"created"`
+	Updated  time.Time `json:"updated"`
+}
+
+// Store is an append-only JSON lines journal of job states. Every change
+// appends the job's full state; on open the last line per job wins, so a
+// crash can at worst lose the line being written.
+type Store struct {
+	mu     sync.Mutex
+	path   string
+	jobs   map[string]*Job
+	order  []string
+	latest map[[2]string]string // {batch, input} -> most recently updated job ID
+}
+
+// DefaultPath returns FileName in the goverter config directory.
+func DefaultPath() (string, error) {
+	dir, err := config.Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, FileName), nil
+}
+
+// Open replays the journal at path, which need not exist yet, and rewrites
+// it with one line per job that is still worth keeping.
+func Open(path string) (*Store, error) {
+	s := &Store{path: path, jobs: make(map[string]*Job), latest: make(map[[2]string]string)}
+
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	lines := 0
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		lines++
+		var job Job
+		if err := json.Unmarshal(scanner.Bytes(), &job); err != nil || job.ID == "" {
+			// A torn last line from a crash; earlier lines still count.
+			continue
+		}
+		s.put(job)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read job store %s: %w", path, err)
+	}
+	file.Close()
+
+	if s.prune(time.Now()) || lines > len(s.order) {
+		if err := s.rewrite(); err != nil {
+			return nil, fmt.Errorf("failed to compact job store %s: %w", path, err)
+		}
+	}
+	return s, nil
+}
+
+// OpenDefault opens the journal at DefaultPath.
+func OpenDefault() (*Store, error) {
+	path, err := DefaultPath()
+	if err != nil {
+		return nil, err
+	}
+	return Open(path)
+}
+
+func (s *Store) Path() string {
+	return s.path
+}
+
+func (s *Store) put(job Job) {
+	if _, ok := s.jobs[job.ID]; !ok {
+		s.order = append(s.order, job.ID)
+	}
+	s.jobs[job.ID] = &job
+
+	key := [2]string{job.Batch, job.Input}
+	if latest, ok := s.jobs[s.latest[key]]; !ok || !latest.Updated.After(job.Updated) {
+		s.latest[key] = job.ID
+	}
+}
+
+// prune forgets jobs that no longer matter, and reports whether any went:
+// finished jobs a later one for the same input replaced, and finished
+// batches past KeepFinished or MaxFinishedBatches. In watch batches a
+// finished job goes once it is past KeepFinished and its input is gone.
+func (s *Store) prune(now time.Time) bool {
+	type batch struct {
+		name     string
+		finished bool
+		updated  time.Time
+	}
+	batches := make(map[string]*batch)
+	drop := make(map[string]bool)
+	for _, id := range s.order {
+		job := s.jobs[id]
+		b, ok := batches[job.Batch]
+		if !ok {
+			b = &batch{name: job.Batch, finished: true}
+			batches[job.Batch] = b
+		}
+		if !job.finished() {
+			b.finished = false
+		}
+		if job.Updated.After(b.updated) {
+			b.updated = job.Updated
+		}
+
+		if !job.finished() {
+			continue
+		}
+		if s.latest[[2]string{job.Batch, job.Input}] != id {
+			drop[id] = true
+		} else if strings.HasPrefix(job.Batch, WatchPrefix) && now.Sub(job.Updated) > KeepFinished {
+			if _, err := os.Stat(job.Input); os.IsNotExist(err) {
+				drop[id] = true
+			}
+		}
+	}
+
+	var finished []*batch
+	for _, b := range batches {
+		if b.finished && !strings.HasPrefix(b.name, WatchPrefix) {
+			finished = append(finished, b)
+		}
+	}
+	sort.Slice(finished, func(i, j int) bool { return finished[i].updated.After(finished[j].updated) })
+	expired := make(map[string]bool)
+	for i, b := range finished {
+		if i >= MaxFinishedBatches || now.Sub(b.updated) > KeepFinished {
+			expired[b.name] = true
+		}
+	}
+
+	order := s.order[:0]
+	for _, id := range s.order {
+		if job := s.jobs[id]; drop[id] || expired[job.Batch] {
+			delete(s.jobs, id)
+			if s.latest[[2]string{job.Batch, job.Input}] == id {
+				delete(s.latest, [2]string{job.Batch, job.Input})
+			}
+			continue
+		}
+		order = append(order, id)
+	}
+	pruned := len(order) < len(s.order)
+	s.order = order
+	return pruned
+}
+
+// rewrite replaces the journal with the current state of every job,
+// through a temporary file so a crash leaves either the old or new one.
+func (s *Store) rewrite() error {
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	writer := bufio.NewWriter(tmp)
+	for _, id := range s.order {
+		data, err := json.Marshal(s.jobs[id])
+		if err != nil {
+			tmp.Close()
+			return err
+		}
+		writer.Write(append(data, '\n'))
+	}
+	if err := writer.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
+
+// Add records a new pending job, filling in its ID, input identity and
+// timestamps, and returns it.
+func (s *Store) Add(job Job) (Job, error) {
+	if job.ID == "" {
+		job.ID = NewID()
+	}
+	if stat, err := os.Stat(job.Input); err == nil && job.InputSize == 0 && job.InputModTime.IsZero() {
+		job.InputSize = stat.Size()
+		job.InputModTime = stat.ModTime()
+	}
+	if job.Status == "" {
+		job.Status = StatusPending
+	}
+	now := time.Now()
+	job.Created = now
+	job.Updated = now
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.append(job); err != nil {
+		return Job{}, err
+	}
+	s.put(job)
+	return job, nil
+}
+
+// Start marks the job running and counts the attempt.
+func (s *Store) Start(id string) error {
+	return s.update(id, func(job *Job) {
+		job.Status = StatusRunning
+		job.Attempts++
+		job.Error = ""
+	})
+}
+
+// Finish records the outcome of a job. On success the output's checksum
+// is stored so a later resume can tell whether it is still intact. A job
+// that was never marked running has this attempt counted here.
+func (s *Store) Finish(id string, runErr error) error {
+	var checksum string
+	if runErr == nil {
+		s.mu.Lock()
+		job, ok := s.jobs[id]
+		s.mu.Unlock()
+		if ok {
+			sum, err := Checksum(job.Output)
+			if err != nil {
+				runErr = fmt.Errorf("output unreadable after conversion: %w", err)
+			}
+			checksum = sum
+		}
+	}
+
+	return s.update(id, func(job *Job) {
+		if job.Status != StatusRunning {
+			job.Attempts++
+		}
+		if runErr != nil {
+			job.Status = StatusFailed
+			job.Error = runErr.Error()
+			job.Checksum = ""
+			return
+		}
+		job.Status = StatusDone
+		job.Error = ""
+		job.Checksum = checksum
+	})
+}
+
+// Reset puts a job back to pending so it runs again.
+func (s *Store) Reset(id string) error {
+	return s.update(id, func(job *Job) {
+		job.Status = StatusPending
+		job.Checksum = ""
+	})
+}
+
+func (s *Store) update(id string, fn func(*Job)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, ok := s.jobs[id]
+	if !ok {
+		return fmt.Errorf("no job %s", id)
+	}
+	job := *current
+	fn(&job)
+	job.Updated = time.Now()
+
+	if err := s.append(job); err != nil {
+		return err
+	}
+	s.put(job)
+	return nil
+}
+
+// append writes one journal line and syncs it. The caller holds s.mu.
+func (s *Store) append(job Job) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	if err := utils.EnsureDir(filepath.Dir(s.path)); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(s.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func (s *Store) Get(id string) (Job, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job, ok := s.jobs[id]
+	if !ok {
+		return Job{}, false
+	}
+	return *job, true
+}
+
+// Jobs returns every job in the order they were added, optionally limited
+// to one batch.
+func (s *Store) Jobs(batch string) []Job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var jobs []Job
+	for _, id := range s.order {
+		job := s.jobs[id]
+		if batch == "" || job.Batch == batch {
+			jobs = append(jobs, *job)
+		}
+	}
+	return jobs
+}
+
+// Latest returns the most recently updated job in batch for input, or
+// false if there is none.
+func (s *Store) Latest(batch, input string) (Job, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job, ok := s.jobs[s.latest[[2]string{batch, input}]]
+	if !ok {
+		return Job{}, false
+	}
+	return *job, true
+}
+
+// Resumable returns the jobs of batch, or of every batch if it is empty,
+// that a resume should run again: all but those completed with their
+// output intact and, unless retryFailed, those that failed.
+func (s *Store) Resumable(batch string, retryFailed bool) []Job {
+	var jobs []Job
+	for _, job := range s.Jobs(batch) {
+		if job.Completed() || (job.Status == StatusFailed && !retryFailed) {
+			continue
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs
+}
+
+func (j Job) finished() bool {
+	return j.Status == StatusDone || j.Status == StatusFailed
+}
+
+// Completed reports whether the job is done and its output still matches
+// the recorded checksum.
+func (j Job) Completed() bool {
+	if j.Status != StatusDone || j.Checksum == "" {
+		return false
+	}
+	sum, err := Checksum(j.Output)
+	return err == nil && sum == j.Checksum
+}
+
+// SameInput reports whether the file at the job's input path is still the
+// one the job was created for.
+func (j Job) SameInput(info os.FileInfo) bool {
+	return info.Size() == j.InputSize && info.ModTime().Equal(j.InputModTime)
+}
+
+// Checksum returns the hex sha256 of the file at path.
+func Checksum(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// NewID returns a random job or batch ID.
+func NewID() string {
+	var b [8]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
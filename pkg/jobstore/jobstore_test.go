@@ -0,0 +1,208 @@
+package jobstore
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeJournal writes jobs as journal lines, followed by extra raw text.
+func writeJournal(t *testing.T, path string, jobs []Job, extra string) {
+	t.Helper()
+	var b strings.Builder
+	for _, job := range jobs {
+		data, err := json.Marshal(job)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b.Write(append(data, '\n'))
+	}
+	b.WriteString(extra)
+	if err := os.WriteFile(path, []byte(b.String()), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func journalLines(t *testing.T, path string) int {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Count(string(data), "\n")
+}
+
+func TestOpenReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	now := time.Now()
+	writeJournal(t, path, []Job{
+		{ID: "a", Batch: "b1", Input: "a.mp4", Status: StatusPending, Updated: now},
+		{ID: "b", Batch: "b1", Input: "b.mp4", Status: StatusPending, Updated: now},
+		{ID: "a", Batch: "b1", Input: "a.mp4", Status: StatusRunning, Attempts: 1, Updated: now},
+	}, `{"id": "b", "batch": "b1", "stat`)
+
+	s, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jobs := s.Jobs("")
+	if len(jobs) != 2 || jobs[0].ID != "a" || jobs[1].ID != "b" {
+		t.Fatalf("Jobs = %v, want a and b in the order added", jobs)
+	}
+	if jobs[0].Status != StatusRunning || jobs[0].Attempts != 1 {
+		t.Errorf("job a = %s after %d attempts, want its last line to win", jobs[0].Status, jobs[0].Attempts)
+	}
+	if jobs[1].Status != StatusPending {
+		t.Errorf("job b = %s, want the torn line ignored", jobs[1].Status)
+	}
+	if n := journalLines(t, path); n != 2 {
+		t.Errorf("journal has %d lines after Open, want one per job", n)
+	}
+
+	if err := s.Start("b"); err != nil {
+		t.Fatal(err)
+	}
+	reopened, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job, _ := reopened.Get("b"); job.Status != StatusRunning {
+		t.Errorf("job b = %s after reopening, want the appended state", job.Status)
+	}
+}
+
+func TestOpenPrunes(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, FileName)
+	present := filepath.Join(dir, "present.mp4")
+	if err := os.WriteFile(present, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	old := now.Add(-KeepFinished - time.Hour)
+	watch := WatchPrefix + dir
+	writeJournal(t, path, []Job{
+		{ID: "old-done", Batch: "old", Input: "x.mp4", Status: StatusDone, Updated: old},
+		{ID: "old-pending", Batch: "old-unfinished", Input: "x.mp4", Status: StatusPending, Updated: old},
+		{ID: "recent", Batch: "recent", Input: "x.mp4", Status: StatusFailed, Updated: now},
+		{ID: "replaced", Batch: watch, Input: present, Status: StatusFailed, Updated: now.Add(-time.Hour)},
+		{ID: "retried", Batch: watch, Input: present, Status: StatusDone, Updated: now},
+		{ID: "gone", Batch: watch, Input: filepath.Join(dir, "moved.mp4"), Status: StatusDone, Updated: old},
+		{ID: "kept", Batch: watch, Input: present + "2", Status: StatusDone, Updated: now},
+	}, "")
+
+	s, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, job := range s.Jobs("") {
+		ids = append(ids, job.ID)
+	}
+	if got, want := strings.Join(ids, " "), "old-pending recent retried kept"; got != want {
+		t.Errorf("jobs after Open = %s, want %s", got, want)
+	}
+	if n := journalLines(t, path); n != len(ids) {
+		t.Errorf("journal has %d lines, want %d", n, len(ids))
+	}
+}
+
+func TestOpenPrunesBeyondMaxFinishedBatches(t *testing.T) {
+	defer func(n int) { MaxFinishedBatches = n }(MaxFinishedBatches)
+	MaxFinishedBatches = 2
+
+	path := filepath.Join(t.TempDir(), FileName)
+	now := time.Now()
+	writeJournal(t, path, []Job{
+		{ID: "1", Batch: "b1", Input: "x", Status: StatusDone, Updated: now.Add(-3 * time.Minute)},
+		{ID: "2", Batch: "b2", Input: "x", Status: StatusDone, Updated: now.Add(-2 * time.Minute)},
+		{ID: "3", Batch: "b3", Input: "x", Status: StatusDone, Updated: now.Add(-time.Minute)},
+	}, "")
+
+	s, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.Get("1"); ok || len(s.Jobs("")) != 2 {
+		t.Errorf("jobs = %v, want only the two newest batches", s.Jobs(""))
+	}
+}
+
+func TestLatest(t *testing.T) {
+	s, err := Open(filepath.Join(t.TempDir(), FileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, err := s.Add(Job{Batch: "b", Input: "in.mp4"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := s.Add(Job{Batch: "b", Input: "in.mp4"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Add(Job{Batch: "other", Input: "in.mp4"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if job, ok := s.Latest("b", "in.mp4"); !ok || job.ID != second.ID {
+		t.Errorf("Latest = %s, %v; want the second job %s", job.ID, ok, second.ID)
+	}
+	time.Sleep(time.Millisecond)
+	if err := s.Reset(first.ID); err != nil {
+		t.Fatal(err)
+	}
+	if job, ok := s.Latest("b", "in.mp4"); !ok || job.ID != first.ID {
+		t.Errorf("Latest = %s, %v; want the just updated job %s", job.ID, ok, first.ID)
+	}
+	if _, ok := s.Latest("b", "missing.mp4"); ok {
+		t.Error("Latest found a job for an input never added")
+	}
+}
+
+func TestResumable(t *testing.T) {
+	dir := t.TempDir()
+	s, err := Open(filepath.Join(dir, FileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	add := func(name string) string {
+		output := filepath.Join(dir, name+".out")
+		if err := os.WriteFile(output, []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+		job, err := s.Add(Job{ID: name, Batch: "b", Input: name, Output: output})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return job.ID
+	}
+	add("pending")
+	s.Start(add("running"))
+	s.Finish(add("done"), nil)
+	s.Finish(add("failed"), os.ErrNotExist)
+	s.Finish(add("changed"), nil)
+	if err := os.WriteFile(filepath.Join(dir, "changed.out"), []byte("edited"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		retryFailed bool
+		want        string
+	}{
+		{false, "pending running changed"},
+		{true, "pending running failed changed"},
+	}
+	for _, tt := range tests {
+		var ids []string
+		for _, job := range s.Resumable("b", tt.retryFailed) {
+			ids = append(ids, job.ID)
+		}
+		if got := strings.Join(ids, " "); got != tt.want {
+			t.Errorf("Resumable(retryFailed %v) = %s, want %s", tt.retryFailed, got, tt.want)
+		}
+	}
+}
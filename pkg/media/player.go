@@ -1,21 +1,32 @@
 package media
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"runtime"
 	"strings"
+
+	"goverter/pkg/converter"
+	"goverter/pkg/formats"
+	"goverter/pkg/probe"
 )
 
 type Player struct {
 	defaultPlayer string
+	prober        *probe.Prober
+	converter     *converter.Converter
 }
 
 func NewPlayer() *Player {
 	return &Player{
 		defaultPlayer: getDefaultPlayer(),
+		prober:        probe.New(),
+		converter:     converter.NewConverter(),
 	}
 }
 
@@ -58,31 +69,8 @@ func (p *Player) Play(filePath string) error {
 }
 
 func (p *Player) isMediaFile(filePath string) bool {
-	ext := strings.ToLower(filepath.Ext(filePath))
-
-	videoExts := []string{
-		".mp4", ".avi", ".mkv", ".mov", ".wmv", ".flv", ".webm",
-		".m4v", ".3gp", ".ogv", ".ts", ".mts", ".m2ts",
-	}
-
-	audioExts := []string{
-		".mp3", ".wav", ".flac", ".aac", ".ogg", ".m4a", ".wma",
-		".opus", ".aiff", ".au", ".ra", ".amr", ".ac3",
-	}
-
-	for _, videoExt := range videoExts {
-		if ext == videoExt {
-			return true
-		}
-	}
-
-	for _, audioExt := range audioExts {
-		if ext == audioExt {
-			return true
-		}
-	}
-
-	return false
+	category := formats.Default().Category(filepath.Ext(filePath))
+	return category == formats.CategoryVideo || category == formats.CategoryAudio
 }
 
 func (p *Player) GetSupportedFormats() []string {
@@ -90,17 +78,19 @@ func (p *Player) GetSupportedFormats() []string {
 }
 
 func (p *Player) getVideoFormats() []string {
-	return []string{
-		"mp4", "avi", "mkv", "mov", "wmv", "flv", "webm",
-		"m4v", "3gp", "ogv", "ts", "mts", "m2ts",
-	}
+	return trimDots(formats.Default().Extensions(formats.CategoryVideo))
 }
 
 func (p *Player) getAudioFormats() []string {
-	return []string{
-		"mp3", "wav", "flac", "aac", "ogg", "m4a", "wma",
-		"opus", "aiff", "au", "ra", "amr", "ac3",
+	return trimDots(formats.Default().Extensions(formats.CategoryAudio))
+}
+
+func trimDots(exts []string) []string {
+	names := make([]string, len(exts))
+	for i, ext := range exts {
+		names[i] = strings.TrimPrefix(ext, ".")
 	}
+	return names
 }
 
 func (p *Player) IsPlayable(filePath string) bool {
@@ -118,7 +108,7 @@ func (p *Player) GetPlayerInfo() map[string]string {
 
 type PreviewInfo struct {
 	FilePath   string
-	Thumbnail  string
+	Thumbnail  string // a frame for video, the waveform for audio
 	Duration   string
 	Title      string
 	Artist     string
@@ -150,65 +140,35 @@ func (p *Player) GeneratePreview(filePath string) (*PreviewInfo, error) {
 		if err == nil {
 			info.Thumbnail = thumbnailPath
 		}
+	} else {
+		waveformPath, err := p.generateWaveform(filePath)
+		if err == nil {
+			info.Thumbnail = waveformPath
+		}
 	}
 
 	return info, nil
 }
 
 func (p *Player) isVideoFile(filePath string) bool {
-	ext := strings.ToLower(filepath.Ext(filePath))
-	videoExts := []string{
-		".mp4", ".avi", ".mkv", ".mov", ".wmv", ".flv", ".webm",
-		".m4v", ".3gp", ".ogv", ".ts", ".mts", ".m2ts",
-	}
-
-	for _, videoExt := range videoExts {
-		if ext == videoExt {
-			return true
-		}
-	}
-	return false
+	return formats.Default().Is(filepath.Ext(filePath), formats.CategoryVideo)
 }
 
 func (p *Player) extractMediaInfo(filePath string, info *PreviewInfo) error {
-	cmd := exec.Command("ffprobe", "-v", "quiet", "-print_format", "json", "-show_format", "-show_streams", filePath)
-	output, err := cmd.Output()
+	probed, err := p.prober.Probe(context.Background(), filePath)
 	if err != nil {
 		return err
 	}
 
-	outputStr := string(output)
-
-	if strings.Contains(outputStr, "duration") {
-		parts := strings.Split(outputStr, "duration")
-		if len(parts) > 1 {
-			durationStr := strings.Split(strings.Split(parts[1], ",")[0], "\"")[1]
-			info.Duration = formatDuration(durationStr)
-		}
-	}
-
-	if strings.Contains(outputStr, "TAG:TITLE") {
-		parts := strings.Split(outputStr, "TAG:TITLE")
-		if len(parts) > 1 {
-			info.Title = strings.Split(parts[1], "\"")[1]
-		}
-	}
-
-	if strings.Contains(outputStr, "TAG:ARTIST") {
-		parts := strings.Split(outputStr, "TAG:ARTIST")
-		if len(parts) > 1 {
-			info.Artist = strings.Split(parts[1], "\"")[1]
-		}
+	if probed.Format.Duration > 0 {
+		info.Duration = formatDuration(probed.Format.Duration)
 	}
+	info.Title = probed.Tag("title")
+	info.Artist = probed.Tag("artist")
 
-	if strings.Contains(outputStr, "width") && strings.Contains(outputStr, "height") {
-		widthParts := strings.Split(outputStr, "width")
-		heightParts := strings.Split(outputStr, "height")
-		if len(widthParts) > 1 && len(heightParts) > 1 {
-			width := strings.Split(strings.Split(widthParts[1], ",")[0], "\"")[1]
-			height := strings.Split(strings.Split(heightParts[1], ",")[0], "\"")[1]
-			info.Resolution = fmt.Sprintf("%sx%s", width, height)
-		}
+	if stream := probed.VideoStream(); stream != nil {
+		width, height := stream.DisplaySize()
+		info.Resolution = fmt.Sprintf("%dx%d", width, height)
 	}
 
 	return nil
@@ -226,6 +186,56 @@ func (p *Player) generateThumbnail(filePath string) (string, error) {
 	return thumbnailPath, nil
 }
 
+// generateWaveform draws an audio file's waveform as its thumbnail, in the
+// preview cache rather than next to the user's file.
+func (p *Player) generateWaveform(filePath string) (string, error) {
+	waveformPath, err := previewPath(filePath, "_waveform.png")
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(waveformPath); err == nil {
+		return waveformPath, nil
+	}
+
+	err = p.converter.DrawWaveform(context.Background(), converter.WaveformRequest{
+		InputPath:  filePath,
+		OutputPath: waveformPath,
+		Width:      640,
+		Height:     160,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return waveformPath, nil
+}
+
+// previewPath returns where a preview image of filePath is cached. The name
+// is derived from the file's path, size and modification time, so an edited
+// file gets a fresh preview.
+func previewPath(filePath, suffix string) (string, error) {
+	stat, err := os.Stat(filePath)
+	if err != nil {
+		return "", err
+	}
+	abs, err := filepath.Abs(filePath)
+	if err != nil {
+		return "", err
+	}
+
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	dir = filepath.Join(dir, "goverter", "previews")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+
+	key := sha256.Sum256([]byte(fmt.Sprintf("%s|%d|%d", abs, stat.Size(), stat.ModTime().UnixNano())))
+	return filepath.Join(dir, hex.EncodeToString(key[:8])+suffix), nil
+}
+
 func formatFileSize(bytes int64) string {
 	const unit = 1024
 	if bytes < unit {
@@ -239,10 +249,7 @@ func formatFileSize(bytes int64) string {
 	return fmt.Sprintf("%.1f %cB", float64(bytes)/float64(div), "KMGTPE"[exp])
 }
 
-func formatDuration(seconds string) string {
-	var totalSeconds float64
-	fmt.Sscanf(seconds, "%f", &totalSeconds)
-
+func formatDuration(totalSeconds float64) string {
 	hours := int(totalSeconds) / 3600
 	minutes := (int(totalSeconds) % 3600) / 60
 	secs := int(totalSeconds) % 60
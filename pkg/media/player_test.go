@@ -0,0 +1,43 @@
+package media
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPreviewPath(t *testing.T) {
+	cache := t.TempDir()
+	t.Setenv("XDG_CACHE_HOME", cache)
+	t.Setenv("HOME", cache)
+
+	input := filepath.Join(t.TempDir(), "song.mp3")
+	if err := os.WriteFile(input, []byte("first"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	path, err := previewPath(input, "_waveform.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Dir(path) == filepath.Dir(input) || !strings.HasSuffix(path, "_waveform.png") {
+		t.Errorf("previewPath = %s, want a _waveform.png outside the input's directory", path)
+	}
+	if again, _ := previewPath(input, "_waveform.png"); again != path {
+		t.Errorf("previewPath changed for an unchanged file: %s, then %s", path, again)
+	}
+
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(input, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if edited, _ := previewPath(input, "_waveform.png"); edited == path {
+		t.Errorf("previewPath = %s for both the old and the edited file", edited)
+	}
+
+	if _, err := previewPath(filepath.Join(t.TempDir(), "missing.mp3"), "_waveform.png"); err == nil {
+		t.Error("previewPath of a missing file succeeded")
+	}
+}
@@ -0,0 +1,257 @@
+package pipeline
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	ActionConvert = "convert"
+	ActionResize  = "resize"
+	ActionCrop    = "crop"
+	ActionRotate  = "rotate"
+	ActionFlip    = "flip"
+	ActionFrame   = "frame"
+	ActionGIF     = "gif"
+)
+
+const (
+	// OnErrorContinue abandons the remaining steps of the failed input and
+	// moves on to the next input.
+	OnErrorContinue = "continue"
+	// OnErrorStop aborts the whole job at the first failure.
+	OnErrorStop = "stop"
+)
+
+const (
+	FromPrevious = "previous"
+	FromSource   = "source"
+)
+
+// Job is a job file:
+//
+//	inputs: ["footage/*.mov"]
+//	output_dir: out
+//	on_error: continue
+//	steps:
+//	  - action: convert
+//	    format: mp4
+//	    options: {quality: "75"}
+//	  - action: frame
+//	    at: "00:00:05"
+//	    output: "thumbs/{{.Name}}.jpg"
+//	  - action: gif
+//	    from: source
+//	    fps: 10
+//	    width: 480
+type Job struct {
+	Name      string   `toml:"name" yaml:"name"`
+	Inputs    []string `toml:"inputs" yaml:"inputs"`
+	OutputDir string   `toml:"output_dir" yaml:"output_dir"`
+	OnError   string   `toml:"on_error" yaml:"on_error"`
+	Steps     []Step   `toml:"steps" yaml:"steps"`
+
+	// Dir is the directory relative input globs and output paths resolve
+	// against; Load sets it to the job file's directory.
+	Dir string `toml:"-" yaml:"-"`
+}
+
+// Step is one operation. Each step works on the previous step's output
+// unless From is "source". Output is a text/template for the output path;
+// see NameData for the fields it can use.
+type Step struct {
+	Action  string            `toml:"action" yaml:"action"`
+	From    string            `toml:"from" yaml:"from"`
+	Output  string            `toml:"output" yaml:"output"`
+	Format  string            `toml:"format" yaml:"format"`
+	Options map[string]string `toml:"options" yaml:"options"`
+
+	Width     int     `toml:"width" yaml:"width"`
+	Height    int     `toml:"height" yaml:"height"`
+	X         int     `toml:"x" yaml:"x"`
+	Y         int     `toml:"y" yaml:"y"`
+	Degrees   float64 `toml:"degrees" yaml:"degrees"`
+	Direction string  `toml:"direction" yaml:"direction"` // flip: "horizontal" or "vertical"
+	At        string  `toml:"at" yaml:"at"`               // frame timestamp
+	FPS       int     `toml:"fps" yaml:"fps"`
+	Quality   int     `toml:"quality" yaml:"quality"`
+
+	tmpl *template.Template
+}
+
+// NameData is available to output templates.
+type NameData struct {
+	Name   string // source file name without extension
+	Ext    string // output extension, with the dot
+	Dir    string // directory of the source file
+	Action string
+	Step   int // 1-based step number
+	Index  int // 1-based input number
+}
+
+// Load reads a YAML or TOML job file. Unknown keys are rejected so that
+// typos do not silently fall back to defaults.
+func Load(path string) (*Job, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var job Job
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".toml":
+		meta, err := toml.Decode(string(data), &job)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+		if undecoded := meta.Undecoded(); len(undecoded) > 0 {
+			return nil, fmt.Errorf("%s: unknown key %q", path, undecoded[0].String())
+		}
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(&job); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported job file %s: expected .yaml, .yml or .toml", path)
+	}
+
+	job.Dir = filepath.Dir(path)
+	if err := job.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &job, nil
+}
+
+// Validate checks the job's own settings and fills in defaults. It reports
+// every problem it finds, not just the first.
+func (j *Job) Validate() error {
+	var errs []error
+
+	if len(j.Inputs) == 0 {
+		errs = append(errs, errors.New("no inputs"))
+	}
+	for _, pattern := range j.Inputs {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			errs = append(errs, fmt.Errorf("input %q: %w", pattern, err))
+		}
+	}
+
+	switch j.OnError {
+	case "":
+		j.OnError = OnErrorContinue
+	case OnErrorContinue, OnErrorStop:
+	default:
+		errs = append(errs, fmt.Errorf("on_error must be %q or %q, got %q", OnErrorContinue, OnErrorStop, j.OnError))
+	}
+
+	if len(j.Steps) == 0 {
+		errs = append(errs, errors.New("no steps"))
+	}
+	for i := range j.Steps {
+		if err := j.Steps[i].validate(); err != nil {
+			errs = append(errs, fmt.Errorf("step %d (%s): %w", i+1, j.Steps[i].Action, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (s *Step) validate() error {
+	var errs []error
+
+	switch s.From {
+	case "":
+		s.From = FromPrevious
+	case FromPrevious, FromSource:
+	default:
+		errs = append(errs, fmt.Errorf("from must be %q or %q", FromPrevious, FromSource))
+	}
+
+	switch s.Action {
+	case ActionConvert:
+		if s.Format == "" {
+			errs = append(errs, errors.New("format is required"))
+		}
+	case ActionResize:
+		if s.Width <= 0 && s.Height <= 0 {
+			errs = append(errs, errors.New("width or height is required"))
+		}
+	case ActionCrop:
+		if s.Width <= 0 || s.Height <= 0 {
+			errs = append(errs, errors.New("width and height are required"))
+		}
+	case ActionRotate:
+		if s.Degrees == 0 {
+			errs = append(errs, errors.New("degrees is required"))
+		}
+	case ActionFlip:
+		if s.Direction != "horizontal" && s.Direction != "vertical" {
+			errs = append(errs, errors.New(`direction must be "horizontal" or "vertical"`))
+		}
+	case ActionFrame:
+		if s.At == "" {
+			errs = append(errs, errors.New("at is required"))
+		}
+	case ActionGIF:
+	case "":
+		errs = append(errs, errors.New("action is required"))
+	default:
+		errs = append(errs, fmt.Errorf("unknown action %q", s.Action))
+	}
+
+	if s.Quality < 0 || s.Quality > 100 {
+		errs = append(errs, errors.New("quality must be between 1 and 100"))
+	}
+
+	output := s.Output
+	if output == "" {
+		output = s.defaultOutput()
+	}
+	tmpl, err := template.New(s.Action).Option("missingkey=error").Parse(output)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("output template: %w", err))
+	}
+	s.tmpl = tmpl
+
+	return errors.Join(errs...)
+}
+
+func (s *Step) defaultOutput() string {
+	if s.Action == ActionConvert {
+		return "{{.Name}}{{.Ext}}"
+	}
+	return "{{.Name}}_{{.Action}}{{.Ext}}"
+}
+
+// outputExt is the extension the step writes, given its input extension.
+func (s *Step) outputExt(inputExt string) string {
+	if s.Format != "" {
+		return "." + strings.TrimPrefix(strings.ToLower(s.Format), ".")
+	}
+	switch s.Action {
+	case ActionFrame:
+		return ".jpg"
+	case ActionGIF:
+		return ".gif"
+	default:
+		return inputExt
+	}
+}
+
+func (s *Step) outputName(data NameData) (string, error) {
+	var b strings.Builder
+	if err := s.tmpl.Execute(&b, data); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
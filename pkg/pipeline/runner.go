@@ -0,0 +1,359 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"goverter/pkg/converter"
+	"goverter/pkg/formats"
+	"goverter/pkg/image"
+	"goverter/pkg/utils"
+	"goverter/pkg/video"
+)
+
+// Plan is a job expanded against the files its inputs match.
+type Plan struct {
+	Tasks []Task
+}
+
+// Task is the chain of steps for one source file.
+type Task struct {
+	Source string
+	Steps  []PlannedStep
+}
+
+// PlannedStep is a step with its input and output paths resolved.
+type PlannedStep struct {
+	Step   *Step
+	Number int // 1-based
+	Input  string
+	Output string
+}
+
+// String describes the step for progress output.
+func (p PlannedStep) String() string {
+	s := p.Step
+	var params []string
+	switch s.Action {
+	case ActionResize:
+		params = append(params, fmt.Sprintf("%dx%d", s.Width, s.Height))
+	case ActionCrop:
+		params = append(params, fmt.Sprintf("%dx%d+%d+%d", s.Width, s.Height, s.X, s.Y))
+	case ActionRotate:
+		params = append(params, strconv.FormatFloat(s.Degrees, 'f', -1, 64)+"°")
+	case ActionFlip:
+		params = append(params, s.Direction)
+	case ActionFrame:
+		params = append(params, "at "+s.At)
+	}
+	if s.Quality > 0 {
+		params = append(params, fmt.Sprintf("quality=%d", s.Quality))
+	}
+	keys := make([]string, 0, len(s.Options))
+	for key := range s.Options {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		params = append(params, key+"="+s.Options[key])
+	}
+
+	line := fmt.Sprintf("%s %s -> %s", s.Action, p.Input, p.Output)
+	if len(params) > 0 {
+		line += " (" + strings.Join(params, ", ") + ")"
+	}
+	return line
+}
+
+// StepResult reports one executed or skipped step.
+type StepResult struct {
+	Task     *Task
+	Step     PlannedStep
+	Err      error
+	Skipped  bool
+	Duration time.Duration
+}
+
+type Runner struct {
+	Converter *converter.Converter
+	Images    *image.Processor
+	Frames    *video.FrameExtractor
+}
+
+func NewRunner() *Runner {
+	return &Runner{
+		Converter: converter.NewConverter(),
+		Images:    image.NewProcessor(),
+		Frames:    video.NewFrameExtractor(),
+	}
+}
+
+// Plan expands the job's input globs and resolves every step's paths. It
+// also checks that each step can handle the file it will receive, so that
+// nothing runs unless the whole job is valid.
+func (r *Runner) Plan(job *Job) (*Plan, error) {
+	sources, err := job.sources()
+	if err != nil {
+		return nil, err
+	}
+	if len(sources) == 0 {
+		return nil, fmt.Errorf("inputs %s match no files", strings.Join(job.Inputs, ", "))
+	}
+
+	plan := &Plan{}
+	var errs []error
+	for i, source := range sources {
+		task := Task{Source: source}
+		previous := source
+
+		for n := range job.Steps {
+			step := &job.Steps[n]
+			input := previous
+			if step.From == FromSource {
+				input = source
+			}
+
+			inputExt := strings.ToLower(filepath.Ext(input))
+			if err := r.check(step, inputExt); err != nil {
+				errs = append(errs, fmt.Errorf("%s: step %d (%s): %w", source, n+1, step.Action, err))
+			}
+
+			name, err := step.outputName(NameData{
+				Name:   strings.TrimSuffix(filepath.Base(source), filepath.Ext(source)),
+				Ext:    step.outputExt(inputExt),
+				Dir:    filepath.Dir(source),
+				Action: step.Action,
+				Step:   n + 1,
+				Index:  i + 1,
+			})
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: step %d (%s): output template: %w", source, n+1, step.Action, err))
+				continue
+			}
+			output := job.resolveOutput(name, source)
+			if output == input || output == source {
+				errs = append(errs, fmt.Errorf("%s: step %d (%s) would overwrite its input", source, n+1, step.Action))
+			}
+
+			task.Steps = append(task.Steps, PlannedStep{Step: step, Number: n + 1, Input: input, Output: output})
+			previous = output
+		}
+		plan.Tasks = append(plan.Tasks, task)
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+// check reports whether step accepts an input with extension ext.
+func (r *Runner) check(step *Step, ext string) error {
+	registry := formats.Default()
+	switch step.Action {
+	case ActionConvert:
+		plan, err := r.Converter.Plan(ext, step.outputExt(ext))
+		if err != nil {
+			return err
+		}
+		return plan.ValidateOptions(step.convertOptions())
+	case ActionResize, ActionCrop, ActionRotate, ActionFlip:
+		if !registry.Is(ext, formats.CategoryImage) {
+			return fmt.Errorf("needs an image input, got %s", ext)
+		}
+	case ActionFrame:
+		if !registry.Is(ext, formats.CategoryVideo) {
+			return fmt.Errorf("needs a video input, got %s", ext)
+		}
+	case ActionGIF:
+		if !registry.Is(ext, formats.CategoryVideo) {
+			return fmt.Errorf("needs a video input, got %s", ext)
+		}
+		outputExt := step.outputExt(ext)
+		if !converter.IsAnimatedFormat(outputExt) {
+			return fmt.Errorf("writes an animation, so format must be gif, webp or apng, got %s", outputExt)
+		}
+		plan, err := r.Converter.Plan(ext, outputExt)
+		if err != nil {
+			return err
+		}
+		return plan.ValidateOptions(step.convertOptions())
+	}
+	return nil
+}
+
+// Run executes plan, calling report after every step. With on_error
+// "continue" a failure skips the rest of that source's steps; with "stop"
+// it skips everything left. Run returns an error if any step failed.
+func (r *Runner) Run(ctx context.Context, job *Job, plan *Plan, report func(StepResult)) error {
+	failed := 0
+	stopped := false
+
+	for t := range plan.Tasks {
+		task := &plan.Tasks[t]
+		abandoned := false
+
+		for _, step := range task.Steps {
+			if stopped || abandoned || ctx.Err() != nil {
+				report(StepResult{Task: task, Step: step, Skipped: true})
+				continue
+			}
+
+			start := time.Now()
+			err := r.runStep(ctx, step)
+			report(StepResult{Task: task, Step: step, Err: err, Duration: time.Since(start)})
+
+			if err != nil {
+				failed++
+				abandoned = true
+				stopped = job.OnError == OnErrorStop
+			}
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d step(s) failed", failed)
+	}
+	return nil
+}
+
+// Commands returns the external commands planned would run, without
+// running them. Analysis passes such as loudness measurement are listed
+// too, and the values they would measure appear as placeholders like
+// <I> in the commands after them. Inputs that exist are still probed with
+// ffprobe. Image steps are done in-process and have no commands.
+func (r *Runner) Commands(ctx context.Context, planned PlannedStep) ([][]string, error) {
+	switch planned.Step.Action {
+	case ActionResize, ActionCrop, ActionRotate, ActionFlip:
+		return nil, nil
+	}
+	ctx, log := utils.WithDryRun(ctx)
+	if err := r.runStep(ctx, planned); err != nil {
+		return nil, err
+	}
+	return log.Commands(), nil
+}
+
+func (r *Runner) runStep(ctx context.Context, planned PlannedStep) error {
+	if utils.DryRun(ctx) == nil {
+		if err := utils.EnsureDir(filepath.Dir(planned.Output)); err != nil {
+			return err
+		}
+	}
+
+	s := planned.Step
+	quality := s.Quality
+	if quality == 0 {
+		quality = 95
+	}
+
+	switch s.Action {
+	case ActionConvert, ActionGIF:
+		return r.Converter.ConvertContext(ctx, converter.ConversionRequest{
+			InputPath:  planned.Input,
+			OutputPath: planned.Output,
+			Options:    s.convertOptions(),
+		})
+	case ActionResize:
+		return r.Images.ResizeContext(ctx, image.ResizeRequest{
+			InputPath:  planned.Input,
+			OutputPath: planned.Output,
+			Width:      s.Width,
+			Height:     s.Height,
+			Quality:    quality,
+		})
+	case ActionCrop:
+		return r.Images.CropContext(ctx, image.CropRequest{
+			InputPath:  planned.Input,
+			OutputPath: planned.Output,
+			X:          s.X,
+			Y:          s.Y,
+			Width:      s.Width,
+			Height:     s.Height,
+			Quality:    quality,
+		})
+	case ActionRotate:
+		return r.Images.RotateContext(ctx, planned.Input, planned.Output, s.Degrees, quality)
+	case ActionFlip:
+		return r.Images.FlipContext(ctx, planned.Input, planned.Output, s.Direction == "horizontal", quality)
+	case ActionFrame:
+		return r.Frames.ExtractFrameContext(ctx, video.ExtractRequest{
+			VideoPath:  planned.Input,
+			OutputPath: planned.Output,
+			Timestamp:  s.At,
+			Width:      s.Width,
+			Height:     s.Height,
+		})
+	default:
+		return fmt.Errorf("unknown action %q", s.Action)
+	}
+}
+
+// convertOptions are the converter options of a convert or gif step: its
+// options with quality, and for a gif its fps and width, set on top.
+func (s *Step) convertOptions() map[string]string {
+	options := make(map[string]string, len(s.Options)+3)
+	for key, value := range s.Options {
+		options[key] = value
+	}
+	if s.Quality > 0 {
+		options["quality"] = strconv.Itoa(s.Quality)
+	}
+	if s.Action == ActionGIF {
+		if s.FPS > 0 {
+			options["fps"] = strconv.Itoa(s.FPS)
+		}
+		if s.Width > 0 {
+			options["width"] = strconv.Itoa(s.Width)
+		}
+	}
+	return options
+}
+
+// sources expands the input globs relative to the job directory, in order
+// and without duplicates.
+func (j *Job) sources() ([]string, error) {
+	seen := make(map[string]bool)
+	var sources []string
+	for _, pattern := range j.Inputs {
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(j.Dir, pattern)
+		}
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("input %q: %w", pattern, err)
+		}
+		for _, match := range matches {
+			if !seen[match] {
+				seen[match] = true
+				sources = append(sources, match)
+			}
+		}
+	}
+	return sources, nil
+}
+
+// resolveOutput places a relative output name under output_dir, or next to
+// the source when the job has none.
+func (j *Job) resolveOutput(name, source string) string {
+	if filepath.IsAbs(name) {
+		return filepath.Clean(name)
+	}
+	if j.OutputDir == "" {
+		return filepath.Join(filepath.Dir(source), name)
+	}
+	dir := j.OutputDir
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(j.Dir, dir)
+	}
+	return filepath.Join(dir, name)
+}
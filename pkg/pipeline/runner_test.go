@@ -0,0 +1,101 @@
+package pipeline
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"goverter/pkg/converter"
+)
+
+// newTestRunner returns a runner whose tools are scripts that fail if run,
+// so a dry run can be checked not to execute anything.
+func newTestRunner(t *testing.T) *Runner {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake tools are shell scripts")
+	}
+	bin := t.TempDir()
+	for _, tool := range []string{"ffmpeg", "ffprobe"} {
+		if err := os.WriteFile(filepath.Join(bin, tool), []byte("#!/bin/sh\nexit 1\n"), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("PATH", bin)
+	r := NewRunner()
+	r.Converter = converter.NewConverterWithRegistry(converter.NewRegistry(converter.BuiltinBackends()...))
+	return r
+}
+
+func newTestJob(t *testing.T, steps ...Step) *Job {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "talk.mp4"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	job := &Job{Inputs: []string{"*.mp4"}, OutputDir: "out", Steps: steps, Dir: dir}
+	if err := job.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	return job
+}
+
+func TestPlanChecksGIFSteps(t *testing.T) {
+	r := newTestRunner(t)
+	tests := []struct {
+		step    Step
+		wantErr string
+	}{
+		{Step{Action: ActionGIF, FPS: 12, Width: 480, Quality: 80}, ""},
+		{Step{Action: ActionGIF, Format: "webp", Options: map[string]string{"loop": "2"}}, ""},
+		{Step{Action: ActionGIF, Format: "mp4"}, "format must be gif, webp or apng"},
+		{Step{Action: ActionGIF, Options: map[string]string{"colours": "64"}}, "unknown option"},
+		{Step{Action: ActionGIF, Options: map[string]string{"dither": "dots"}}, "dither"},
+		{Step{Action: ActionGIF, FPS: 500}, "fps"},
+	}
+	for _, tt := range tests {
+		_, err := r.Plan(newTestJob(t, tt.step))
+		switch {
+		case tt.wantErr == "" && err != nil:
+			t.Errorf("%+v: Plan = %v, want nil", tt.step, err)
+		case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+			t.Errorf("%+v: Plan = %v, want error containing %q", tt.step, err, tt.wantErr)
+		}
+	}
+}
+
+func TestCommandsTwoStepDryRun(t *testing.T) {
+	r := newTestRunner(t)
+	job := newTestJob(t,
+		Step{Action: ActionConvert, Format: "mp3"},
+		Step{Action: ActionConvert, Format: "ogg", Options: map[string]string{"loudness": "-16"}},
+	)
+	plan, err := r.Plan(job)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var lines []string
+	for _, step := range plan.Tasks[0].Steps {
+		commands, err := r.Commands(context.Background(), step)
+		if err != nil {
+			t.Fatalf("step %d: %v", step.Number, err)
+		}
+		for _, argv := range commands {
+			lines = append(lines, strings.Join(argv, " "))
+		}
+	}
+
+	if len(lines) != 3 {
+		t.Fatalf("commands:\n%s\nwant the encode, the measurement and the normalising encode", strings.Join(lines, "\n"))
+	}
+	if !strings.Contains(lines[1], "print_format=json") || !strings.Contains(lines[2], "measured_I=<I>") {
+		t.Errorf("second step:\n%s\n%s\nwant a measurement pass and a placeholder for its result", lines[1], lines[2])
+	}
+	if _, err := os.Stat(filepath.Join(job.Dir, "out")); !os.IsNotExist(err) {
+		t.Errorf("the dry run created the output directory")
+	}
+}
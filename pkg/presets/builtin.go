@@ -0,0 +1,38 @@
+package presets
+
+// Builtin is the preset table goverter ships with. A presets file in the
+// config directory can add to or override it.
+var Builtin = []Preset{
+	{
+		Name:        "web-mp4-720p",
+		Description: "720p H.264 MP4 for web players",
+		Format:      "mp4",
+		Options:     map[string]string{"quality": "75", "height": "720", "audio_bitrate": "128k"},
+	},
+	{
+		Name:        "discord-8mb",
+		Description: "720p MP4 encoded in two passes to fit Discord's 8 MB limit",
+		Format:      "mp4",
+		Options:     map[string]string{"height": "720", "target_size": "8MB", "audio_bitrate": "96k"},
+	},
+	{
+		Name:        "podcast-mp3-mono",
+		Description: "96 kbit/s mono MP3 at -16 LUFS for spoken word",
+		Format:      "mp3",
+		Options:     map[string]string{"bitrate": "96k", "channels": "1", "sample_rate": "44100", "loudness": "-16"},
+	},
+	{
+		Name:        "lossless-flac",
+		Description: "Lossless FLAC at the source sample rate",
+		Format:      "flac",
+	},
+	{
+		Name:        "thumbnail-jpg",
+		Description: "JPEG fitting in 320x320; one frame for videos",
+		Format:      "jpg",
+		Options: map[string]string{
+			"quality": "85", "width": "320", "height": "320",
+			"columns": "1", "rows": "1", "tile_width": "320",
+		},
+	},
+}
@@ -0,0 +1,200 @@
+package presets
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+
+	"goverter/internal/config"
+	"goverter/pkg/converter"
+	"goverter/pkg/formats"
+)
+
+// Preset is a named output format with the conversion options to use for
+// it.
+type Preset struct {
+	Name        string            `toml:"name" yaml:"name" json:"name"`
+	Description string            `toml:"description" yaml:"description" json:"description,omitempty"`
+	Format      string            `toml:"format" yaml:"format" json:"format"`
+	Options     map[string]string `toml:"options" yaml:"options" json:"options,omitempty"`
+}
+
+// Ext returns the preset's output extension with the leading dot.
+func (p Preset) Ext() string {
+	return formats.NormalizeExt(p.Format)
+}
+
+// Merge returns the preset's options with overrides applied on top.
+func (p Preset) Merge(overrides map[string]string) map[string]string {
+	options := make(map[string]string, len(p.Options)+len(overrides))
+	for key, value := range p.Options {
+		options[key] = value
+	}
+	for key, value := range overrides {
+		options[key] = value
+	}
+	return options
+}
+
+// Registry indexes presets by name. Names are matched case-insensitively.
+type Registry struct {
+	mu      sync.RWMutex
+	presets map[string]Preset
+}
+
+func NewRegistry(presets ...Preset) *Registry {
+	r := &Registry{presets: make(map[string]Preset)}
+	for _, preset := range presets {
+		r.Add(preset)
+	}
+	return r
+}
+
+// Add registers preset, replacing any preset with the same name.
+func (r *Registry) Add(preset Preset) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.presets[strings.ToLower(preset.Name)] = preset
+}
+
+func (r *Registry) Lookup(name string) (Preset, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	preset, ok := r.presets[strings.ToLower(name)]
+	return preset, ok
+}
+
+// Get is Lookup with an error that lists the known presets.
+func (r *Registry) Get(name string) (Preset, error) {
+	if preset, ok := r.Lookup(name); ok {
+		return preset, nil
+	}
+	return Preset{}, fmt.Errorf("unknown preset %q (available: %s)", name, strings.Join(r.Names(), ", "))
+}
+
+// Presets returns every preset sorted by name.
+func (r *Registry) Presets() []Preset {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	presets := make([]Preset, 0, len(r.presets))
+	for _, preset := range r.presets {
+		presets = append(presets, preset)
+	}
+	sort.Slice(presets, func(i, j int) bool {
+		return presets[i].Name < presets[j].Name
+	})
+	return presets
+}
+
+func (r *Registry) Names() []string {
+	presets := r.Presets()
+	names := make([]string, len(presets))
+	for i, preset := range presets {
+		names[i] = preset.Name
+	}
+	return names
+}
+
+// ConfigFileNames are looked up, in order, in the goverter config directory.
+var ConfigFileNames = []string{"presets.toml", "presets.yaml", "presets.yml"}
+
+// configFile is the layout of a presets file:
+//
+//	[[presets]]
+//	name = "archive-mkv"
+//	description = "High quality MKV for archiving"
+//	format = "mkv"
+//	options = { quality = "18" }
+type configFile struct {
+	Presets []Preset `toml:"presets" yaml:"presets"`
+}
+
+// LoadFile adds the presets in a TOML or YAML file to r. A preset whose name
+// is already registered replaces it. Nothing is added unless every preset
+// names an output format and options valid for it.
+func (r *Registry) LoadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var file configFile
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".toml":
+		err = toml.Unmarshal(data, &file)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &file)
+	default:
+		return fmt.Errorf("unsupported presets file %s: expected .toml, .yaml or .yml", path)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	for i, preset := range file.Presets {
+		if preset.Name == "" || preset.Format == "" {
+			return fmt.Errorf("%s: preset #%d needs both name and format", path, i+1)
+		}
+		if format, ok := formats.Default().Lookup(preset.Format); !ok || !format.Write {
+			return fmt.Errorf("%s: preset %q: %s is not an output format", path, preset.Name, preset.Format)
+		}
+		if err := converter.ValidateOptions(preset.Ext(), preset.Options); err != nil {
+			return fmt.Errorf("%s: preset %q: %w", path, preset.Name, err)
+		}
+	}
+	for _, preset := range file.Presets {
+		r.Add(preset)
+	}
+	return nil
+}
+
+// ConfigPath returns the presets file in the config directory, or "" if
+// there is none.
+func ConfigPath() string {
+	dir, err := config.Dir()
+	if err != nil {
+		return ""
+	}
+	for _, name := range ConfigFileNames {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+var (
+	defaultOnce     sync.Once
+	defaultRegistry *Registry
+	defaultErr      error
+)
+
+// Default returns the shared registry: the builtin presets extended by the
+// user's presets file, if any. If that file is invalid the builtin presets
+// are used on their own and the problem is reported by DefaultLoadError.
+func Default() *Registry {
+	defaultOnce.Do(func() {
+		defaultRegistry = NewRegistry(Builtin...)
+		if path := ConfigPath(); path != "" {
+			user := NewRegistry(defaultRegistry.Presets()...)
+			if defaultErr = user.LoadFile(path); defaultErr == nil {
+				defaultRegistry = user
+			}
+		}
+	})
+	return defaultRegistry
+}
+
+// DefaultLoadError returns the error from loading the user's presets file.
+func DefaultLoadError() error {
+	Default()
+	return defaultErr
+}
@@ -0,0 +1,48 @@
+package presets
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"goverter/pkg/converter"
+)
+
+func TestBuiltinPresetsAreValid(t *testing.T) {
+	for _, preset := range Builtin {
+		if err := converter.ValidateOptions(preset.Ext(), preset.Options); err != nil {
+			t.Errorf("preset %s: %v", preset.Name, err)
+		}
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{"valid", "[[presets]]\nname = \"small\"\nformat = \"mp4\"\noptions = { quality = \"60\", width = \"640\" }\n", ""},
+		{"no format", "[[presets]]\nname = \"small\"\n", "needs both name and format"},
+		{"not writable", "[[presets]]\nname = \"small\"\nformat = \"3gp\"\n", "not an output format"},
+		{"unknown option", "[[presets]]\nname = \"small\"\nformat = \"mp4\"\noptions = { qualty = \"60\" }\n", `preset "small": unknown option`},
+		{"bad value", "[[presets]]\nname = \"small\"\nformat = \"mp3\"\noptions = { bitrate = \"fast\" }\n", `preset "small"`},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(t.TempDir(), "presets.toml")
+		if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		r := NewRegistry()
+		err := r.LoadFile(path)
+		switch {
+		case tt.wantErr == "" && err != nil:
+			t.Errorf("%s: LoadFile = %v, want nil", tt.name, err)
+		case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr) || !strings.Contains(err.Error(), path)):
+			t.Errorf("%s: LoadFile = %v, want error naming the file and containing %q", tt.name, err, tt.wantErr)
+		case tt.wantErr != "" && len(r.Names()) != 0:
+			t.Errorf("%s: presets %v were added from an invalid file", tt.name, r.Names())
+		}
+	}
+}
@@ -0,0 +1,313 @@
+package probe
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"goverter/pkg/utils"
+)
+
+const (
+	CodecTypeVideo    = "video"
+	CodecTypeAudio    = "audio"
+	CodecTypeSubtitle = "subtitle"
+)
+
+// Info is the ffprobe description of a media file.
+type Info struct {
+	Format   Format    `json:"format"`
+	Streams  []Stream  `json:"streams,omitempty"`
+	Chapters []Chapter `json:"chapters,omitempty"`
+}
+
+type Format struct {
+	Filename   string            `json:"filename,omitempty"`
+	Name       string            `json:"name,omitempty"` // ffprobe's format_name, e.g. "mov,mp4,m4a,3gp,3g2,mj2"
+	LongName   string            `json:"long_name,omitempty"`
+	Duration   float64           `json:"duration,omitempty"` // seconds
+	Size       int64             `json:"size,omitempty"`     // bytes
+	BitRate    int64             `json:"bit_rate,omitempty"` // bits per second
+	NumStreams int               `json:"nb_streams,omitempty"`
+	Tags       map[string]string `json:"tags,omitempty"`
+}
+
+type Stream struct {
+	Index         int               `json:"index"`
+	CodecType     string            `json:"codec_type,omitempty"` // "video", "audio", "subtitle", "data"
+	CodecName     string            `json:"codec_name,omitempty"`
+	CodecLongName string            `json:"codec_long_name,omitempty"`
+	Profile       string            `json:"profile,omitempty"`
+	PixelFormat   string            `json:"pix_fmt,omitempty"`
+	Width         int               `json:"width,omitempty"`
+	Height        int               `json:"height,omitempty"`
+	FrameRate     float64           `json:"frame_rate,omitempty"` // average frames per second, 0 if unknown
+	SampleRate    int               `json:"sample_rate,omitempty"`
+	Channels      int               `json:"channels,omitempty"`
+	ChannelLayout string            `json:"channel_layout,omitempty"`
+	BitRate       int64             `json:"bit_rate,omitempty"`
+	Duration      float64           `json:"duration,omitempty"`
+	Rotation      int               `json:"rotation,omitempty"` // degrees clockwise, in [0, 360)
+	AttachedPic   bool              `json:"attached_pic,omitempty"`
+	Tags          map[string]string `json:"tags,omitempty"`
+}
+
+type Chapter struct {
+	ID    int64             `json:"id"`
+	Start float64           `json:"start,omitempty"` // seconds
+	End   float64           `json:"end,omitempty"`
+	Title string            `json:"title,omitempty"`
+	Tags  map[string]string `json:"tags,omitempty"`
+}
+
+// Prober runs ffprobe.
+type Prober struct {
+	path string
+}
+
+// New returns a Prober using the ffprobe found on PATH.
+func New() *Prober {
+	path, _ := exec.LookPath("ffprobe")
+	return &Prober{path: path}
+}
+
+func (p *Prober) Available() bool {
+	return p.path != ""
+}
+
+// Probe describes path with ffprobe's format, streams and chapters.
+func (p *Prober) Probe(ctx context.Context, path string) (*Info, error) {
+	if p.path == "" {
+		return nil, fmt.Errorf("ffprobe not found. Please install FFmpeg for media information")
+	}
+
+	cmd := utils.CommandContext(ctx, p.path, "-v", "error", "-print_format", "json",
+		"-show_format", "-show_streams", "-show_chapters", path)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("ffprobe failed on %s: %s", path, msg)
+		}
+		return nil, fmt.Errorf("ffprobe failed on %s: %w", path, err)
+	}
+
+	return Parse(output)
+}
+
+// Probe describes path using the ffprobe found on PATH.
+func Probe(ctx context.Context, path string) (*Info, error) {
+	return New().Probe(ctx, path)
+}
+
+// Parse decodes the JSON written by ffprobe -print_format json.
+func Parse(data []byte) (*Info, error) {
+	var raw rawOutput
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse ffprobe output: %w", err)
+	}
+
+	info := &Info{
+		Format: Format{
+			Filename:   raw.Format.Filename,
+			Name:       raw.Format.FormatName,
+			LongName:   raw.Format.FormatLongName,
+			Duration:   parseFloat(raw.Format.Duration),
+			Size:       parseInt(raw.Format.Size),
+			BitRate:    parseInt(raw.Format.BitRate),
+			NumStreams: raw.Format.NbStreams,
+			Tags:       raw.Format.Tags,
+		},
+	}
+
+	for _, s := range raw.Streams {
+		info.Streams = append(info.Streams, Stream{
+			Index:         s.Index,
+			CodecType:     s.CodecType,
+			CodecName:     s.CodecName,
+			CodecLongName: s.CodecLongName,
+			Profile:       s.Profile,
+			PixelFormat:   s.PixFmt,
+			Width:         s.Width,
+			Height:        s.Height,
+			FrameRate:     parseRate(s.AvgFrameRate, s.RFrameRate),
+			SampleRate:    int(parseInt(s.SampleRate)),
+			Channels:      s.Channels,
+			ChannelLayout: s.ChannelLayout,
+			BitRate:       parseInt(s.BitRate),
+			Duration:      parseFloat(s.Duration),
+			Rotation:      s.rotation(),
+			AttachedPic:   s.Disposition["attached_pic"] == 1,
+			Tags:          s.Tags,
+		})
+	}
+
+	for _, c := range raw.Chapters {
+		info.Chapters = append(info.Chapters, Chapter{
+			ID:    c.ID,
+			Start: parseFloat(c.StartTime),
+			End:   parseFloat(c.EndTime),
+			Title: lookupTag(c.Tags, "title"),
+			Tags:  c.Tags,
+		})
+	}
+
+	return info, nil
+}
+
+// VideoStream returns the first video stream that is not cover art, or nil.
+func (i *Info) VideoStream() *Stream {
+	for n := range i.Streams {
+		if s := &i.Streams[n]; s.CodecType == CodecTypeVideo && !s.AttachedPic {
+			return s
+		}
+	}
+	return nil
+}
+
+// AudioStream returns the first audio stream, or nil.
+func (i *Info) AudioStream() *Stream {
+	for n := range i.Streams {
+		if s := &i.Streams[n]; s.CodecType == CodecTypeAudio {
+			return s
+		}
+	}
+	return nil
+}
+
+// Tag returns a container tag, matching key case-insensitively since
+// formats disagree on case ("title" in MP4, "TITLE" in Matroska).
+func (i *Info) Tag(key string) string {
+	return lookupTag(i.Format.Tags, key)
+}
+
+// DisplaySize is the frame size after applying the rotation.
+func (s *Stream) DisplaySize() (width, height int) {
+	if s.Rotation == 90 || s.Rotation == 270 {
+		return s.Height, s.Width
+	}
+	return s.Width, s.Height
+}
+
+func (s *Stream) Tag(key string) string {
+	return lookupTag(s.Tags, key)
+}
+
+type rawOutput struct {
+	Format struct {
+		Filename       string            `json:"filename"`
+		FormatName     string            `json:"format_name"`
+		FormatLongName string            `json:"format_long_name"`
+		Duration       string            `json:"duration"`
+		Size           string            `json:"size"`
+		BitRate        string            `json:"bit_rate"`
+		NbStreams      int               `json:"nb_streams"`
+		Tags           map[string]string `json:"tags"`
+	} `json:"format"`
+	Streams  []rawStream `json:"streams"`
+	Chapters []struct {
+		ID        int64             `json:"id"`
+		StartTime string            `json:"start_time"`
+		EndTime   string            `json:"end_time"`
+		Tags      map[string]string `json:"tags"`
+	} `json:"chapters"`
+}
+
+type rawStream struct {
+	Index         int               `json:"index"`
+	CodecType     string            `json:"codec_type"`
+	CodecName     string            `json:"codec_name"`
+	CodecLongName string            `json:"codec_long_name"`
+	Profile       string            `json:"profile"`
+	PixFmt        string            `json:"pix_fmt"`
+	Width         int               `json:"width"`
+	Height        int               `json:"height"`
+	AvgFrameRate  string            `json:"avg_frame_rate"`
+	RFrameRate    string            `json:"r_frame_rate"`
+	SampleRate    string            `json:"sample_rate"`
+	Channels      int               `json:"channels"`
+	ChannelLayout string            `json:"channel_layout"`
+	BitRate       string            `json:"bit_rate"`
+	Duration      string            `json:"duration"`
+	Disposition   map[string]int    `json:"disposition"`
+	Tags          map[string]string `json:"tags"`
+	SideDataList  []struct {
+		Rotation float64 `json:"rotation"`
+	} `json:"side_data_list"`
+}
+
+// rotation reads the display matrix side data, which newer ffprobe versions
+// report, falling back to the legacy "rotate" tag. The display matrix angle
+// is counter-clockwise, the tag clockwise.
+func (s rawStream) rotation() int {
+	degrees := 0
+	found := false
+	for _, side := range s.SideDataList {
+		if side.Rotation != 0 {
+			degrees = -int(side.Rotation)
+			found = true
+			break
+		}
+	}
+	if !found {
+		degrees, _ = strconv.Atoi(lookupTag(s.Tags, "rotate"))
+	}
+
+	degrees %= 360
+	if degrees < 0 {
+		degrees += 360
+	}
+	return degrees
+}
+
+func lookupTag(tags map[string]string, key string) string {
+	if value, ok := tags[key]; ok {
+		return value
+	}
+	for k, value := range tags {
+		if strings.EqualFold(k, key) {
+			return value
+		}
+	}
+	return ""
+}
+
+// parseFloat and parseInt return 0 for ffprobe's "N/A" and missing values.
+func parseFloat(value string) float64 {
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0
+	}
+	return f
+}
+
+func parseInt(value string) int64 {
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+// parseRate converts the first usable "num/den" rate to frames per second.
+func parseRate(rates ...string) float64 {
+	for _, rate := range rates {
+		num, den, ok := strings.Cut(rate, "/")
+		if !ok {
+			if f := parseFloat(rate); f > 0 {
+				return f
+			}
+			continue
+		}
+		n, d := parseFloat(num), parseFloat(den)
+		if n > 0 && d > 0 {
+			return n / d
+		}
+	}
+	return 0
+}
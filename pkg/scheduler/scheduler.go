@@ -0,0 +1,183 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// ErrSkipped is wrapped by the error of a job that was never started,
+// because the context was cancelled or an earlier job failed in fail-fast
+// mode.
+var ErrSkipped = errors.New("job skipped")
+
+var errEarlierFailure = errors.New("an earlier job failed")
+
+// Job is one unit of work. Category groups jobs that share a limit, e.g.
+// the name of the tool that runs them.
+type Job struct {
+	Category string
+	Run      func(ctx context.Context) error
+}
+
+// Result reports the outcome of the job at Index in the slice passed to Run.
+type Result struct {
+	Index    int
+	Err      error
+	Duration time.Duration // zero for skipped jobs
+}
+
+// Skipped reports whether the job never ran.
+func (r Result) Skipped() bool {
+	return errors.Is(r.Err, ErrSkipped)
+}
+
+// Scheduler runs jobs on a bounded number of workers.
+type Scheduler struct {
+	// Workers is the total number of jobs run at once. Zero or less means
+	// runtime.NumCPU().
+	Workers int
+
+	// Limits caps the number of concurrent jobs per category. Categories
+	// without an entry are only bounded by Workers.
+	Limits map[string]int
+
+	// FailFast cancels running jobs and skips the rest after the first
+	// failure. Otherwise every job runs regardless of earlier errors.
+	FailFast bool
+}
+
+func New(workers int) *Scheduler {
+	return &Scheduler{Workers: workers, Limits: make(map[string]int)}
+}
+
+// SetLimit caps category at n concurrent jobs. n <= 0 removes the limit.
+func (s *Scheduler) SetLimit(category string, n int) {
+	if s.Limits == nil {
+		s.Limits = make(map[string]int)
+	}
+	if n <= 0 {
+		delete(s.Limits, category)
+		return
+	}
+	s.Limits[category] = n
+}
+
+func (s *Scheduler) workers() int {
+	if s.Workers > 0 {
+		return s.Workers
+	}
+	return runtime.NumCPU()
+}
+
+// Run starts jobs in order, as workers and category limits allow, and
+// streams one Result per job as it finishes. The channel is closed once
+// every job has reported.
+func (s *Scheduler) Run(ctx context.Context, jobs []Job) <-chan Result {
+	results := make(chan Result, len(jobs))
+	go s.dispatch(ctx, jobs, results)
+	return results
+}
+
+// RunAll runs jobs and returns their errors indexed like jobs.
+func (s *Scheduler) RunAll(ctx context.Context, jobs []Job) []error {
+	errs := make([]error, len(jobs))
+	for result := range s.Run(ctx, jobs) {
+		errs[result.Index] = result.Err
+	}
+	return errs
+}
+
+func (s *Scheduler) dispatch(ctx context.Context, jobs []Job, results chan<- Result) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	limits := make(map[string]int, len(s.Limits))
+	for category, n := range s.Limits {
+		limits[category] = n
+	}
+
+	var (
+		mu       sync.Mutex
+		cond     = sync.NewCond(&mu)
+		running  int
+		perCat   = make(map[string]int)
+		pending  = make([]int, len(jobs))
+		wg       sync.WaitGroup
+		maxSlots = s.workers()
+	)
+	for i := range jobs {
+		pending[i] = i
+	}
+
+	// Wake the dispatcher when the context ends so it can skip the rest.
+	stop := context.AfterFunc(ctx, func() {
+		mu.Lock()
+		cond.Broadcast()
+		mu.Unlock()
+	})
+	defer stop()
+
+	// next returns the position in pending of the first job that fits
+	// within the limits, or -1.
+	next := func() int {
+		if running >= maxSlots {
+			return -1
+		}
+		for pos, index := range pending {
+			category := jobs[index].Category
+			if limit, ok := limits[category]; ok && perCat[category] >= limit {
+				continue
+			}
+			return pos
+		}
+		return -1
+	}
+
+	mu.Lock()
+	for len(pending) > 0 {
+		pos := next()
+		for pos < 0 && ctx.Err() == nil {
+			cond.Wait()
+			pos = next()
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		index := pending[pos]
+		pending = append(pending[:pos], pending[pos+1:]...)
+		job := jobs[index]
+		running++
+		perCat[job.Category]++
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			start := time.Now()
+			err := job.Run(ctx)
+			if err != nil && s.FailFast {
+				cancel(errEarlierFailure)
+			}
+			results <- Result{Index: index, Err: err, Duration: time.Since(start)}
+
+			mu.Lock()
+			running--
+			perCat[job.Category]--
+			cond.Broadcast()
+			mu.Unlock()
+		}()
+	}
+	skipped := pending
+	mu.Unlock()
+
+	for _, index := range skipped {
+		results <- Result{Index: index, Err: fmt.Errorf("%w: %w", ErrSkipped, context.Cause(ctx))}
+	}
+
+	wg.Wait()
+	close(results)
+}
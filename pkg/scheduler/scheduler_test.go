@@ -0,0 +1,158 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// tracker records how many jobs of each category run at once.
+type tracker struct {
+	mu      sync.Mutex
+	running map[string]int
+	peak    map[string]int
+	total   int
+	max     int
+}
+
+func newTracker() *tracker {
+	return &tracker{running: make(map[string]int), peak: make(map[string]int)}
+}
+
+func (tr *tracker) job(category string) Job {
+	return Job{Category: category, Run: func(ctx context.Context) error {
+		tr.mu.Lock()
+		tr.running[category]++
+		tr.total++
+		tr.peak[category] = max(tr.peak[category], tr.running[category])
+		tr.max = max(tr.max, tr.total)
+		tr.mu.Unlock()
+
+		time.Sleep(10 * time.Millisecond)
+
+		tr.mu.Lock()
+		tr.running[category]--
+		tr.total--
+		tr.mu.Unlock()
+		return nil
+	}}
+}
+
+func TestBoundedPool(t *testing.T) {
+	tests := []struct {
+		name     string
+		workers  int
+		limits   map[string]int
+		jobs     []string // categories
+		wantMax  int
+		wantPeak map[string]int
+	}{
+		{"workers", 2, nil, []string{"", "", "", "", "", ""}, 2, nil},
+		{"one worker", 1, nil, []string{"", "", ""}, 1, nil},
+		{"category limit", 4, map[string]int{"ffmpeg": 1},
+			[]string{"ffmpeg", "ffmpeg", "ffmpeg", "magick", "magick", "magick"}, 4,
+			map[string]int{"ffmpeg": 1, "magick": 3}},
+	}
+	for _, tt := range tests {
+		s := New(tt.workers)
+		for category, n := range tt.limits {
+			s.SetLimit(category, n)
+		}
+		tr := newTracker()
+		var jobs []Job
+		for _, category := range tt.jobs {
+			jobs = append(jobs, tr.job(category))
+		}
+
+		for i, err := range s.RunAll(context.Background(), jobs) {
+			if err != nil {
+				t.Errorf("%s: job %d: %v", tt.name, i, err)
+			}
+		}
+		if tr.max > tt.wantMax {
+			t.Errorf("%s: %d jobs ran at once, want at most %d", tt.name, tr.max, tt.wantMax)
+		}
+		for category, want := range tt.wantPeak {
+			if got := tr.peak[category]; got > want {
+				t.Errorf("%s: %d %s jobs ran at once, want at most %d", tt.name, got, category, want)
+			}
+		}
+	}
+}
+
+func TestFailFast(t *testing.T) {
+	failure := errors.New("boom")
+	blocked := make(chan struct{})
+	jobs := []Job{
+		// Runs until the failure of the next job cancels it
+		{Run: func(ctx context.Context) error {
+			close(blocked)
+			<-ctx.Done()
+			return ctx.Err()
+		}},
+		{Run: func(ctx context.Context) error {
+			<-blocked
+			return failure
+		}},
+		{Run: func(ctx context.Context) error { return nil }},
+		{Run: func(ctx context.Context) error { return nil }},
+	}
+
+	s := New(2)
+	s.FailFast = true
+	results := make([]Result, len(jobs))
+	for result := range s.Run(context.Background(), jobs) {
+		results[result.Index] = result
+	}
+
+	if !errors.Is(results[0].Err, context.Canceled) {
+		t.Errorf("running job: err = %v, want it cancelled", results[0].Err)
+	}
+	if !errors.Is(results[1].Err, failure) {
+		t.Errorf("failing job: err = %v, want %v", results[1].Err, failure)
+	}
+	for _, result := range results[2:] {
+		if !result.Skipped() || !errors.Is(result.Err, errEarlierFailure) {
+			t.Errorf("job %d: err = %v, want it skipped after the failure", result.Index, result.Err)
+		}
+	}
+}
+
+func TestNoFailFast(t *testing.T) {
+	failure := errors.New("boom")
+	var mu sync.Mutex
+	ran := 0
+	job := func(err error) Job {
+		return Job{Run: func(ctx context.Context) error {
+			mu.Lock()
+			ran++
+			mu.Unlock()
+			return err
+		}}
+	}
+
+	errs := New(1).RunAll(context.Background(), []Job{job(failure), job(nil), job(nil)})
+	if ran != 3 {
+		t.Errorf("%d jobs ran, want all 3", ran)
+	}
+	if !errors.Is(errs[0], failure) || errs[1] != nil || errs[2] != nil {
+		t.Errorf("errs = %v, want only the first to fail", errs)
+	}
+}
+
+func TestCancelledBeforeStart(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	jobs := []Job{
+		{Run: func(ctx context.Context) error { t.Error("job 0 ran"); return nil }},
+		{Run: func(ctx context.Context) error { t.Error("job 1 ran"); return nil }},
+	}
+	for i, err := range New(2).RunAll(ctx, jobs) {
+		if !errors.Is(err, ErrSkipped) || !errors.Is(err, context.Canceled) {
+			t.Errorf("job %d: err = %v, want skipped because of the cancellation", i, err)
+		}
+	}
+}
@@ -0,0 +1,287 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"goverter/pkg/converter"
+)
+
+const (
+	JobQueued    = "queued"
+	JobRunning   = "running"
+	JobDone      = "done"
+	JobFailed    = "failed"
+	JobCancelled = "cancelled"
+)
+
+// JobStatus is the JSON view of a job.
+type JobStatus struct {
+	ID       string            `json:"id"`
+	Status   string            `json:"status"`
+	Progress float64           `json:"progress"`
+	Input    string            `json:"input"`
+	Output   string            `json:"output"`
+	Options  map[string]string `json:"options,omitempty"`
+	Error    string            `json:"error,omitempty"`
+	Hint     string            `json:"hint,omitempty"`
+	Created  time.Time         `json:"created"`
+	Started  *time.Time        `json:"started,omitempty"`
+	Finished *time.Time        `json:"finished,omitempty"`
+}
+
+func (s JobStatus) terminal() bool {
+	return s.Status == JobDone || s.Status == JobFailed || s.Status == JobCancelled
+}
+
+type job struct {
+	mu      sync.Mutex
+	status  JobStatus
+	cancel  context.CancelFunc
+	changed chan struct{} // closed and replaced on every update
+}
+
+func (j *job) snapshot() (JobStatus, <-chan struct{}) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.status, j.changed
+}
+
+func (j *job) update(fn func(*JobStatus)) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	fn(&j.status)
+	close(j.changed)
+	j.changed = make(chan struct{})
+}
+
+// jobQueue runs conversions on a fixed number of workers. Finished jobs
+// are pruned whenever the queue is used: those that ended more than ttl
+// ago go first, then the oldest beyond maxFinished. A job's output is
+// deleted with it only if it lies in resultsDir, where the server put it.
+// Uploads in uploadsDir that no remaining job reads are pruned by the same
+// rules, counting from when they were uploaded.
+type jobQueue struct {
+	converter *converter.Converter
+	ctx       context.Context
+	slots     chan struct{}
+
+	ttl         time.Duration
+	maxFinished int
+	resultsDir  string
+	uploadsDir  string
+
+	mu   sync.Mutex
+	jobs map[string]*job
+}
+
+func newJobQueue(ctx context.Context, c *converter.Converter, workers int) *jobQueue {
+	return &jobQueue{
+		converter: c,
+		ctx:       ctx,
+		slots:     make(chan struct{}, workers),
+		jobs:      make(map[string]*job),
+	}
+}
+
+func (q *jobQueue) submit(req converter.ConversionRequest) JobStatus {
+	ctx, cancel := context.WithCancel(q.ctx)
+	j := &job{
+		status: JobStatus{
+			ID:      newID(),
+			Status:  JobQueued,
+			Input:   req.InputPath,
+			Output:  req.OutputPath,
+			Options: req.Options,
+			Created: time.Now(),
+		},
+		cancel:  cancel,
+		changed: make(chan struct{}),
+	}
+
+	// Added before pruning so that the upload it reads is kept
+	q.mu.Lock()
+	q.jobs[j.status.ID] = j
+	q.prune(time.Now())
+	q.mu.Unlock()
+
+	go q.run(ctx, j, req)
+	status, _ := j.snapshot()
+	return status
+}
+
+func (q *jobQueue) run(ctx context.Context, j *job, req converter.ConversionRequest) {
+	defer j.cancel()
+
+	select {
+	case q.slots <- struct{}{}:
+		defer func() { <-q.slots }()
+	case <-ctx.Done():
+		j.update(func(s *JobStatus) {
+			now := time.Now()
+			s.Status = JobCancelled
+			s.Finished = &now
+		})
+		return
+	}
+
+	j.update(func(s *JobStatus) {
+		now := time.Now()
+		s.Status = JobRunning
+		s.Started = &now
+	})
+
+	progress := make(chan float64, 16)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for value := range progress {
+			j.update(func(s *JobStatus) { s.Progress = value })
+		}
+	}()
+
+	req.Progress = progress
+	err := q.converter.ConvertContext(ctx, req)
+	close(progress)
+	<-done
+
+	j.update(func(s *JobStatus) {
+		now := time.Now()
+		s.Finished = &now
+		switch {
+		case err == nil:
+			s.Status = JobDone
+			s.Progress = 1
+		case ctx.Err() != nil:
+			s.Status = JobCancelled
+			s.Error = err.Error()
+		default:
+			s.Status = JobFailed
+			s.Error = err.Error()
+			s.Hint = converter.ErrorHint(err)
+		}
+	})
+}
+
+func (q *jobQueue) get(id string) (*job, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.prune(time.Now())
+	j, ok := q.jobs[id]
+	return j, ok
+}
+
+func (q *jobQueue) list() []JobStatus {
+	q.mu.Lock()
+	q.prune(time.Now())
+	jobs := make([]*job, 0, len(q.jobs))
+	for _, j := range q.jobs {
+		jobs = append(jobs, j)
+	}
+	q.mu.Unlock()
+
+	statuses := make([]JobStatus, len(jobs))
+	for i, j := range jobs {
+		statuses[i], _ = j.snapshot()
+	}
+	sort.Slice(statuses, func(a, b int) bool {
+		return statuses[a].Created.Before(statuses[b].Created)
+	})
+	return statuses
+}
+
+// prune forgets finished jobs that have expired or are over the limit.
+// q.mu must be held.
+func (q *jobQueue) prune(now time.Time) {
+	var finished []JobStatus
+	for id, j := range q.jobs {
+		status, _ := j.snapshot()
+		if status.Finished == nil {
+			continue
+		}
+		if q.ttl > 0 && now.Sub(*status.Finished) > q.ttl {
+			q.evict(id, status)
+			continue
+		}
+		finished = append(finished, status)
+	}
+
+	if extra := len(finished) - q.maxFinished; q.maxFinished > 0 && extra > 0 {
+		sort.Slice(finished, func(a, b int) bool {
+			return finished[a].Finished.Before(*finished[b].Finished)
+		})
+		for _, status := range finished[:extra] {
+			q.evict(status.ID, status)
+		}
+	}
+	q.pruneUploads(now)
+}
+
+// pruneUploads removes uploads that no job reads once they are older than
+// ttl, then the oldest beyond maxFinished. q.mu must be held.
+func (q *jobQueue) pruneUploads(now time.Time) {
+	if q.uploadsDir == "" {
+		return
+	}
+	entries, err := os.ReadDir(q.uploadsDir)
+	if err != nil {
+		return
+	}
+
+	inUse := make(map[string]bool)
+	for _, j := range q.jobs {
+		status, _ := j.snapshot()
+		inUse[filepath.Dir(status.Input)] = true
+	}
+
+	type upload struct {
+		dir     string
+		created time.Time
+	}
+	var unused []upload
+	for _, entry := range entries {
+		dir := filepath.Join(q.uploadsDir, entry.Name())
+		info, err := entry.Info()
+		if err != nil || !entry.IsDir() || inUse[dir] {
+			continue
+		}
+		if q.ttl > 0 && now.Sub(info.ModTime()) > q.ttl {
+			os.RemoveAll(dir)
+			continue
+		}
+		unused = append(unused, upload{dir, info.ModTime()})
+	}
+
+	if extra := len(unused) - q.maxFinished; q.maxFinished > 0 && extra > 0 {
+		sort.Slice(unused, func(a, b int) bool {
+			return unused[a].created.Before(unused[b].created)
+		})
+		for _, u := range unused[:extra] {
+			os.RemoveAll(u.dir)
+		}
+	}
+}
+
+func (q *jobQueue) evict(id string, status JobStatus) {
+	delete(q.jobs, id)
+	if q.resultsDir == "" {
+		return
+	}
+	// Results are written to their own directory under resultsDir
+	if rel, err := filepath.Rel(q.resultsDir, filepath.Dir(status.Output)); err == nil && rel != "." && !strings.Contains(rel, "..") && !strings.ContainsRune(rel, filepath.Separator) {
+		os.RemoveAll(filepath.Dir(status.Output))
+	}
+}
+
+func newID() string {
+	var b [8]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
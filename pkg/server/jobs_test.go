@@ -0,0 +1,98 @@
+package server
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func finishedJob(id, output string, finished time.Time) *job {
+	return &job{
+		status:  JobStatus{ID: id, Status: JobDone, Output: output, Finished: &finished},
+		changed: make(chan struct{}),
+	}
+}
+
+func TestPrune(t *testing.T) {
+	results := t.TempDir()
+	resultDir := filepath.Join(results, "r1")
+	if err := os.Mkdir(resultDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	elsewhere := filepath.Join(t.TempDir(), "kept.mp4")
+	if err := os.WriteFile(elsewhere, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	q := newJobQueue(context.Background(), nil, 1)
+	q.ttl, q.maxFinished, q.resultsDir = time.Hour, 2, results
+	q.jobs = map[string]*job{
+		"expired":  finishedJob("expired", filepath.Join(resultDir, "out.mp4"), now.Add(-2*time.Hour)),
+		"external": finishedJob("external", elsewhere, now.Add(-90*time.Minute)),
+		"old":      finishedJob("old", "", now.Add(-30*time.Minute)),
+		"mid":      finishedJob("mid", "", now.Add(-20*time.Minute)),
+		"new":      finishedJob("new", "", now.Add(-10*time.Minute)),
+		"running":  {status: JobStatus{ID: "running", Status: JobRunning}, changed: make(chan struct{})},
+	}
+
+	q.prune(now)
+
+	for _, id := range []string{"mid", "new", "running"} {
+		if _, ok := q.jobs[id]; !ok {
+			t.Errorf("job %s was pruned, want it kept", id)
+		}
+	}
+	for _, id := range []string{"expired", "external", "old"} {
+		if _, ok := q.jobs[id]; ok {
+			t.Errorf("job %s was kept, want it pruned", id)
+		}
+	}
+	if _, err := os.Stat(resultDir); !os.IsNotExist(err) {
+		t.Errorf("result directory of a pruned job still exists (err %v)", err)
+	}
+	if _, err := os.Stat(elsewhere); err != nil {
+		t.Errorf("output outside the results directory was removed: %v", err)
+	}
+}
+
+func TestPruneUploads(t *testing.T) {
+	uploads := t.TempDir()
+	now := time.Now()
+	upload := func(id string, age time.Duration) string {
+		dir := filepath.Join(uploads, id)
+		if err := os.Mkdir(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(dir, now.Add(-age), now.Add(-age)); err != nil {
+			t.Fatal(err)
+		}
+		return dir
+	}
+	upload("expired", 2*time.Hour)
+	inUse := upload("in-use", 3*time.Hour)
+	upload("old", 30*time.Minute)
+	upload("mid", 20*time.Minute)
+	upload("new", 10*time.Minute)
+
+	q := newJobQueue(context.Background(), nil, 1)
+	q.ttl, q.maxFinished, q.uploadsDir = time.Hour, 2, uploads
+	q.jobs = map[string]*job{
+		"running": {status: JobStatus{ID: "running", Status: JobRunning, Input: filepath.Join(inUse, "in.mp4")}, changed: make(chan struct{})},
+	}
+
+	q.prune(now)
+
+	for _, id := range []string{"in-use", "mid", "new"} {
+		if _, err := os.Stat(filepath.Join(uploads, id)); err != nil {
+			t.Errorf("upload %s was removed, want it kept", id)
+		}
+	}
+	for _, id := range []string{"expired", "old"} {
+		if _, err := os.Stat(filepath.Join(uploads, id)); !os.IsNotExist(err) {
+			t.Errorf("upload %s was kept, want it removed", id)
+		}
+	}
+}
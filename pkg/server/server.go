@@ -0,0 +1,487 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"goverter/pkg/converter"
+	"goverter/pkg/formats"
+	"goverter/pkg/utils"
+)
+
+type Config struct {
+	Addr string
+
+	// Root is the only directory clients may reference by path, for inputs
+	// and explicit outputs.
+	Root string
+
+	// DataDir holds uploads and results of jobs without an explicit output
+	// path. Defaults to a temporary directory removed on shutdown.
+	DataDir string
+
+	// MaxUpload is the largest accepted upload in bytes.
+	MaxUpload int64
+
+	// Workers is the number of conversions run at once. Defaults to 2.
+	Workers int
+
+	// Finished jobs are forgotten JobTTL after they end, and beyond the
+	// newest MaxFinishedJobs, along with any result the server wrote for
+	// them in DataDir. Uploads no remaining job reads are removed under the
+	// same limits. They default to an hour and 1000.
+	JobTTL          time.Duration
+	MaxFinishedJobs int
+}
+
+type Server struct {
+	config  Config
+	root    string
+	ownData bool
+	queue   *jobQueue
+	ctx     context.Context
+}
+
+// New prepares a server; jobs submitted to it are cancelled when ctx ends.
+func New(ctx context.Context, c *converter.Converter, config Config) (*Server, error) {
+	root, err := filepath.Abs(config.Root)
+	if err != nil {
+		return nil, err
+	}
+	if root, err = filepath.EvalSymlinks(root); err != nil {
+		return nil, fmt.Errorf("root %s: %w", config.Root, err)
+	}
+
+	s := &Server{config: config, root: root, ctx: ctx}
+	if s.config.DataDir == "" {
+		if s.config.DataDir, err = os.MkdirTemp("", "goverter-serve-"); err != nil {
+			return nil, err
+		}
+		s.ownData = true
+	}
+	if s.config.Workers <= 0 {
+		s.config.Workers = 2
+	}
+	if s.config.MaxUpload <= 0 {
+		s.config.MaxUpload = 1 << 30
+	}
+
+	if s.config.JobTTL <= 0 {
+		s.config.JobTTL = time.Hour
+	}
+	if s.config.MaxFinishedJobs <= 0 {
+		s.config.MaxFinishedJobs = 1000
+	}
+
+	s.queue = newJobQueue(ctx, c, s.config.Workers)
+	s.queue.ttl = s.config.JobTTL
+	s.queue.maxFinished = s.config.MaxFinishedJobs
+	s.queue.resultsDir = filepath.Join(s.config.DataDir, "results")
+	s.queue.uploadsDir = filepath.Join(s.config.DataDir, "uploads")
+	return s, nil
+}
+
+// ListenAndServe serves until ctx is done, then shuts down gracefully.
+func (s *Server) ListenAndServe(ctx context.Context) error {
+	httpServer := &http.Server{
+		Addr:              s.config.Addr,
+		Handler:           s,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	errs := make(chan error, 1)
+	go func() { errs <- httpServer.ListenAndServe() }()
+
+	select {
+	case err := <-errs:
+		s.cleanup()
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err := httpServer.Shutdown(shutdownCtx)
+	s.cleanup()
+	return err
+}
+
+func (s *Server) cleanup() {
+	if s.ownData {
+		os.RemoveAll(s.config.DataDir)
+	}
+}
+
+// ServeHTTP routes requests:
+//
+//	GET    /health
+//	GET    /formats
+//	POST   /uploads              multipart form with a "file" field
+//	POST   /jobs                 JSON jobRequest
+//	GET    /jobs
+//	GET    /jobs/{id}
+//	DELETE /jobs/{id}            cancel
+//	GET    /jobs/{id}/events     server-sent progress events
+//	GET    /jobs/{id}/result     download the output
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+
+	switch {
+	case len(parts) == 1 && parts[0] == "health":
+		s.allow(w, r, http.MethodGet, func() {
+			writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+		})
+	case len(parts) == 1 && parts[0] == "formats":
+		s.allow(w, r, http.MethodGet, func() {
+			writeJSON(w, http.StatusOK, formats.Default().Formats())
+		})
+	case len(parts) == 1 && parts[0] == "uploads":
+		s.allow(w, r, http.MethodPost, func() { s.handleUpload(w, r) })
+	case len(parts) == 1 && parts[0] == "jobs":
+		switch r.Method {
+		case http.MethodGet:
+			writeJSON(w, http.StatusOK, s.queue.list())
+		case http.MethodPost:
+			s.handleSubmit(w, r)
+		default:
+			methodNotAllowed(w, http.MethodGet, http.MethodPost)
+		}
+	case len(parts) == 2 && parts[0] == "jobs":
+		switch r.Method {
+		case http.MethodGet:
+			s.withJob(w, parts[1], func(j *job) {
+				status, _ := j.snapshot()
+				writeJSON(w, http.StatusOK, status)
+			})
+		case http.MethodDelete:
+			s.withJob(w, parts[1], func(j *job) {
+				j.cancel()
+				status, _ := j.snapshot()
+				writeJSON(w, http.StatusAccepted, status)
+			})
+		default:
+			methodNotAllowed(w, http.MethodGet, http.MethodDelete)
+		}
+	case len(parts) == 3 && parts[0] == "jobs" && parts[2] == "events":
+		s.allow(w, r, http.MethodGet, func() {
+			s.withJob(w, parts[1], func(j *job) { s.handleEvents(w, r, j) })
+		})
+	case len(parts) == 3 && parts[0] == "jobs" && parts[2] == "result":
+		s.allow(w, r, http.MethodGet, func() {
+			s.withJob(w, parts[1], func(j *job) { s.handleResult(w, r, j) })
+		})
+	default:
+		writeError(w, http.StatusNotFound, "not found")
+	}
+}
+
+func (s *Server) allow(w http.ResponseWriter, r *http.Request, method string, handle func()) {
+	if r.Method != method {
+		methodNotAllowed(w, method)
+		return
+	}
+	handle()
+}
+
+func (s *Server) withJob(w http.ResponseWriter, id string, handle func(*job)) {
+	j, ok := s.queue.get(id)
+	if !ok {
+		writeError(w, http.StatusNotFound, "no job "+id)
+		return
+	}
+	handle(j)
+}
+
+type uploadResponse struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Size int64  `json:"size"`
+}
+
+func (s *Server) handleUpload(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, s.config.MaxUpload)
+
+	reader, err := r.MultipartReader()
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "expected a multipart upload: "+err.Error())
+		return
+	}
+
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			writeError(w, http.StatusBadRequest, `missing "file" field`)
+			return
+		}
+		if err != nil {
+			writeUploadError(w, err)
+			return
+		}
+		if part.FormName() != "file" {
+			continue
+		}
+
+		name := filepath.Base(filepath.Clean("/" + part.FileName()))
+		if name == "/" || name == "." {
+			writeError(w, http.StatusBadRequest, "upload needs a file name")
+			return
+		}
+
+		id := newID()
+		dir := filepath.Join(s.queue.uploadsDir, id)
+		if err := utils.EnsureDir(dir); err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		file, err := os.Create(filepath.Join(dir, name))
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		size, err := io.Copy(file, part)
+		file.Close()
+		if err != nil {
+			os.RemoveAll(dir)
+			writeUploadError(w, err)
+			return
+		}
+
+		writeJSON(w, http.StatusCreated, uploadResponse{ID: id, Name: name, Size: size})
+		return
+	}
+}
+
+func writeUploadError(w http.ResponseWriter, err error) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		writeError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("upload exceeds %d bytes", tooLarge.Limit))
+		return
+	}
+	writeError(w, http.StatusBadRequest, err.Error())
+}
+
+// jobRequest submits a conversion. The input is either an upload or a
+// path under the root. The output is written under the root at OutputPath
+// if given, otherwise in the data directory with the target Format.
+type jobRequest struct {
+	UploadID   string            `json:"upload_id"`
+	InputPath  string            `json:"input_path"`
+	OutputPath string            `json:"output_path"`
+	Format     string            `json:"format"`
+	Options    map[string]string `json:"options"`
+}
+
+func (s *Server) handleSubmit(w http.ResponseWriter, r *http.Request) {
+	var req jobRequest
+	decoder := json.NewDecoder(io.LimitReader(r.Body, 1<<20))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid job: "+err.Error())
+		return
+	}
+
+	input, err := s.resolveInput(req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var output, ext string
+	switch {
+	case req.OutputPath != "":
+		output, err = s.sandbox(req.OutputPath, false)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if utils.SamePath(input, output) {
+			writeError(w, http.StatusBadRequest, "output_path is the input file")
+			return
+		}
+		ext = filepath.Ext(output)
+	case req.Format != "":
+		if strings.ContainsAny(req.Format, `/\`) || strings.Contains(req.Format, "..") {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid format %q", req.Format))
+			return
+		}
+		format, ok := formats.Default().Lookup(req.Format)
+		if !ok || !format.Write {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("cannot convert to %s", req.Format))
+			return
+		}
+		ext = format.Ext
+	default:
+		writeError(w, http.StatusBadRequest, "either format or output_path is required")
+		return
+	}
+
+	plan, err := s.queue.converter.Plan(filepath.Ext(input), ext)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	if err := plan.ValidateOptions(req.Options); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Only a valid job gets a results directory
+	if output == "" {
+		dir := filepath.Join(s.queue.resultsDir, newID())
+		name := strings.TrimSuffix(filepath.Base(input), filepath.Ext(input))
+		output = filepath.Join(dir, name+ext)
+		if filepath.Dir(output) != dir {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid format %q", req.Format))
+			return
+		}
+		if err := utils.EnsureDir(dir); err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+
+	status := s.queue.submit(converter.ConversionRequest{
+		InputPath:  input,
+		OutputPath: output,
+		Options:    req.Options,
+	})
+	w.Header().Set("Location", "/jobs/"+status.ID)
+	writeJSON(w, http.StatusAccepted, status)
+}
+
+func (s *Server) resolveInput(req jobRequest) (string, error) {
+	switch {
+	case req.UploadID != "" && req.InputPath != "":
+		return "", errors.New("give either upload_id or input_path, not both")
+	case req.UploadID != "":
+		if strings.ContainsAny(req.UploadID, `/\.`) {
+			return "", errors.New("invalid upload_id")
+		}
+		entries, err := os.ReadDir(filepath.Join(s.queue.uploadsDir, req.UploadID))
+		if err != nil || len(entries) != 1 {
+			return "", fmt.Errorf("no upload %s", req.UploadID)
+		}
+		return filepath.Join(s.queue.uploadsDir, req.UploadID, entries[0].Name()), nil
+	case req.InputPath != "":
+		return s.sandbox(req.InputPath, true)
+	default:
+		return "", errors.New("either upload_id or input_path is required")
+	}
+}
+
+// sandbox resolves path against the root and rejects anything that ends up
+// outside it, including through symlinks. Outputs need not exist yet, so
+// only their directory is resolved, and an output that is itself a symlink
+// is refused because ffmpeg would write through it.
+func (s *Server) sandbox(path string, mustExist bool) (string, error) {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(s.root, path)
+	}
+	path = filepath.Clean(path)
+	if !s.inRoot(path) {
+		return "", fmt.Errorf("%s is outside the server root", path)
+	}
+
+	var resolved string
+	if mustExist {
+		real, err := filepath.EvalSymlinks(path)
+		if err != nil {
+			return "", fmt.Errorf("input %s does not exist", path)
+		}
+		resolved = real
+	} else {
+		dir, err := filepath.EvalSymlinks(filepath.Dir(path))
+		if err != nil {
+			return "", fmt.Errorf("output directory %s does not exist", filepath.Dir(path))
+		}
+		resolved = filepath.Join(dir, filepath.Base(path))
+		if info, err := os.Lstat(resolved); err == nil && info.Mode()&os.ModeSymlink != 0 {
+			return "", fmt.Errorf("output %s is a symlink", path)
+		}
+	}
+
+	if !s.inRoot(resolved) {
+		return "", fmt.Errorf("%s is outside the server root", path)
+	}
+	return resolved, nil
+}
+
+func (s *Server) inRoot(path string) bool {
+	rel, err := filepath.Rel(s.root, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// handleEvents streams the job as server-sent events: a "progress" event
+// on every change and a final "done", "failed" or "cancelled" event.
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request, j *job) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "streaming not supported")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	for {
+		status, changed := j.snapshot()
+		event := "progress"
+		if status.terminal() {
+			event = status.Status
+		}
+
+		data, _ := json.Marshal(status)
+		fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+		flusher.Flush()
+
+		if status.terminal() {
+			return
+		}
+
+		select {
+		case <-changed:
+		case <-r.Context().Done():
+			return
+		case <-s.ctx.Done():
+			return
+		}
+	}
+}
+
+func (s *Server) handleResult(w http.ResponseWriter, r *http.Request, j *job) {
+	status, _ := j.snapshot()
+	if status.Status != JobDone {
+		writeError(w, http.StatusConflict, "job is "+status.Status)
+		return
+	}
+
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filepath.Base(status.Output)))
+	if format, ok := formats.Default().Lookup(filepath.Ext(status.Output)); ok && format.MIME != "" {
+		w.Header().Set("Content-Type", format.MIME)
+	}
+	http.ServeFile(w, r, status.Output)
+}
+
+func writeJSON(w http.ResponseWriter, code int, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(value)
+}
+
+func writeError(w http.ResponseWriter, code int, message string) {
+	writeJSON(w, code, map[string]string{"error": message})
+}
+
+func methodNotAllowed(w http.ResponseWriter, methods ...string) {
+	w.Header().Set("Allow", strings.Join(methods, ", "))
+	writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+}
@@ -0,0 +1,121 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"goverter/pkg/converter"
+)
+
+func newTestServer(t *testing.T) (*Server, string, string) {
+	t.Helper()
+	root, outside := t.TempDir(), t.TempDir()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	s, err := New(ctx, converter.NewConverter(), Config{Root: root, DataDir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The temp dir may itself sit behind a symlink (macOS /var)
+	return s, s.root, outside
+}
+
+func TestSandbox(t *testing.T) {
+	s, root, outside := newTestServer(t)
+	for _, name := range []string{"in.mp4", "out.mp4"} {
+		if err := os.WriteFile(filepath.Join(root, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(outside, "secret.mp4"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(outside, "secret.mp4"), filepath.Join(root, "escape.mp4")); err != nil {
+		t.Skip("symlinks not supported:", err)
+	}
+	if err := os.Symlink(outside, filepath.Join(root, "away")); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path      string
+		mustExist bool
+		wantErr   string
+	}{
+		{"in.mp4", true, ""},
+		{"new.mp4", false, ""},
+		{"out.mp4", false, ""},
+		{"missing.mp4", true, "does not exist"},
+		{"../x.mp4", false, "outside the server root"},
+		{filepath.Join(outside, "x.mp4"), false, "outside the server root"},
+		{"escape.mp4", true, "outside the server root"},
+		{"escape.mp4", false, "symlink"},
+		{"away/x.mp4", false, "outside the server root"},
+	}
+	for _, tt := range tests {
+		_, err := s.sandbox(tt.path, tt.mustExist)
+		switch {
+		case tt.wantErr == "" && err != nil:
+			t.Errorf("sandbox(%q, %v) = %v, want nil", tt.path, tt.mustExist, err)
+		case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+			t.Errorf("sandbox(%q, %v) = %v, want error containing %q", tt.path, tt.mustExist, err, tt.wantErr)
+		}
+	}
+}
+
+func TestSubmitRejectsOutputOverInput(t *testing.T) {
+	s, root, _ := newTestServer(t)
+	if err := os.WriteFile(filepath.Join(root, "in.mp4"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	body := `{"input_path": "in.mp4", "output_path": "./in.mp4"}`
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/jobs", strings.NewReader(body)))
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "is the input") {
+		t.Errorf("POST /jobs = %d %s, want 400 rejecting the output", rec.Code, rec.Body.String())
+	}
+}
+
+func TestSubmitFormat(t *testing.T) {
+	s, root, outside := newTestServer(t)
+	if err := os.WriteFile(filepath.Join(root, "in.png"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		format  string
+		options string
+		wantErr string
+	}{
+		{"/../../../../../../" + outside + "/evil.txt", "", "invalid format"},
+		{"x/../../../../../" + outside + "/job.jpg", "", "invalid format"},
+		{`..\evil.jpg`, "", "invalid format"},
+		{"nosuchformat", "", "cannot convert to"},
+		{"jpg", `{"nosuchoption": "1"}`, "unknown option"},
+	}
+	for _, tt := range tests {
+		body := `{"input_path": "in.png", "format": "` + strings.ReplaceAll(tt.format, `\`, `\\`) + `"`
+		if tt.options != "" {
+			body += `, "options": ` + tt.options
+		}
+		body += "}"
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/jobs", strings.NewReader(body)))
+		if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), tt.wantErr) {
+			t.Errorf("format %q: POST /jobs = %d %s, want 400 containing %q", tt.format, rec.Code, rec.Body.String(), tt.wantErr)
+		}
+	}
+
+	if entries, _ := os.ReadDir(outside); len(entries) != 0 {
+		t.Errorf("files were created outside the data directory: %v", entries)
+	}
+	if entries, _ := os.ReadDir(s.queue.resultsDir); len(entries) != 0 {
+		t.Errorf("rejected jobs left %d results directories", len(entries))
+	}
+}
@@ -0,0 +1,57 @@
+package utils
+
+import (
+	"context"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+// processWaitDelay bounds how long Wait blocks on a killed process whose
+// output pipes are still held open by grandchildren.
+const processWaitDelay = 5 * time.Second
+
+// CommandContext is like exec.CommandContext, but cancelling ctx kills the
+// whole process tree rather than only the direct child, so helper processes
+// spawned by ffmpeg, magick or pandoc do not outlive the conversion.
+func CommandContext(ctx context.Context, name string, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.WaitDelay = processWaitDelay
+	killProcessTree(cmd)
+	return cmd
+}
+
+// CommandLog collects the commands a dry run would have executed.
+type CommandLog struct {
+	mu       sync.Mutex
+	commands [][]string
+}
+
+type dryRunKey struct{}
+
+// WithDryRun returns a context under which tool runners record their
+// commands in the returned log instead of executing them.
+func WithDryRun(ctx context.Context) (context.Context, *CommandLog) {
+	log := &CommandLog{}
+	return context.WithValue(ctx, dryRunKey{}, log), log
+}
+
+// DryRun returns the log of a context made by WithDryRun, or nil.
+func DryRun(ctx context.Context) *CommandLog {
+	log, _ := ctx.Value(dryRunKey{}).(*CommandLog)
+	return log
+}
+
+// Record adds the command name with args to the log.
+func (l *CommandLog) Record(name string, args []string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.commands = append(l.commands, append([]string{name}, args...))
+}
+
+// Commands returns the recorded argvs in the order they were recorded.
+func (l *CommandLog) Commands() [][]string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([][]string(nil), l.commands...)
+}
@@ -0,0 +1,16 @@
+//go:build !windows
+
+package utils
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+func killProcessTree(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		// A negative pid signals every process in the child's group.
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+}
@@ -0,0 +1,18 @@
+//go:build windows
+
+package utils
+
+import (
+	"os/exec"
+	"strconv"
+)
+
+func killProcessTree(cmd *exec.Cmd) {
+	cmd.Cancel = func() error {
+		kill := exec.Command("taskkill", "/T", "/F", "/PID", strconv.Itoa(cmd.Process.Pid))
+		if err := kill.Run(); err != nil {
+			return cmd.Process.Kill()
+		}
+		return nil
+	}
+}
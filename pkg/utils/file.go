@@ -2,12 +2,20 @@ package utils
 
 import (
 	"fmt"
+	"io"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
+
+	"goverter/pkg/formats"
 )
 
 func GetFilesByExtension(dir string, extensions []string) ([]string, error) {
+	return ListFilesByExtension(dir, extensions, true)
+}
+
+func ListFilesByExtension(dir string, extensions []string, recursive bool) ([]string, error) {
 	var files []string
 
 	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
@@ -16,6 +24,9 @@ func GetFilesByExtension(dir string, extensions []string) ([]string, error) {
 		}
 
 		if info.IsDir() {
+			if !recursive && path != dir {
+				return filepath.SkipDir
+			}
 			return nil
 		}
 
@@ -33,10 +44,65 @@ func GetFilesByExtension(dir string, extensions []string) ([]string, error) {
 	return files, err
 }
 
+// MatchesAnyGlob reports whether file matches one of the patterns. Each
+// pattern is tried against the slash-separated path and its base name, so
+// both "*.mov" and "raw/*.mov" work, with the same meaning on every OS.
+func MatchesAnyGlob(file string, patterns []string) bool {
+	slashed := filepath.ToSlash(file)
+	base := path.Base(slashed)
+	for _, pattern := range patterns {
+		pattern = filepath.ToSlash(pattern)
+		if ok, _ := path.Match(pattern, slashed); ok {
+			return true
+		}
+		if ok, _ := path.Match(pattern, base); ok {
+			return true
+		}
+	}
+	return false
+}
+
 func EnsureDir(dir string) error {
 	return os.MkdirAll(dir, 0755)
 }
 
+// SamePath reports whether a and b name the same file: the same absolute
+// path, or, when both exist, the same file reached through a link.
+func SamePath(a, b string) bool {
+	absA, errA := filepath.Abs(a)
+	absB, errB := filepath.Abs(b)
+	if errA == nil && errB == nil && absA == absB {
+		return true
+	}
+	infoA, errA := os.Stat(a)
+	infoB, errB := os.Stat(b)
+	return errA == nil && errB == nil && os.SameFile(infoA, infoB)
+}
+
+// FileState records whether a file existed and how it looked, so that a
+// cancelled run can tell whether it wrote to its output.
+type FileState struct {
+	path string
+	info os.FileInfo // nil if the file did not exist
+}
+
+func StatFile(path string) FileState {
+	info, _ := os.Stat(path)
+	return FileState{path: path, info: info}
+}
+
+// RemoveIfChanged removes the file if it has been created or rewritten
+// since StatFile, leaving alone a file the run never got to.
+func (s FileState) RemoveIfChanged() {
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return
+	}
+	if s.info == nil || !info.ModTime().Equal(s.info.ModTime()) || info.Size() != s.info.Size() {
+		os.Remove(s.path)
+	}
+}
+
 func GetUniqueFilename(basePath string) string {
 	ext := filepath.Ext(basePath)
 	base := basePath[:len(basePath)-len(ext)]
@@ -54,6 +120,43 @@ func GetUniqueFilename(basePath string) string {
 	}
 }
 
+// MoveFile moves src into dir under a unique name and returns the new path.
+// It falls back to copy and remove when a rename is not possible, e.g.
+// across filesystems.
+func MoveFile(src, dir string) (string, error) {
+	if err := EnsureDir(dir); err != nil {
+		return "", err
+	}
+	dst := GetUniqueFilename(filepath.Join(dir, filepath.Base(src)))
+
+	if err := os.Rename(src, dst); err == nil {
+		return dst, nil
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return "", err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(dst)
+		return "", err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(dst)
+		return "", err
+	}
+
+	in.Close()
+	return dst, os.Remove(src)
+}
+
 func FormatFileSize(bytes int64) string {
 	const unit = 1024
 	if bytes < unit {
@@ -68,34 +171,13 @@ func FormatFileSize(bytes int64) string {
 }
 
 func IsValidVideoFile(filename string) bool {
-	ext := strings.ToLower(filepath.Ext(filename))
-	videoExts := []string{".mp4", ".avi", ".mkv", ".mov", ".wmv", ".flv", ".webm"}
-	for _, validExt := range videoExts {
-		if ext == validExt {
-			return true
-		}
-	}
-	return false
+	return formats.Default().Is(filepath.Ext(filename), formats.CategoryVideo)
 }
 
 func IsValidImageFile(filename string) bool {
-	ext := strings.ToLower(filepath.Ext(filename))
-	imageExts := []string{".jpg", ".jpeg", ".png", ".gif", ".bmp", ".webp", ".tiff"}
-	for _, validExt := range imageExts {
-		if ext == validExt {
-			return true
-		}
-	}
-	return false
+	return formats.Default().Is(filepath.Ext(filename), formats.CategoryImage)
 }
 
 func IsValidAudioFile(filename string) bool {
-	ext := strings.ToLower(filepath.Ext(filename))
-	audioExts := []string{".mp3", ".wav", ".flac", ".aac", ".ogg", ".m4a"}
-	for _, validExt := range audioExts {
-		if ext == validExt {
-			return true
-		}
-	}
-	return false
+	return formats.Default().Is(filepath.Ext(filename), formats.CategoryAudio)
 }
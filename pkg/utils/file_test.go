@@ -0,0 +1,95 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSamePath(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "clip.mp4")
+	if err := os.WriteFile(file, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(dir, "link.mp4")
+	if err := os.Symlink(file, link); err != nil {
+		t.Skip("symlinks not supported:", err)
+	}
+
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{file, file, true},
+		{file, filepath.Join(dir, ".", "sub", "..", "clip.mp4"), true},
+		{file, link, true},
+		{file, filepath.Join(dir, "other.mp4"), false},
+		{filepath.Join(dir, "missing.mp4"), filepath.Join(dir, "missing.mp4"), true},
+	}
+	for _, tt := range tests {
+		if got := SamePath(tt.a, tt.b); got != tt.want {
+			t.Errorf("SamePath(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestFileStateRemoveIfChanged(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	tests := []struct {
+		name        string
+		setup       func() FileState
+		wantRemoved bool
+	}{
+		{"untouched existing file", func() FileState {
+			return StatFile(write("kept.mp4", "original"))
+		}, false},
+		{"created by the run", func() FileState {
+			state := StatFile(filepath.Join(dir, "new.mp4"))
+			write("new.mp4", "partial")
+			return state
+		}, true},
+		{"rewritten by the run", func() FileState {
+			state := StatFile(write("rewritten.mp4", "original"))
+			write("rewritten.mp4", "partial output")
+			return state
+		}, true},
+	}
+	for _, tt := range tests {
+		state := tt.setup()
+		state.RemoveIfChanged()
+		_, err := os.Stat(state.path)
+		if removed := os.IsNotExist(err); removed != tt.wantRemoved {
+			t.Errorf("%s: removed = %v, want %v", tt.name, removed, tt.wantRemoved)
+		}
+	}
+}
+
+func TestMatchesAnyGlob(t *testing.T) {
+	tests := []struct {
+		file    string
+		pattern string
+		want    bool
+	}{
+		{"a.png", "*.png", true},
+		{"sub/a.png", "*.png", true},
+		{"sub/a.png", "sub/*.png", true},
+		{filepath.Join("sub", "a.png"), "sub/*.png", true},
+		{"sub/deeper/a.png", "sub/*.png", false},
+		{"other/a.png", "sub/*.png", false},
+		{"sub/a.jpg", "*.png", false},
+	}
+	for _, tt := range tests {
+		if got := MatchesAnyGlob(tt.file, []string{tt.pattern}); got != tt.want {
+			t.Errorf("MatchesAnyGlob(%q, %q) = %v, want %v", tt.file, tt.pattern, got, tt.want)
+		}
+	}
+}
@@ -1,16 +1,21 @@
 package video
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strconv"
 	"strings"
+
+	"goverter/pkg/probe"
+	"goverter/pkg/utils"
 )
 
 type FrameExtractor struct {
 	ffmpegPath string
+	prober     *probe.Prober
 }
 
 type ExtractRequest struct {
@@ -24,10 +29,16 @@ type ExtractRequest struct {
 
 func NewFrameExtractor() *FrameExtractor {
 	ffmpegPath, _ := exec.LookPath("ffmpeg")
-	return &FrameExtractor{ffmpegPath: ffmpegPath}
+	return &FrameExtractor{ffmpegPath: ffmpegPath, prober: probe.New()}
 }
 
 func (fe *FrameExtractor) ExtractFrame(req ExtractRequest) error {
+	return fe.ExtractFrameContext(context.Background(), req)
+}
+
+// ExtractFrameContext extracts a frame like ExtractFrame, killing ffmpeg and
+// removing the partial image if ctx is cancelled first.
+func (fe *FrameExtractor) ExtractFrameContext(ctx context.Context, req ExtractRequest) error {
 	if fe.ffmpegPath == "" {
 		return fmt.Errorf("ffmpeg not found. Please install FFmpeg for video processing")
 	}
@@ -61,88 +72,86 @@ func (fe *FrameExtractor) ExtractFrame(req ExtractRequest) error {
 
 	args = append(args, "-y", req.OutputPath)
 
-	cmd := exec.Command(fe.ffmpegPath, args...)
-	return cmd.Run()
-}
+	if log := utils.DryRun(ctx); log != nil {
+		log.Record(fe.ffmpegPath, args)
+		return nil
+	}
 
-func (fe *FrameExtractor) GetVideoInfo(videoPath string) (*VideoInfo, error) {
-	if fe.ffmpegPath == "" {
-		return nil, fmt.Errorf("ffmpeg not found")
+	before := utils.StatFile(req.OutputPath)
+	cmd := utils.CommandContext(ctx, fe.ffmpegPath, args...)
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			before.RemoveIfChanged()
+			return fmt.Errorf("frame extraction stopped: %w", ctx.Err())
+		}
+		return err
 	}
+	return nil
+}
 
-	args := []string{"-i", videoPath, "-hide_banner"}
-	cmd := exec.Command(fe.ffmpegPath, args...)
+func (fe *FrameExtractor) GetVideoInfo(videoPath string) (*VideoInfo, error) {
+	return fe.GetVideoInfoContext(context.Background(), videoPath)
+}
 
-	// FFmpeg outputs to stderr by default for info
-	output, err := cmd.CombinedOutput()
+func (fe *FrameExtractor) GetVideoInfoContext(ctx context.Context, videoPath string) (*VideoInfo, error) {
+	probed, err := fe.prober.Probe(ctx, videoPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get video info: %w", err)
 	}
 
-	return parseVideoInfo(string(output)), nil
-}
-
-type VideoInfo struct {
-	Duration  string
-	Width     int
-	Height    int
-	FrameRate string
-	Bitrate   string
-	Codec     string
-}
+	stream := probed.VideoStream()
+	if stream == nil {
+		return nil, fmt.Errorf("no video stream in %s", videoPath)
+	}
 
-func parseVideoInfo(output string) *VideoInfo {
-	info := &VideoInfo{}
-	lines := strings.Split(output, "\n")
-
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-
-		if strings.Contains(line, "Duration:") {
-			parts := strings.Split(line, "Duration:")
-			if len(parts) > 1 {
-				duration := strings.TrimSpace(parts[1])
-				if idx := strings.Index(duration, ","); idx != -1 {
-					info.Duration = duration[:idx]
-				}
-			}
+	info := &VideoInfo{
+		Duration: formatTimestamp(probed.Format.Duration),
+		Codec:    stream.CodecName,
+		Probe:    probed,
+	}
+	info.Width, info.Height = stream.DisplaySize()
+	if stream.FrameRate > 0 {
+		info.FrameRate = strconv.FormatFloat(stream.FrameRate, 'f', -1, 64)
+		if len(info.FrameRate) > 6 {
+			info.FrameRate = fmt.Sprintf("%.2f", stream.FrameRate)
 		}
+	}
 
-		if strings.Contains(line, "Video:") {
-			// Extract dimensions
-			if strings.Contains(line, "x") {
-				fields := strings.Fields(line)
-				for _, field := range fields {
-					if strings.Contains(field, "x") && strings.Count(field, "x") == 1 {
-						dims := strings.Split(field, "x")
-						if len(dims) == 2 {
-							if w, err := strconv.Atoi(dims[0]); err == nil {
-								info.Width = w
-							}
-							if h, err := strconv.Atoi(dims[1]); err == nil {
-								info.Height = h
-							}
-						}
-						break
-					}
-				}
-			}
-
-			// Extract codec
-			fields := strings.Fields(line)
-			for i, field := range fields {
-				if field == "Video:" && i+1 < len(fields) {
-					info.Codec = fields[i+1]
-					break
-				}
-			}
-		}
+	bitrate := stream.BitRate
+	if bitrate == 0 {
+		// Matroska and WebM only report the overall bitrate
+		bitrate = probed.Format.BitRate
+	}
+	if bitrate > 0 {
+		info.Bitrate = fmt.Sprintf("%d kb/s", bitrate/1000)
 	}
 
-	return info
+	return info, nil
+}
+
+type VideoInfo struct {
+	Duration  string `json:"duration,omitempty"`
+	Width     int    `json:"width,omitempty"`
+	Height    int    `json:"height,omitempty"`
+	FrameRate string `json:"frame_rate,omitempty"`
+	Bitrate   string `json:"bitrate,omitempty"`
+	Codec     string `json:"codec,omitempty"`
+
+	// Probe holds the full ffprobe description.
+	Probe *probe.Info `json:"probe,omitempty"`
+}
+
+// formatTimestamp renders seconds as HH:MM:SS.ss, like ffmpeg does.
+func formatTimestamp(seconds float64) string {
+	whole := int(seconds)
+	return fmt.Sprintf("%02d:%02d:%05.2f", whole/3600, whole%3600/60, seconds-float64(whole-whole%60))
 }
 
 func (fe *FrameExtractor) ExtractMultipleFrames(videoPath, outputDir string, intervalSeconds int) ([]string, error) {
+	return fe.ExtractMultipleFramesContext(context.Background(), videoPath, outputDir, intervalSeconds)
+}
+
+func (fe *FrameExtractor) ExtractMultipleFramesContext(ctx context.Context, videoPath, outputDir string, intervalSeconds int) ([]string, error) {
 	if fe.ffmpegPath == "" {
 		return nil, fmt.Errorf("ffmpeg not found")
 	}
@@ -159,8 +168,11 @@ func (fe *FrameExtractor) ExtractMultipleFrames(videoPath, outputDir string, int
 		outputPattern,
 	}
 
-	cmd := exec.Command(fe.ffmpegPath, args...)
+	cmd := utils.CommandContext(ctx, fe.ffmpegPath, args...)
 	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("frame extraction stopped: %w", ctx.Err())
+		}
 		return nil, fmt.Errorf("failed to extract frames: %w", err)
 	}
 
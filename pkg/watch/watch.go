@@ -0,0 +1,309 @@
+package watch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"goverter/pkg/converter"
+	"goverter/pkg/formats"
+	"goverter/pkg/jobstore"
+	"goverter/pkg/utils"
+)
+
+type Options struct {
+	Dir     string
+	OutDir  string
+	Format  string // target extension, with or without the dot
+	Options map[string]string
+
+	// DoneDir and FailedDir, if set, receive the original after a
+	// successful or failed conversion.
+	DoneDir   string
+	FailedDir string
+
+	// Settle is how long a file's size and modification time must stay
+	// unchanged before it is converted. Defaults to 2s.
+	Settle time.Duration
+
+	// Store records every file so that a restart skips those already
+	// processed. Each watched directory is its own batch.
+	Store *jobstore.Store
+}
+
+// Event reports one processed file.
+type Event struct {
+	Input    string
+	Output   string
+	MovedTo  string
+	Err      error
+	Duration time.Duration
+}
+
+type Watcher struct {
+	opts      Options
+	converter *converter.Converter
+	batch     string
+	targetExt string
+	inputExts map[string]bool
+}
+
+func New(c *converter.Converter, opts Options) (*Watcher, error) {
+	if opts.Dir == "" || opts.OutDir == "" || opts.Format == "" {
+		return nil, errors.New("watch needs a directory, an output directory and a target format")
+	}
+	if stat, err := os.Stat(opts.Dir); err != nil {
+		return nil, err
+	} else if !stat.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", opts.Dir)
+	}
+	if opts.Settle <= 0 {
+		opts.Settle = 2 * time.Second
+	}
+	if opts.Store == nil {
+		return nil, errors.New("watch needs a job store to remember processed files")
+	}
+	// Journaled paths must not depend on the working directory, or a
+	// restart from elsewhere would miss the history.
+	var err error
+	if opts.Dir, err = filepath.Abs(opts.Dir); err != nil {
+		return nil, err
+	}
+	if opts.OutDir, err = filepath.Abs(opts.OutDir); err != nil {
+		return nil, err
+	}
+
+	targetExt := formats.NormalizeExt(opts.Format)
+	inputExts := make(map[string]bool)
+	for _, ext := range c.GetInputFormats(targetExt) {
+		if ext != targetExt {
+			inputExts[ext] = true
+		}
+	}
+	if len(inputExts) == 0 {
+		return nil, fmt.Errorf("no supported input formats convert to %s", targetExt)
+	}
+	if err := converter.ValidateOptions(targetExt, opts.Options); err != nil {
+		return nil, err
+	}
+
+	return &Watcher{
+		opts:      opts,
+		converter: c,
+		batch:     Batch(opts.Dir),
+		targetExt: targetExt,
+		inputExts: inputExts,
+	}, nil
+}
+
+// pendingFile tracks a file that is still being written.
+type pendingFile struct {
+	size    int64
+	modTime time.Time
+	since   time.Time
+}
+
+// Run watches until ctx is done, calling report after every file. Files
+// already in the directory when Run starts are picked up too, except those
+// the ledger has seen.
+func (w *Watcher) Run(ctx context.Context, report func(Event)) error {
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer fsw.Close()
+	if err := fsw.Add(w.opts.Dir); err != nil {
+		return err
+	}
+
+	pending := make(map[string]*pendingFile)
+	queue := make(chan string, 64)
+	queued := make(map[string]bool)
+	processed := make(chan string, 64)
+
+	// Conversions run one at a time in their own goroutine so that the
+	// event loop keeps tracking files while a long conversion runs.
+	go func() {
+		for path := range queue {
+			report(w.process(ctx, path))
+			processed <- path
+		}
+		close(processed)
+	}()
+	defer func() {
+		close(queue)
+		for range processed {
+		}
+	}()
+
+	track := func(path string) {
+		if !w.eligible(path) || queued[path] {
+			return
+		}
+		stat, err := os.Stat(path)
+		if err != nil || !stat.Mode().IsRegular() || w.seen(path, stat) {
+			return
+		}
+		if p, ok := pending[path]; ok && p.size == stat.Size() && p.modTime.Equal(stat.ModTime()) {
+			return
+		}
+		pending[path] = &pendingFile{size: stat.Size(), modTime: stat.ModTime(), since: time.Now()}
+	}
+
+	entries, err := os.ReadDir(w.opts.Dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		track(filepath.Join(w.opts.Dir, entry.Name()))
+	}
+
+	ticker := time.NewTicker(w.opts.Settle / 4)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-fsw.Events:
+			if !ok {
+				return nil
+			}
+			if event.Has(fsnotify.Create) || event.Has(fsnotify.Write) {
+				track(event.Name)
+			}
+		case err, ok := <-fsw.Errors:
+			if !ok {
+				return nil
+			}
+			return fmt.Errorf("watching %s: %w", w.opts.Dir, err)
+		case path := <-processed:
+			delete(queued, path)
+		case <-ticker.C:
+			for path, p := range pending {
+				stat, err := os.Stat(path)
+				if err != nil {
+					delete(pending, path)
+					continue
+				}
+				if stat.Size() != p.size || !stat.ModTime().Equal(p.modTime) {
+					p.size, p.modTime, p.since = stat.Size(), stat.ModTime(), time.Now()
+					continue
+				}
+				if time.Since(p.since) < w.opts.Settle {
+					continue
+				}
+				delete(pending, path)
+				queued[path] = true
+				select {
+				case queue <- path:
+				default:
+					// The queue is full; try again on a later tick.
+					delete(queued, path)
+					pending[path] = p
+				}
+			}
+		}
+	}
+}
+
+// Batch is the job store batch of a watched directory.
+func Batch(dir string) string {
+	return jobstore.WatchPrefix + dir
+}
+
+// seen reports whether this exact file already finished, successfully or
+// not. Files whose conversion was interrupted are picked up again.
+func (w *Watcher) seen(path string, stat os.FileInfo) bool {
+	job, ok := w.opts.Store.Latest(w.batch, path)
+	if !ok || !job.SameInput(stat) {
+		return false
+	}
+	return job.Status == jobstore.StatusDone || job.Status == jobstore.StatusFailed
+}
+
+// eligible filters out dotfiles (including most partial downloads) and
+// formats that cannot become the target.
+func (w *Watcher) eligible(path string) bool {
+	name := filepath.Base(path)
+	if strings.HasPrefix(name, ".") {
+		return false
+	}
+	return w.inputExts[strings.ToLower(filepath.Ext(name))]
+}
+
+func (w *Watcher) process(ctx context.Context, path string) Event {
+	start := time.Now()
+	event := Event{Input: path}
+
+	if err := utils.EnsureDir(w.opts.OutDir); err != nil {
+		event.Err = err
+		return event
+	}
+	job, err := w.startJob(path)
+	if err != nil {
+		event.Err = fmt.Errorf("failed to record job: %w", err)
+		return event
+	}
+	event.Output = job.Output
+
+	event.Err = w.converter.ConvertContext(ctx, converter.ConversionRequest{
+		InputPath:  path,
+		OutputPath: event.Output,
+		Options:    w.opts.Options,
+	})
+	event.Duration = time.Since(start)
+	if ctx.Err() != nil {
+		// Shutting down: the job stays running so it is retried.
+		return event
+	}
+
+	if err := w.opts.Store.Finish(job.ID, event.Err); err != nil && event.Err == nil {
+		event.Err = fmt.Errorf("converted but failed to record job: %w", err)
+	}
+
+	moveTo := w.opts.DoneDir
+	if event.Err != nil {
+		moveTo = w.opts.FailedDir
+	}
+	if moveTo != "" {
+		moved, err := utils.MoveFile(path, moveTo)
+		if err != nil && event.Err == nil {
+			event.Err = fmt.Errorf("converted but failed to move original: %w", err)
+		}
+		event.MovedTo = moved
+	}
+
+	return event
+}
+
+// startJob records that path is being converted. A job for the same file
+// that a crash or shutdown interrupted is taken up again with its output
+// path, after removing whatever it had written; otherwise a new job gets a
+// free output name.
+func (w *Watcher) startJob(path string) (jobstore.Job, error) {
+	job, ok := w.opts.Store.Latest(w.batch, path)
+	stat, err := os.Stat(path)
+	if ok && err == nil && job.SameInput(stat) && (job.Status == jobstore.StatusPending || job.Status == jobstore.StatusRunning) {
+		if err := os.Remove(job.Output); err != nil && !os.IsNotExist(err) {
+			return jobstore.Job{}, err
+		}
+	} else {
+		name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		job, err = w.opts.Store.Add(jobstore.Job{
+			Batch:   w.batch,
+			Input:   path,
+			Output:  utils.GetUniqueFilename(filepath.Join(w.opts.OutDir, name+w.targetExt)),
+			Options: w.opts.Options,
+		})
+		if err != nil {
+			return jobstore.Job{}, err
+		}
+	}
+	return job, w.opts.Store.Start(job.ID)
+}
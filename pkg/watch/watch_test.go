@@ -0,0 +1,88 @@
+package watch
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"goverter/pkg/converter"
+	"goverter/pkg/jobstore"
+)
+
+func newTestWatcher(t *testing.T, dir string) *Watcher {
+	t.Helper()
+	store, err := jobstore.Open(filepath.Join(t.TempDir(), jobstore.FileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := New(converter.NewConverter(), Options{Dir: dir, OutDir: filepath.Join(dir, "out"), Format: "mp3", Store: store})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return w
+}
+
+func TestNewResolvesDir(t *testing.T) {
+	dir := t.TempDir()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	w := newTestWatcher(t, ".")
+	abs, _ := filepath.Abs(".")
+	if w.opts.Dir != abs || w.opts.OutDir != filepath.Join(abs, "out") || w.batch != Batch(abs) {
+		t.Errorf("dir %s, out %s, batch %s; want all based on %s", w.opts.Dir, w.opts.OutDir, w.batch, abs)
+	}
+}
+
+func TestStartJobResumesInterruptedJob(t *testing.T) {
+	dir := t.TempDir()
+	w := newTestWatcher(t, dir)
+	input := filepath.Join(dir, "talk.wav")
+	if err := os.WriteFile(input, []byte("audio"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(w.opts.OutDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := w.startJob(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The conversion was cut short, leaving a partial file behind
+	if err := os.WriteFile(first.Output, []byte("partial"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	again, err := w.startJob(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.ID != first.ID || again.Output != first.Output {
+		t.Errorf("retry got job %s -> %s, want %s -> %s again", again.ID, again.Output, first.ID, first.Output)
+	}
+	if _, err := os.Stat(first.Output); !os.IsNotExist(err) {
+		t.Errorf("partial output %s was left for the retry", first.Output)
+	}
+	if job, _ := w.opts.Store.Get(first.ID); job.Attempts != 2 {
+		t.Errorf("job has %d attempts, want 2", job.Attempts)
+	}
+
+	if err := w.opts.Store.Finish(first.ID, errors.New("boom")); err != nil {
+		t.Fatal(err)
+	}
+	next, err := w.startJob(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next.ID == first.ID {
+		t.Errorf("a finished job was taken up again")
+	}
+}